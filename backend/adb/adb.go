@@ -1,32 +1,140 @@
 package adb
 
 import (
+	"androidcontrol/logging"
 	"androidcontrol/models"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+// DefaultCommandTimeout bounds short adb commands so a hung device (flaky WiFi) can't block forever
+const DefaultCommandTimeout = 10 * time.Second
+
+// transferTimeout bounds install/push, which legitimately take longer
+const transferTimeout = 5 * time.Minute
+
+// wirelessTimeout bounds adb connect/pair, which can hang on unreachable hosts
+const wirelessTimeout = 10 * time.Second
+
 // ADBClient wraps ADB command execution
 type ADBClient struct {
 	ADBPath string
+	Timeout time.Duration // Per-command timeout for short commands
+
+	// Remote ADB server (adb -H/-P); empty means the local default server
+	Host string
+	Port string
 }
 
 // NewADBClient creates a new ADB client
+// ADB_SERVER_HOST / ADB_SERVER_PORT select a remote ADB server (e.g. one running in a container)
 func NewADBClient() *ADBClient {
 	return &ADBClient{
 		ADBPath: "adb", // Assumes ADB is in PATH
+		Timeout: DefaultCommandTimeout,
+		Host:    getEnv("ADB_SERVER_HOST", ""),
+		Port:    getEnv("ADB_SERVER_PORT", ""),
+	}
+}
+
+// args builds adb arguments: server flags, then -s <deviceID> (omitted when empty), then extra
+func (c *ADBClient) args(deviceID string, extra ...string) []string {
+	args := make([]string, 0, len(extra)+6)
+	if c.Host != "" {
+		args = append(args, "-H", c.Host)
+	}
+	if c.Port != "" {
+		args = append(args, "-P", c.Port)
+	}
+	if deviceID != "" {
+		args = append(args, "-s", deviceID)
+	}
+	return append(args, extra...)
+}
+
+// ForwardHost is where `adb forward` ports listen: the ADB server's host
+// A remote server must be started with -a for its forwards to be reachable
+func (c *ADBClient) ForwardHost() string {
+	if c.Host != "" {
+		return c.Host
+	}
+	return "127.0.0.1"
+}
+
+// commandContext builds an adb command that is killed when ctx is done
+func (c *ADBClient) commandContext(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, c.ADBPath, args...)
+	cmd.WaitDelay = time.Second // Don't hang on pipes still held by orphaned children after kill
+	return cmd
+}
+
+// outputContext runs an adb command bound to ctx and returns stdout
+// On timeout/cancel the process is killed and a wrapped ctx error is returned
+func (c *ADBClient) outputContext(ctx context.Context, args ...string) ([]byte, error) {
+	output, err := c.commandContext(ctx, args...).Output()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			logging.Device(argDeviceID(args)).Warn("adb_timeout", "⚠️ adb %s timed out", strings.Join(args, " "))
+		}
+		return nil, fmt.Errorf("adb %s: %w", strings.Join(args, " "), ctxErr)
+	}
+	return output, err
+}
+
+// argDeviceID returns the -s serial from adb arguments ("" for server-wide commands)
+func argDeviceID(args []string) string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-s" {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// outputTimeout runs an adb command with the given timeout and returns stdout
+func (c *ADBClient) outputTimeout(timeout time.Duration, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.outputContext(ctx, args...)
+}
+
+// output runs a short adb command with the client timeout and returns stdout
+func (c *ADBClient) output(args ...string) ([]byte, error) {
+	return c.outputTimeout(c.Timeout, args...)
+}
+
+// Version returns the first line of `adb version` (e.g. "Android Debug Bridge version 1.0.41")
+// Fails when the adb binary is missing, so it doubles as a startup check
+func (c *ADBClient) Version() (string, error) {
+	output, err := c.output("version")
+	if err != nil {
+		return "", fmt.Errorf("adb version failed: %w", err)
 	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(line), nil
 }
 
 // ListDevices returns a list of connected Android devices
 // If the same physical device is connected via both USB and WiFi, WiFi is preferred
 func (c *ADBClient) ListDevices() ([]models.Device, error) {
-	cmd := exec.Command(c.ADBPath, "devices", "-l")
-	output, err := cmd.Output()
+	return c.ListDevicesCached(nil)
+}
+
+// ListDevicesCached is ListDevices reusing serials and static properties from cache
+// Only battery is queried for devices already in the cache
+func (c *ADBClient) ListDevicesCached(cache *PropertyCache) ([]models.Device, error) {
+	output, err := c.output(c.args("", "devices", "-l")...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list devices: %w", err)
 	}
@@ -40,18 +148,74 @@ func (c *ADBClient) ListDevices() ([]models.Device, error) {
 		return nil, err
 	}
 
+	cache.retain(devices)
+
 	// Deduplicate: If same physical device connected via USB and WiFi, prefer WiFi
-	return c.deduplicateDevices(devices), nil
+	devices = c.deduplicateDevices(devices, cache)
+
+	// Get additional device properties (after dedup so duplicates aren't queried)
+	// Devices that aren't online can't run shell commands
+	forEachParallel(len(devices), func(i int) {
+		if devices[i].Status != models.DeviceStatusOnline {
+			return
+		}
+		if err := c.enrichDeviceInfo(&devices[i], cache); err != nil {
+			// Log error but don't fail
+			fmt.Printf("Warning: Failed to get full info for %s: %v\n", devices[i].ADBDeviceID, err)
+		}
+	})
+	return devices, nil
+}
+
+// maxScanWorkers bounds concurrent adb shell round-trips during a scan
+const maxScanWorkers = 8
+
+// forEachParallel runs fn(0..n-1) on at most maxScanWorkers goroutines and waits for all
+// Each call must only touch index i of shared slices
+func forEachParallel(n int, fn func(i int)) {
+	sem := make(chan struct{}, maxScanWorkers)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
 }
 
 // getSerialNumber gets the hardware serial number of the device
-func (c *ADBClient) getSerialNumber(adbDeviceID string) string {
-	cmd := exec.Command(c.ADBPath, "-s", adbDeviceID, "shell", "getprop", "ro.serialno")
-	output, err := cmd.Output()
+// Falls back to the last serial seen for this ADB ID when getprop fails
+func (c *ADBClient) getSerialNumber(adbDeviceID string, cache *PropertyCache) string {
+	if hwSerial, ok := cache.serial(adbDeviceID); ok {
+		return hwSerial
+	}
+
+	hwSerial, err := readSerial(c, adbDeviceID)
 	if err != nil {
-		return ""
+		hwSerial = ""
+	}
+	if hwSerial != "" {
+		cache.setSerial(adbDeviceID, hwSerial)
+		return hwSerial
+	}
+
+	// Model/resolution heuristics would merge identical phones on a rig, so only
+	// trust a serial this exact ADB ID has reported before
+	if known, ok := cache.lastKnownSerial(adbDeviceID); ok {
+		fmt.Printf("⚠️ Serial lookup failed for %s, using last known %s\n", adbDeviceID, known)
+		return known
 	}
-	return strings.TrimSpace(string(output))
+	return ""
+}
+
+// readSerial queries ro.serialno (a var so dedup can be exercised without adb)
+var readSerial = func(c *ADBClient, adbDeviceID string) (string, error) {
+	output, err := c.output(c.args(adbDeviceID, "shell", "getprop", "ro.serialno")...)
+	return strings.TrimSpace(string(output)), err
 }
 
 // isWiFiConnection checks if the device ID is a WiFi connection (IP:port format)
@@ -59,44 +223,58 @@ func isWiFiConnection(adbDeviceID string) bool {
 	return strings.Contains(adbDeviceID, ":")
 }
 
+// SetConnectionInfo sets a device's connection type, and ip/port for WiFi, from its ADB ID
+func SetConnectionInfo(device *models.Device) {
+	device.IP, device.Port = "", 0
+	switch {
+	case isWiFiConnection(device.ADBDeviceID):
+		device.ConnectionType = models.ConnectionTypeWiFi
+		if host, port, err := net.SplitHostPort(device.ADBDeviceID); err == nil {
+			device.IP = host
+			device.Port, _ = strconv.Atoi(port)
+		}
+	case strings.HasPrefix(device.ADBDeviceID, "emulator-"):
+		device.ConnectionType = models.ConnectionTypeEmulator
+	default:
+		device.ConnectionType = models.ConnectionTypeUSB
+	}
+}
+
 // deduplicateDevices removes duplicate entries when same device is connected via USB and WiFi
 // WiFi connections are preferred over USB
-func (c *ADBClient) deduplicateDevices(devices []models.Device) []models.Device {
-	// Map hardware serial -> device (prefer WiFi)
-	serialToDevice := make(map[string]models.Device)
+func (c *ADBClient) deduplicateDevices(devices []models.Device, cache *PropertyCache) []models.Device {
+	// First pass: get hardware serial for each device (in parallel, one slot per device)
+	serials := make([]string, len(devices))
+	forEachParallel(len(devices), func(i int) {
+		if devices[i].Status == models.DeviceStatusOnline {
+			serials[i] = c.getSerialNumber(devices[i].ADBDeviceID, cache)
+		}
+	})
+
+	// Map hardware serial -> index in result (prefer WiFi), keeping first-seen order
+	serialToIndex := make(map[string]int)
+	result := make([]models.Device, 0, len(devices))
 
-	// First pass: get hardware serial for each device
 	for i := range devices {
-		hwSerial := c.getSerialNumber(devices[i].ADBDeviceID)
+		hwSerial := serials[i]
 		if hwSerial == "" {
 			// Can't get serial, keep device as-is using ADB ID as key
 			hwSerial = devices[i].ADBDeviceID
 		}
 		devices[i].HardwareSerial = hwSerial // Store for reference
 
-		existing, exists := serialToDevice[hwSerial]
+		idx, exists := serialToIndex[hwSerial]
 		if !exists {
-			serialToDevice[hwSerial] = devices[i]
-		} else {
-			// Duplicate found - prefer WiFi connection
-			currentIsWiFi := isWiFiConnection(devices[i].ADBDeviceID)
-			existingIsWiFi := isWiFiConnection(existing.ADBDeviceID)
-
-			if currentIsWiFi && !existingIsWiFi {
-				// Current is WiFi, existing is USB - replace with WiFi
-				// Dedup: prefer WiFi over USB
-				serialToDevice[hwSerial] = devices[i]
-			} else if !currentIsWiFi && existingIsWiFi {
-				// Current is USB, existing is WiFi - keep WiFi (no-op)
-			}
-			// If both are same type, keep the first one
+			serialToIndex[hwSerial] = len(result)
+			result = append(result, devices[i])
+			continue
 		}
-	}
 
-	// Convert map back to slice
-	result := make([]models.Device, 0, len(serialToDevice))
-	for _, device := range serialToDevice {
-		result = append(result, device)
+		// Duplicate found - prefer WiFi connection
+		// If both are same type, keep the first one
+		if isWiFiConnection(devices[i].ADBDeviceID) && !isWiFiConnection(result[idx].ADBDeviceID) {
+			result[idx] = devices[i]
+		}
 	}
 
 	// Only log if deduplication actually happened
@@ -106,6 +284,14 @@ func (c *ADBClient) deduplicateDevices(devices []models.Device) []models.Device
 	return result
 }
 
+// deviceStates maps `adb devices` states to device status values
+var deviceStates = map[string]string{
+	"device":         models.DeviceStatusOnline,
+	"offline":        models.DeviceStatusOffline,
+	"unauthorized":   models.DeviceStatusUnauthorized,
+	"no permissions": models.DeviceStatusNoPermissions,
+}
+
 // parseDeviceList parses the output of 'adb devices -l'
 func (c *ADBClient) parseDeviceList(output string) ([]models.Device, error) {
 	var devices []models.Device
@@ -125,11 +311,15 @@ func (c *ADBClient) parseDeviceList(output string) ([]models.Device, error) {
 
 		serial := parts[0]
 		state := parts[1]
+		if state == "no" && len(parts) > 2 && parts[2] == "permissions" {
+			state = "no permissions" // "no permissions (udev rules...)" spans several fields
+		}
 
 		fmt.Printf("🔍 Found device: Serial=%s, State=%s\n", serial, state)
 
-		// Only include devices that are online
-		if state != "device" {
+		// Surface devices that need user attention; skip transient states (recovery, sideload...)
+		status, ok := deviceStates[state]
+		if !ok {
 			fmt.Printf("⚠️ Skipping device %s because state is %s\n", serial, state)
 			continue
 		}
@@ -139,23 +329,18 @@ func (c *ADBClient) parseDeviceList(output string) ([]models.Device, error) {
 			ID:          fmt.Sprintf("device_%s", serial),
 			ADBDeviceID: serial,
 			Name:        serial, // Will be updated with model name
-			Status:      "online",
+			Status:      status,
 		}
+		SetConnectionInfo(&device)
 
 		// Parse additional device info
 		for _, part := range parts[2:] {
 			if strings.HasPrefix(part, "model:") {
-				device.Name = strings.TrimPrefix(part, "model:")
-				device.Name = strings.ReplaceAll(device.Name, "_", " ")
+				device.Model = strings.ReplaceAll(strings.TrimPrefix(part, "model:"), "_", " ")
+				device.Name = device.Model
 			}
 		}
 
-		// Get additional device properties
-		if err := c.enrichDeviceInfo(&device); err != nil {
-			// Log error but don't fail
-			fmt.Printf("Warning: Failed to get full info for %s: %v\n", serial, err)
-		}
-
 		devices = append(devices, device)
 	}
 
@@ -163,16 +348,22 @@ func (c *ADBClient) parseDeviceList(output string) ([]models.Device, error) {
 }
 
 // enrichDeviceInfo gets additional device properties via shell commands
-func (c *ADBClient) enrichDeviceInfo(device *models.Device) error {
-	// Get Android version
-	if version, err := c.getProperty(device.ADBDeviceID, "ro.build.version.release"); err == nil {
-		device.AndroidVersion = strings.TrimSpace(version)
+// Static properties come from cache when present; battery is always queried
+func (c *ADBClient) enrichDeviceInfo(device *models.Device, cache *PropertyCache) error {
+	props, cached := cache.get(device.HardwareSerial)
+	if !cached {
+		props = c.getStaticProps(device.ADBDeviceID)
+		// Only cache a complete read - a half-answered getprop shouldn't stick
+		if props.AndroidVersion != "" && props.Resolution != "" {
+			cache.set(device.HardwareSerial, props)
+		}
 	}
 
-	// Get screen resolution
-	if resolution, err := c.getScreenResolution(device.ADBDeviceID); err == nil {
-		device.Resolution = resolution
-	}
+	device.AndroidVersion = props.AndroidVersion
+	device.Manufacturer = props.Manufacturer
+	device.SDKInt = props.SDKInt
+	device.CPUABI = props.CPUABI
+	device.Resolution = props.Resolution
 
 	// Get battery level
 	if battery, err := c.getBatteryLevel(device.ADBDeviceID); err == nil {
@@ -182,10 +373,37 @@ func (c *ADBClient) enrichDeviceInfo(device *models.Device) error {
 	return nil
 }
 
+// getStaticProps queries the properties that don't change while connected
+func (c *ADBClient) getStaticProps(deviceID string) StaticProps {
+	var props StaticProps
+
+	// Get Android version
+	if version, err := c.getProperty(deviceID, "ro.build.version.release"); err == nil {
+		props.AndroidVersion = strings.TrimSpace(version)
+	}
+
+	// Get manufacturer, API level and primary ABI (for compatibility filtering)
+	if manufacturer, err := c.getProperty(deviceID, "ro.product.manufacturer"); err == nil {
+		props.Manufacturer = strings.TrimSpace(manufacturer)
+	}
+	if sdk, err := c.getProperty(deviceID, "ro.build.version.sdk"); err == nil {
+		fmt.Sscanf(strings.TrimSpace(sdk), "%d", &props.SDKInt)
+	}
+	if abi, err := c.getProperty(deviceID, "ro.product.cpu.abi"); err == nil {
+		props.CPUABI = strings.TrimSpace(abi)
+	}
+
+	// Get screen resolution
+	if resolution, err := c.getScreenResolution(deviceID); err == nil {
+		props.Resolution = resolution
+	}
+
+	return props
+}
+
 // getProperty gets a system property from the device
 func (c *ADBClient) getProperty(deviceID, property string) (string, error) {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "shell", "getprop", property)
-	output, err := cmd.Output()
+	output, err := c.output(c.args(deviceID, "shell", "getprop", property)...)
 	if err != nil {
 		return "", err
 	}
@@ -195,8 +413,7 @@ func (c *ADBClient) getProperty(deviceID, property string) (string, error) {
 // getScreenResolution gets the device screen resolution
 // Prioritizes "Override size" if set, otherwise uses "Physical size"
 func (c *ADBClient) getScreenResolution(deviceID string) (string, error) {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "shell", "wm", "size")
-	output, err := cmd.Output()
+	output, err := c.output(c.args(deviceID, "shell", "wm", "size")...)
 	if err != nil {
 		return "", err
 	}
@@ -236,8 +453,7 @@ func (c *ADBClient) getScreenResolution(deviceID string) (string, error) {
 
 // getBatteryLevel gets the device battery level (0-100)
 func (c *ADBClient) getBatteryLevel(deviceID string) (int, error) {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "shell", "dumpsys", "battery")
-	output, err := cmd.Output()
+	output, err := c.output(c.args(deviceID, "shell", "dumpsys", "battery")...)
 	if err != nil {
 		return 0, err
 	}
@@ -258,45 +474,95 @@ func (c *ADBClient) getBatteryLevel(deviceID string) (int, error) {
 	return 0, fmt.Errorf("battery level not found")
 }
 
+// ShellResult is the outcome of a shell command that ran on the device
+type ShellResult struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
 // ExecuteCommand executes a generic ADB shell command
-func (c *ADBClient) ExecuteCommand(deviceID, command string) (string, error) {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "shell", command)
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("command failed: %w", err)
+func (c *ADBClient) ExecuteCommand(deviceID, command string) (ShellResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	return c.ExecuteCommandContext(ctx, deviceID, command)
+}
+
+// ExecuteCommandContext executes a generic ADB shell command bound to ctx
+// A non-zero exit status is reported in ShellResult, not as an error
+// (adb forwards the remote exit code on Android 7+)
+func (c *ADBClient) ExecuteCommandContext(ctx context.Context, deviceID, command string) (ShellResult, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := c.commandContext(ctx, c.args(deviceID, "shell", command)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ShellResult{}, fmt.Errorf("command failed: %w", ctxErr)
 	}
-	return string(output), nil
+
+	result := ShellResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return ShellResult{}, fmt.Errorf("command failed: %w", err)
+	}
+	return result, nil
 }
 
 // ScreenCapture captures the device screen and returns PNG bytes
 func (c *ADBClient) ScreenCapture(deviceID string) ([]byte, error) {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "exec-out", "screencap", "-p")
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	cmd := c.commandContext(ctx, c.args(deviceID, "exec-out", "screencap", "-p")...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("screencap failed: %w", ctx.Err())
+		}
 		return nil, fmt.Errorf("screencap failed: %w, stderr: %s", err, stderr.String())
 	}
 
 	return stdout.Bytes(), nil
 }
 
+// H264Opts sets screenrecord's encoder for one device; zero fields fall back to the
+// H264_SIZE / H264_BITRATE env values, then to the built-in defaults
+type H264Opts struct {
+	MaxSize int // Longest edge in px; the other edge follows the device's aspect ratio
+	BitRate int // bps
+}
+
+// defaultH264MaxSize is the longest edge used when neither opts nor H264_SIZE set a size
+const defaultH264MaxSize = 1280
+
 // StartH264Stream starts hardware-encoded H.264 streaming using screenrecord
 // Returns io.ReadCloser for streaming raw H.264 data, and *exec.Cmd for process control
-func (c *ADBClient) StartH264Stream(deviceID string) (io.ReadCloser, *exec.Cmd, error) {
+func (c *ADBClient) StartH264Stream(deviceID string, opts H264Opts) (io.ReadCloser, *exec.Cmd, error) {
 	// Increased quality settings to reduce blur during interaction
 	bitrate := getEnv("H264_BITRATE", "2000000") // 2 Mbps (good balance for grid view)
-	size := getEnv("H264_SIZE", "720x1280")      // Higher resolution for sharper image
+	if opts.BitRate > 0 {
+		bitrate = strconv.Itoa(opts.BitRate)
+	}
+	size := os.Getenv("H264_SIZE") // Explicit WxH, used as-is
+	if opts.MaxSize > 0 || size == "" {
+		size = c.h264Size(deviceID, opts.MaxSize)
+	}
 
 	// Start screenrecord with H.264 output (default 3-minute limit for compatibility)
 	// Backend will auto-restart when stream ends
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "exec-out",
+	cmd := exec.Command(c.ADBPath, c.args(deviceID, "exec-out",
 		"screenrecord",
 		"--output-format=h264",
 		"--bit-rate="+bitrate,
 		"--size="+size,
-		"-") // stdout
+		"-")...) // stdout
 
 	// Get stdout pipe for streaming
 	stdout, err := cmd.StdoutPipe()
@@ -315,6 +581,73 @@ func (c *ADBClient) StartH264Stream(deviceID string) (io.ReadCloser, *exec.Cmd,
 	return stdout, cmd, nil
 }
 
+// h264Size scales the device's screen so its longest edge is at most maxSize
+// (0 = default), keeping the aspect ratio so landscape tablets aren't squashed
+func (c *ADBClient) h264Size(deviceID string, maxSize int) string {
+	if maxSize <= 0 {
+		maxSize = defaultH264MaxSize
+	}
+	resolution, err := c.getScreenResolution(deviceID)
+	var width, height int
+	if err == nil {
+		_, err = fmt.Sscanf(resolution, "%dx%d", &width, &height)
+	}
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = maxSize*9/16, maxSize
+		logging.Device(deviceID).Warn("screen_size_unknown", "⚠️ Screen size unknown (%v), screenrecord uses %dx%d", err, width, height)
+	}
+	width, height = fitSize(width, height, maxSize)
+	return fmt.Sprintf("%dx%d", width, height)
+}
+
+// fitSize scales width x height down (never up) to a longest edge of maxSize,
+// rounding both edges down to multiples of 8 as hardware encoders expect
+func fitSize(width, height, maxSize int) (int, int) {
+	if longest := max(width, height); longest > maxSize {
+		width, height = width*maxSize/longest, height*maxSize/longest
+	}
+	return max(width&^7, 8), max(height&^7, 8)
+}
+
+// logcatFilterPattern restricts logcat args since adb forwards them to the device shell
+var logcatFilterPattern = regexp.MustCompile(`^[A-Za-z0-9_.*:\-]+$`)
+
+// StartLogcat starts streaming logcat output (adb -s <id> logcat <filters...>)
+// Returns io.ReadCloser for reading log lines, and *exec.Cmd for process control
+func (c *ADBClient) StartLogcat(deviceID string, filters []string) (io.ReadCloser, *exec.Cmd, error) {
+	args := c.args(deviceID, "logcat")
+	for _, f := range filters {
+		if !logcatFilterPattern.MatchString(f) {
+			return nil, nil, fmt.Errorf("invalid logcat filter: %q", f)
+		}
+		args = append(args, f)
+	}
+
+	cmd := exec.Command(c.ADBPath, args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start logcat: %w", err)
+	}
+
+	return stdout, cmd, nil
+}
+
+// packageNamePattern matches Android package names (args are forwarded to the device shell)
+var packageNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*$`)
+
+// validatePackage rejects anything that isn't a plain package name
+func validatePackage(pkg string) error {
+	if !packageNamePattern.MatchString(pkg) {
+		return fmt.Errorf("invalid package name: %q", pkg)
+	}
+	return nil
+}
+
 // getEnv gets environment variable with fallback default
 func getEnv(key, defaultVal string) string {
 	if val := os.Getenv(key); val != "" {
@@ -325,10 +658,9 @@ func getEnv(key, defaultVal string) string {
 
 // SendTap sends a tap event to the device
 func (c *ADBClient) SendTap(deviceID string, x, y int) error {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "shell", "input", "tap",
-		fmt.Sprintf("%d", x), fmt.Sprintf("%d", y))
-
-	if err := cmd.Run(); err != nil {
+	_, err := c.output(c.args(deviceID, "shell", "input", "tap",
+		fmt.Sprintf("%d", x), fmt.Sprintf("%d", y))...)
+	if err != nil {
 		return fmt.Errorf("tap failed: %w", err)
 	}
 	return nil
@@ -336,12 +668,19 @@ func (c *ADBClient) SendTap(deviceID string, x, y int) error {
 
 // SendSwipe sends a swipe gesture to the device
 func (c *ADBClient) SendSwipe(deviceID string, x1, y1, x2, y2, duration int) error {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "shell", "input", "swipe",
+	return c.SendSwipeContext(context.Background(), deviceID, x1, y1, x2, y2, duration)
+}
+
+// SendSwipeContext is SendSwipe bound to ctx; cancelling kills the adb process
+func (c *ADBClient) SendSwipeContext(ctx context.Context, deviceID string, x1, y1, x2, y2, duration int) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	_, err := c.outputContext(ctx, c.args(deviceID, "shell", "input", "swipe",
 		fmt.Sprintf("%d", x1), fmt.Sprintf("%d", y1),
 		fmt.Sprintf("%d", x2), fmt.Sprintf("%d", y2),
-		fmt.Sprintf("%d", duration))
-
-	if err := cmd.Run(); err != nil {
+		fmt.Sprintf("%d", duration))...)
+	if err != nil {
 		return fmt.Errorf("swipe failed: %w", err)
 	}
 	return nil
@@ -352,9 +691,7 @@ func (c *ADBClient) SendText(deviceID, text string) error {
 	// Escape special characters for shell
 	escapedText := strings.ReplaceAll(text, " ", "%s")
 
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "shell", "input", "text", escapedText)
-
-	if err := cmd.Run(); err != nil {
+	if _, err := c.output(c.args(deviceID, "shell", "input", "text", escapedText)...); err != nil {
 		return fmt.Errorf("text input failed: %w", err)
 	}
 	return nil
@@ -362,53 +699,305 @@ func (c *ADBClient) SendText(deviceID, text string) error {
 
 // SendKey sends a key event to the device
 func (c *ADBClient) SendKey(deviceID string, keycode int) error {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "shell", "input", "keyevent",
-		fmt.Sprintf("%d", keycode))
-
-	if err := cmd.Run(); err != nil {
+	_, err := c.output(c.args(deviceID, "shell", "input", "keyevent",
+		fmt.Sprintf("%d", keycode))...)
+	if err != nil {
 		return fmt.Errorf("key event failed: %w", err)
 	}
 	return nil
 }
 
+// InstallOpts are optional `adb install` flags
+type InstallOpts struct {
+	Reinstall bool `json:"reinstall"` // -r: replace an installed app, keeping its data
+	GrantAll  bool `json:"grant_all"` // -g: grant all runtime permissions
+}
+
 // InstallAPK installs an APK on the device
-func (c *ADBClient) InstallAPK(deviceID, apkPath string) error {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "install", apkPath)
+// Failures carry adb's own message (e.g. "Failure [INSTALL_FAILED_VERSION_DOWNGRADE]")
+func (c *ADBClient) InstallAPK(deviceID, apkPath string, opts InstallOpts) error {
+	return c.InstallAPKContext(context.Background(), deviceID, apkPath, opts)
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("apk install failed: %w", err)
+// InstallAPKContext is InstallAPK bound to ctx; cancelling kills the adb process
+func (c *ADBClient) InstallAPKContext(ctx context.Context, deviceID, apkPath string, opts InstallOpts) error {
+	extra := []string{"install"}
+	if opts.Reinstall {
+		extra = append(extra, "-r")
 	}
-	return nil
+	if opts.GrantAll {
+		extra = append(extra, "-g")
+	}
+	extra = append(extra, apkPath)
+
+	ctx, cancel := context.WithTimeout(ctx, transferTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := c.commandContext(ctx, c.args(deviceID, extra...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("apk install failed: %w", ctxErr)
+	}
+
+	// Older adb versions print Failure on stdout and still exit 0
+	if err == nil && !strings.Contains(stdout.String(), "Failure") {
+		return nil
+	}
+	reason := strings.TrimSpace(stderr.String())
+	if reason == "" {
+		reason = strings.TrimSpace(stdout.String())
+	}
+	if reason == "" && err != nil {
+		reason = err.Error()
+	}
+	return fmt.Errorf("apk install failed: %s", reason)
 }
 
 // PushFile pushes a file to the device
 func (c *ADBClient) PushFile(deviceID, localPath, remotePath string) error {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "push", localPath, remotePath)
+	return c.PushFileContext(context.Background(), deviceID, localPath, remotePath)
+}
 
-	if err := cmd.Run(); err != nil {
+// PushFileContext is PushFile bound to ctx; cancelling kills the adb process
+func (c *ADBClient) PushFileContext(ctx context.Context, deviceID, localPath, remotePath string) error {
+	ctx, cancel := context.WithTimeout(ctx, transferTimeout)
+	defer cancel()
+
+	if _, err := c.outputContext(ctx, c.args(deviceID, "push", localPath, remotePath)...); err != nil {
 		return fmt.Errorf("file push failed: %w", err)
 	}
 	return nil
 }
 
+// PullFile pulls a file from the device to a local path
+func (c *ADBClient) PullFile(deviceID, remotePath, localPath string) error {
+	if _, err := c.outputTimeout(transferTimeout, c.args(deviceID, "pull", remotePath, localPath)...); err != nil {
+		return fmt.Errorf("file pull failed: %w", err)
+	}
+	return nil
+}
+
 // OpenApp opens an app by package name
 func (c *ADBClient) OpenApp(deviceID, packageName string) error {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "shell", "monkey", "-p", packageName, "-c", "android.intent.category.LAUNCHER", "1")
-
-	if err := cmd.Run(); err != nil {
+	if _, err := c.output(c.args(deviceID, "shell", "monkey", "-p", packageName, "-c", "android.intent.category.LAUNCHER", "1")...); err != nil {
 		return fmt.Errorf("app launch failed: %w", err)
 	}
 	return nil
 }
 
+// ListPackages lists installed packages (pm list packages [-3])
+func (c *ADBClient) ListPackages(deviceID string, thirdPartyOnly bool) ([]string, error) {
+	args := c.args(deviceID, "shell", "pm", "list", "packages")
+	if thirdPartyOnly {
+		args = append(args, "-3")
+	}
+
+	output, err := c.output(args...)
+	if err != nil {
+		return nil, fmt.Errorf("list packages failed: %w", err)
+	}
+
+	// Output format: package:com.example.app
+	packages := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "package:") {
+			packages = append(packages, strings.TrimPrefix(line, "package:"))
+		}
+	}
+	return packages, nil
+}
+
+// UninstallApp uninstalls a package
+func (c *ADBClient) UninstallApp(deviceID, pkg string) error {
+	if err := validatePackage(pkg); err != nil {
+		return err
+	}
+
+	output, err := c.outputTimeout(transferTimeout, c.args(deviceID, "uninstall", pkg)...)
+	if err != nil {
+		return fmt.Errorf("uninstall failed: %w", err)
+	}
+	if !strings.Contains(string(output), "Success") {
+		return fmt.Errorf("uninstall failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ForceStop force-stops a package (am force-stop)
+func (c *ADBClient) ForceStop(deviceID, pkg string) error {
+	if err := validatePackage(pkg); err != nil {
+		return err
+	}
+
+	if _, err := c.output(c.args(deviceID, "shell", "am", "force-stop", pkg)...); err != nil {
+		return fmt.Errorf("force-stop failed: %w", err)
+	}
+	return nil
+}
+
+// ClearData clears a package's data and cache (pm clear)
+func (c *ADBClient) ClearData(deviceID, pkg string) error {
+	if err := validatePackage(pkg); err != nil {
+		return err
+	}
+
+	output, err := c.output(c.args(deviceID, "shell", "pm", "clear", pkg)...)
+	if err != nil {
+		return fmt.Errorf("clear data failed: %w", err)
+	}
+	if !strings.Contains(string(output), "Success") {
+		return fmt.Errorf("clear data failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Reboot reboots the device; mode is "" (normal), "recovery" or "bootloader"
+func (c *ADBClient) Reboot(deviceID, mode string) error {
+	args := c.args(deviceID, "reboot")
+	switch mode {
+	case "":
+	case "recovery", "bootloader":
+		args = append(args, mode)
+	default:
+		return fmt.Errorf("invalid reboot mode: %s", mode)
+	}
+
+	if _, err := c.output(args...); err != nil {
+		return fmt.Errorf("reboot failed: %w", err)
+	}
+	return nil
+}
+
+// ScreenPower turns the screen on (KEYCODE_WAKEUP) or off (KEYCODE_SLEEP)
+// Unlike KEYCODE_POWER these are not toggles, so repeated calls are safe
+func (c *ADBClient) ScreenPower(deviceID string, on bool) error {
+	keycode := 223 // KEYCODE_SLEEP
+	if on {
+		keycode = 224 // KEYCODE_WAKEUP
+	}
+
+	if _, err := c.output(c.args(deviceID, "shell", "input", "keyevent", fmt.Sprintf("%d", keycode))...); err != nil {
+		return fmt.Errorf("screen power failed: %w", err)
+	}
+	return nil
+}
+
+// screenSizePattern matches a wm size override like 1080x1920
+var screenSizePattern = regexp.MustCompile(`^[0-9]+x[0-9]+$`)
+
+// SetScreenSize overrides the display size (wm size WxH); "reset" restores the physical size
+func (c *ADBClient) SetScreenSize(deviceID, size string) error {
+	if size != "reset" && !screenSizePattern.MatchString(size) {
+		return fmt.Errorf("invalid screen size: %q (expected WxH or reset)", size)
+	}
+
+	if _, err := c.output(c.args(deviceID, "shell", "wm", "size", size)...); err != nil {
+		return fmt.Errorf("set screen size failed: %w", err)
+	}
+	return nil
+}
+
+// SetDensity overrides the display density (wm density <dpi>); 0 restores the physical density
+func (c *ADBClient) SetDensity(deviceID string, dpi int) error {
+	if dpi < 0 {
+		return fmt.Errorf("invalid density: %d", dpi)
+	}
+
+	value := "reset"
+	if dpi > 0 {
+		value = fmt.Sprintf("%d", dpi)
+	}
+	if _, err := c.output(c.args(deviceID, "shell", "wm", "density", value)...); err != nil {
+		return fmt.Errorf("set density failed: %w", err)
+	}
+	return nil
+}
+
+// ResetDisplay restores the physical screen size and density
+func (c *ADBClient) ResetDisplay(deviceID string) error {
+	if err := c.SetScreenSize(deviceID, "reset"); err != nil {
+		return err
+	}
+	return c.SetDensity(deviceID, 0)
+}
+
+// Rotation modes for Rotate: "" pins the given rotation, "lock" pins the current one, "unlock" restores auto-rotation
+const (
+	RotationLock   = "lock"
+	RotationUnlock = "unlock"
+)
+
+// currentRotationPattern matches the internal display's rotation in dumpsys input
+// ("SurfaceOrientation: 1" before Android 12, "orientation=1" in viewport lines after)
+var currentRotationPattern = regexp.MustCompile(`(?:SurfaceOrientation: |orientation=)([0-3])\b`)
+
+// SetRotation pins the screen to rotation 0-3 (0/180/90/270 are 0/2/1/3) with auto-rotation off
+func (c *ADBClient) SetRotation(deviceID string, rotation int) error {
+	if rotation < 0 || rotation > 3 {
+		return fmt.Errorf("invalid rotation: %d (expected 0-3)", rotation)
+	}
+
+	if _, err := c.output(c.args(deviceID, "shell", "settings", "put", "system", "accelerometer_rotation", "0")...); err != nil {
+		return fmt.Errorf("disable auto-rotation failed: %w", err)
+	}
+	if _, err := c.output(c.args(deviceID, "shell", "settings", "put", "system", "user_rotation", fmt.Sprintf("%d", rotation))...); err != nil {
+		return fmt.Errorf("set rotation failed: %w", err)
+	}
+	return nil
+}
+
+// SetRotationLock pins the current rotation (locked) or restores auto-rotation (unlocked)
+func (c *ADBClient) SetRotationLock(deviceID string, locked bool) error {
+	if !locked {
+		if _, err := c.output(c.args(deviceID, "shell", "settings", "put", "system", "accelerometer_rotation", "1")...); err != nil {
+			return fmt.Errorf("enable auto-rotation failed: %w", err)
+		}
+		return nil
+	}
+
+	// Turning auto-rotation off alone would snap to the stale user_rotation, so pin what's on screen
+	output, err := c.output(c.args(deviceID, "shell", "dumpsys", "input")...)
+	if err != nil {
+		return fmt.Errorf("read rotation failed: %w", err)
+	}
+	match := currentRotationPattern.FindSubmatch(output)
+	if match == nil {
+		return fmt.Errorf("current rotation not found in dumpsys input")
+	}
+	return c.SetRotation(deviceID, int(match[1][0]-'0'))
+}
+
+// Rotate applies a rotation mode: RotationLock, RotationUnlock, or "" to pin rotation (0-3)
+func (c *ADBClient) Rotate(deviceID, mode string, rotation int) error {
+	switch mode {
+	case "":
+		return c.SetRotation(deviceID, rotation)
+	case RotationLock:
+		return c.SetRotationLock(deviceID, true)
+	case RotationUnlock:
+		return c.SetRotationLock(deviceID, false)
+	default:
+		return fmt.Errorf("invalid rotation mode: %s (expected %s or %s)", mode, RotationLock, RotationUnlock)
+	}
+}
+
+// GetScreenResolution returns the current screen resolution (override size if set)
+func (c *ADBClient) GetScreenResolution(deviceID string) (string, error) {
+	return c.getScreenResolution(deviceID)
+}
+
 // Forward creates ADB port forwarding from local TCP port to remote abstract socket
 // Example: adb -s <deviceID> forward tcp:27183 localabstract:scrcpy
 func (c *ADBClient) Forward(deviceID string, localPort int, remoteSocket string) error {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "forward",
+	_, err := c.output(c.args(deviceID, "forward",
 		fmt.Sprintf("tcp:%d", localPort),
-		fmt.Sprintf("localabstract:%s", remoteSocket))
-
-	if err := cmd.Run(); err != nil {
+		fmt.Sprintf("localabstract:%s", remoteSocket))...)
+	if err != nil {
 		return fmt.Errorf("adb forward failed: %w", err)
 	}
 	return nil
@@ -416,26 +1005,166 @@ func (c *ADBClient) Forward(deviceID string, localPort int, remoteSocket string)
 
 // RemoveForward removes ADB port forwarding for the specified local port
 func (c *ADBClient) RemoveForward(deviceID string, localPort int) error {
-	cmd := exec.Command(c.ADBPath, "-s", deviceID, "forward", "--remove",
-		fmt.Sprintf("tcp:%d", localPort))
-
-	if err := cmd.Run(); err != nil {
+	_, err := c.output(c.args(deviceID, "forward", "--remove",
+		fmt.Sprintf("tcp:%d", localPort))...)
+	if err != nil {
 		return fmt.Errorf("adb forward remove failed: %w", err)
 	}
 	return nil
 }
 
+// scrcpyServerPattern matches the server's command line but not the shell running pkill
+// (the bracket keeps "pkill -f ..." itself from matching and killing its own shell)
+const scrcpyServerPattern = "com.genymobile.scrcp[y]"
+
+// KillScrcpyServers kills scrcpy server processes left on a device and removes its scrcpy forwards
+// Leftovers from crashed or force-killed sessions hold sockets ("address already in use")
+func (c *ADBClient) KillScrcpyServers(deviceID string) error {
+	// pkill exits 1 when nothing matched
+	if _, err := c.output(c.args(deviceID, "shell", "pkill -f '"+scrcpyServerPattern+"' || true")...); err != nil {
+		return fmt.Errorf("kill scrcpy servers failed: %w", err)
+	}
+
+	// forward --list prints "<serial> tcp:<port> localabstract:scrcpy_<scid>" for every device
+	output, err := c.output(c.args("", "forward", "--list")...)
+	if err != nil {
+		return fmt.Errorf("adb forward list failed: %w", err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != deviceID || !strings.HasPrefix(fields[2], "localabstract:scrcpy") {
+			continue
+		}
+		port, err := strconv.Atoi(strings.TrimPrefix(fields[1], "tcp:"))
+		if err != nil {
+			continue
+		}
+		if err := c.RemoveForward(deviceID, port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WakeAndUnlock turns the screen on and dismisses the keyguard, so capture doesn't start on a
+// black or lock screen. KEYCODE_WAKEUP is a no-op on an awake screen (unlike POWER, which toggles).
+// A secure lock (PIN/pattern) only brings up its bouncer; it can't be bypassed from adb.
+func (c *ADBClient) WakeAndUnlock(deviceID string) error {
+	if _, err := c.output(c.args(deviceID, "shell", "input", "keyevent", "KEYCODE_WAKEUP")...); err != nil {
+		return fmt.Errorf("wake failed: %w", err)
+	}
+	// wm dismiss-keyguard exists since Android 8; its error text goes to stdout
+	output, err := c.output(c.args(deviceID, "shell", "wm", "dismiss-keyguard")...)
+	if err != nil {
+		return fmt.Errorf("dismiss keyguard failed: %w", err)
+	}
+	if out := strings.TrimSpace(string(output)); strings.Contains(out, "Unknown command") || strings.HasPrefix(out, "Error") {
+		return fmt.Errorf("dismiss keyguard failed: %s", out)
+	}
+	return nil
+}
+
+// Connect connects to a device over WiFi (adb connect ip:port)
+// adb exits 0 even when the connection fails, so the output is inspected
+func (c *ADBClient) Connect(ip string, port int) error {
+	addr := fmt.Sprintf("%s:%d", ip, port)
+	output, err := c.runWireless("connect", addr)
+	if err != nil {
+		return err
+	}
+
+	// "already connected to ip:port" is fine - device is reachable
+	if strings.Contains(output, "already connected") || strings.HasPrefix(output, "connected to") {
+		return nil
+	}
+	return fmt.Errorf("adb connect %s failed: %s", addr, output)
+}
+
+// Pair pairs with a device using Android 11+ wireless debugging
+// ipPort is the pairing address shown on the device (differs from the connect port)
+func (c *ADBClient) Pair(ipPort, code string) error {
+	output, err := c.runWireless("pair", ipPort, code)
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(output, "Successfully paired") {
+		return nil
+	}
+	return fmt.Errorf("adb pair %s failed: %s", ipPort, output)
+}
+
+// Disconnect disconnects a WiFi device (adb disconnect ip:port)
+func (c *ADBClient) Disconnect(ip string, port int) error {
+	addr := fmt.Sprintf("%s:%d", ip, port)
+	output, err := c.runWireless("disconnect", addr)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(output, "error") {
+		return fmt.Errorf("adb disconnect %s failed: %s", addr, output)
+	}
+	return nil
+}
+
+// runWireless runs an adb connect/pair/disconnect command with a timeout
+// Returns the trimmed combined output
+func (c *ADBClient) runWireless(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), wirelessTimeout)
+	defer cancel()
+
+	output, err := c.commandContext(ctx, c.args("", args...)...).CombinedOutput()
+	outStr := strings.TrimSpace(string(output))
+
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("adb %s timed out after %v: %w", strings.Join(args[:2], " "), wirelessTimeout, ctx.Err())
+	}
+	if err != nil {
+		return "", fmt.Errorf("adb %s failed: %w, output: %s", args[0], err, outStr)
+	}
+	return outStr, nil
+}
+
+// Reverse creates ADB reverse forwarding from a device abstract socket to a local TCP port
+// Example: adb -s <deviceID> reverse localabstract:agent tcp:8080
+func (c *ADBClient) Reverse(deviceID string, remoteSocket string, localPort int) error {
+	_, err := c.output(c.args(deviceID, "reverse",
+		fmt.Sprintf("localabstract:%s", remoteSocket),
+		fmt.Sprintf("tcp:%d", localPort))...)
+	if err != nil {
+		return fmt.Errorf("adb reverse failed: %w", err)
+	}
+	return nil
+}
+
+// RemoveReverse removes ADB reverse forwarding for the specified device socket
+func (c *ADBClient) RemoveReverse(deviceID, remoteSocket string) error {
+	_, err := c.output(c.args(deviceID, "reverse", "--remove",
+		fmt.Sprintf("localabstract:%s", remoteSocket))...)
+	if err != nil {
+		return fmt.Errorf("adb reverse remove failed: %w", err)
+	}
+	return nil
+}
+
 // ExecuteCommandBackground starts a non-blocking shell command on the device
+// output receives the command's stdout and stderr; nil sends stderr to the console
 // Returns the exec.Cmd for process management (caller must handle cleanup)
-func (c *ADBClient) ExecuteCommandBackground(deviceID string, args []string) (*exec.Cmd, error) {
+func (c *ADBClient) ExecuteCommandBackground(deviceID string, args []string, output io.Writer) (*exec.Cmd, error) {
 	// Build full command: adb -s <deviceID> shell <args...>
-	fullArgs := []string{"-s", deviceID, "shell"}
+	fullArgs := c.args(deviceID, "shell")
 	fullArgs = append(fullArgs, args...)
 
 	cmd := exec.Command(c.ADBPath, fullArgs...)
 
-	// Capture stderr for debugging
-	cmd.Stderr = os.Stderr
+	// Capture output for debugging
+	if output != nil {
+		cmd.Stdout = output
+		cmd.Stderr = output
+	} else {
+		cmd.Stderr = os.Stderr
+	}
 
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start background command: %w", err)
@@ -0,0 +1,270 @@
+package adb
+
+import (
+	"androidcontrol/models"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// One phone attached over USB and over WiFi, plus an unrelated USB phone
+const mixedDevicesFixture = `List of devices attached
+R58M123ABC             device usb:1-1 product:beyond1 model:SM_G973F device:beyond1 transport_id:1
+192.168.1.50:5555      device product:beyond1 model:SM_G973F device:beyond1 transport_id:2
+ZY22BCDEF              device usb:1-2 product:ocean model:moto_g7 device:ocean transport_id:3
+`
+
+// stubSerials replaces the getprop serial read for the duration of a test
+func stubSerials(t *testing.T, fn func(adbDeviceID string) (string, error)) {
+	t.Helper()
+	orig := readSerial
+	readSerial = func(_ *ADBClient, adbDeviceID string) (string, error) { return fn(adbDeviceID) }
+	t.Cleanup(func() { readSerial = orig })
+}
+
+func parseFixture(t *testing.T, c *ADBClient) []models.Device {
+	t.Helper()
+	devices, err := c.parseDeviceList(mixedDevicesFixture)
+	if err != nil {
+		t.Fatalf("parseDeviceList: %v", err)
+	}
+	if len(devices) != 3 {
+		t.Fatalf("parsed %d devices, want 3", len(devices))
+	}
+	return devices
+}
+
+func adbIDs(devices []models.Device) []string {
+	ids := make([]string, len(devices))
+	for i, d := range devices {
+		ids[i] = d.ADBDeviceID
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, devices []models.Device, want ...string) {
+	t.Helper()
+	got := adbIDs(devices)
+	if len(got) != len(want) {
+		t.Fatalf("devices = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("devices = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDeduplicatePrefersWiFi(t *testing.T) {
+	stubSerials(t, func(id string) (string, error) {
+		if id == "ZY22BCDEF" {
+			return "ZY22BCDEF", nil
+		}
+		return "R58M123ABC", nil // USB and WiFi report the same hardware serial
+	})
+
+	c := NewADBClient()
+	devices := c.deduplicateDevices(parseFixture(t, c), NewPropertyCache())
+	assertIDs(t, devices, "192.168.1.50:5555", "ZY22BCDEF")
+	if devices[0].HardwareSerial != "R58M123ABC" || devices[0].ConnectionType != models.ConnectionTypeWiFi {
+		t.Errorf("merged device = %+v", devices[0])
+	}
+}
+
+func TestDeduplicateFallsBackToLastKnownSerial(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(id string) (string, error)
+	}{
+		{"empty getprop", func(string) (string, error) { return "", nil }},
+		{"getprop error", func(string) (string, error) { return "", errors.New("device offline") }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stubSerials(t, tc.read)
+
+			cache := NewPropertyCache()
+			cache.Remember("R58M123ABC", "R58M123ABC")
+			cache.Remember("192.168.1.50:5555", "R58M123ABC")
+
+			c := NewADBClient()
+			devices := c.deduplicateDevices(parseFixture(t, c), cache)
+			assertIDs(t, devices, "192.168.1.50:5555", "ZY22BCDEF")
+			if devices[0].HardwareSerial != "R58M123ABC" {
+				t.Errorf("hardware serial = %q, want R58M123ABC", devices[0].HardwareSerial)
+			}
+			// No serial ever seen: keyed by its ADB ID, not merged with anything
+			if devices[1].HardwareSerial != "ZY22BCDEF" {
+				t.Errorf("hardware serial = %q, want the ADB ID", devices[1].HardwareSerial)
+			}
+		})
+	}
+}
+
+func TestDeduplicateWithoutSerialKeepsBoth(t *testing.T) {
+	stubSerials(t, func(string) (string, error) { return "", nil })
+
+	c := NewADBClient()
+	devices := c.deduplicateDevices(parseFixture(t, c), NewPropertyCache())
+	assertIDs(t, devices, "R58M123ABC", "192.168.1.50:5555", "ZY22BCDEF")
+}
+
+func TestPropertyCacheKeepsLastKnownSerial(t *testing.T) {
+	cache := NewPropertyCache()
+	cache.setSerial("R58M123ABC", "R58M123ABC")
+	cache.set("R58M123ABC", StaticProps{Manufacturer: "samsung"})
+
+	cache.retain(nil) // USB cable pulled
+	if _, ok := cache.serial("R58M123ABC"); ok {
+		t.Error("retain kept the serial of a disconnected device")
+	}
+	if known, ok := cache.lastKnownSerial("R58M123ABC"); !ok || known != "R58M123ABC" {
+		t.Errorf("last known after retain = %q, %t", known, ok)
+	}
+
+	cache.Clear()
+	if _, ok := cache.get("R58M123ABC"); ok {
+		t.Error("Clear kept static properties")
+	}
+	if known, ok := cache.lastKnownSerial("R58M123ABC"); !ok || known != "R58M123ABC" {
+		t.Errorf("last known after Clear = %q, %t", known, ok)
+	}
+
+	// A cleared cache re-reads the serial, then still falls back to lastKnown when getprop fails
+	stubSerials(t, func(string) (string, error) { return "", nil })
+	if got := NewADBClient().getSerialNumber("R58M123ABC", cache); got != "R58M123ABC" {
+		t.Errorf("getSerialNumber = %q, want R58M123ABC", got)
+	}
+}
+
+func TestPropertyCacheForgetsNetworkSerialWhenDeviceDrops(t *testing.T) {
+	const wifiID = "192.168.1.50:5555"
+	online := []models.Device{{ADBDeviceID: wifiID, Status: models.DeviceStatusOnline}}
+	tests := []struct {
+		name    string
+		devices []models.Device
+	}{
+		{"disconnected", nil},
+		{"offline", []models.Device{{ADBDeviceID: wifiID, Status: models.DeviceStatusOffline}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := NewPropertyCache()
+			cache.setSerial(wifiID, "R58M123ABC")
+
+			cache.retain(online)
+			if known, ok := cache.lastKnownSerial(wifiID); !ok || known != "R58M123ABC" {
+				t.Fatalf("last known while online = %q, %t", known, ok)
+			}
+
+			cache.retain(tc.devices)
+			if _, ok := cache.serial(wifiID); ok {
+				t.Error("retain kept the serial of a dropped network device")
+			}
+			if known, ok := cache.lastKnownSerial(wifiID); ok {
+				t.Errorf("last known = %q, want it dropped: the address may come back as another phone", known)
+			}
+
+			// Another phone takes the address and getprop fails: it must not inherit the old serial
+			stubSerials(t, func(string) (string, error) { return "", errors.New("device offline") })
+			if got := NewADBClient().getSerialNumber(wifiID, cache); got != "" {
+				t.Errorf("getSerialNumber = %q, want no serial", got)
+			}
+		})
+	}
+}
+
+func TestForEachParallelOrderIsDeterministic(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const n = 50
+	delays := make([]time.Duration, n)
+	for i := range delays {
+		delays[i] = time.Duration(rng.Intn(2000)) * time.Microsecond
+	}
+
+	out := make([]int, n)
+	forEachParallel(n, func(i int) {
+		time.Sleep(delays[i]) // Workers finish in random order
+		out[i] = i * i
+	})
+	for i, v := range out {
+		if v != i*i {
+			t.Fatalf("out[%d] = %d, want %d", i, v, i*i)
+		}
+	}
+}
+
+func TestDeduplicateOrderWithRandomCompletion(t *testing.T) {
+	var fixture strings.Builder
+	fixture.WriteString("List of devices attached\n")
+	var want []string
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("SERIAL%02d", i)
+		fmt.Fprintf(&fixture, "%s device usb:1-%d model:Pixel_%d\n", id, i, i)
+		want = append(want, id)
+	}
+
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(2))
+	stubSerials(t, func(id string) (string, error) {
+		mu.Lock()
+		delay := time.Duration(rng.Intn(2000)) * time.Microsecond
+		mu.Unlock()
+		time.Sleep(delay)
+		return id, nil
+	})
+
+	c := NewADBClient()
+	for run := 0; run < 5; run++ {
+		devices, err := c.parseDeviceList(fixture.String())
+		if err != nil {
+			t.Fatalf("parseDeviceList: %v", err)
+		}
+		assertIDs(t, c.deduplicateDevices(devices, nil), want...)
+	}
+}
+
+// BenchmarkForEachParallel compares a scan of 16 devices with a 2ms adb round-trip each
+// against the sequential loop it replaced
+func BenchmarkForEachParallel(b *testing.B) {
+	const devices = 16
+	roundTrip := func(int) { time.Sleep(2 * time.Millisecond) }
+
+	b.Run("sequential", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for i := 0; i < devices; i++ {
+				roundTrip(i)
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			forEachParallel(devices, roundTrip)
+		}
+	})
+}
+
+// BenchmarkDeduplicateDevices measures the serial pass of a scan with a stubbed 2ms getprop
+func BenchmarkDeduplicateDevices(b *testing.B) {
+	orig := readSerial
+	readSerial = func(_ *ADBClient, adbDeviceID string) (string, error) {
+		time.Sleep(2 * time.Millisecond)
+		return adbDeviceID, nil
+	}
+	defer func() { readSerial = orig }()
+
+	c := NewADBClient()
+	devices := make([]models.Device, 16)
+	for i := range devices {
+		devices[i] = models.Device{ADBDeviceID: fmt.Sprintf("SERIAL%02d", i), Status: models.DeviceStatusOnline}
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c.deduplicateDevices(devices, nil)
+	}
+}
@@ -0,0 +1,97 @@
+package adb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// "TOTAL PSS:   123456" (Android 10+ App Summary) or the "TOTAL" row of the PSS table
+var (
+	meminfoTotalPSS = regexp.MustCompile(`TOTAL PSS:\s+(\d+)`)
+	meminfoTotalRow = regexp.MustCompile(`(?m)^\s*TOTAL\s+(\d+)`)
+)
+
+// GetProcessStats samples an app's CPU (top) and memory (dumpsys meminfo, PSS in KB)
+// CPU is summed over the app's processes (pkg and pkg:<name>) and is relative to one
+// core, so it can exceed 100 on multi-core devices
+func (c *ADBClient) GetProcessStats(deviceID, pkg string) (cpuPct float64, memKB int, err error) {
+	if err := validatePackage(pkg); err != nil {
+		return 0, 0, err
+	}
+
+	meminfo, err := c.output(c.args(deviceID, "shell", "dumpsys", "meminfo", pkg)...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("dumpsys meminfo failed: %w", err)
+	}
+	memKB, err = parseMeminfoPSS(string(meminfo))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", pkg, err)
+	}
+
+	top, err := c.output(c.args(deviceID, "shell", "top", "-n", "1", "-b")...)
+	if err != nil {
+		// Older toolbox top has no -b
+		top, err = c.output(c.args(deviceID, "shell", "top", "-n", "1")...)
+		if err != nil {
+			return 0, 0, fmt.Errorf("top failed: %w", err)
+		}
+	}
+	return parseTopCPU(string(top), pkg), memKB, nil
+}
+
+// parseMeminfoPSS extracts the total PSS in KB from dumpsys meminfo <pkg>
+func parseMeminfoPSS(output string) (int, error) {
+	if strings.Contains(output, "No process found") {
+		return 0, fmt.Errorf("process not running")
+	}
+
+	match := meminfoTotalPSS.FindStringSubmatch(output)
+	if match == nil {
+		match = meminfoTotalRow.FindStringSubmatch(output)
+	}
+	if match == nil {
+		return 0, fmt.Errorf("no TOTAL in meminfo output")
+	}
+	return strconv.Atoi(match[1])
+}
+
+// parseTopCPU sums the CPU column of top rows whose process name is pkg or pkg:<sub>
+// Handles toybox ("S[%CPU]" header over two columns) and toolbox ("CPU%") layouts
+func parseTopCPU(output, pkg string) float64 {
+	cpuCol := -1
+	var total float64
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if cpuCol < 0 {
+			// Header: find the CPU column
+			for i, f := range fields {
+				switch {
+				case f == "S[%CPU]":
+					cpuCol = i + 1 // State and CPU are separate columns in the rows
+				case f == "%CPU", f == "[%CPU]", f == "CPU%":
+					cpuCol = i
+				}
+			}
+			continue
+		}
+
+		if len(fields) <= cpuCol {
+			continue
+		}
+		name := fields[len(fields)-1]
+		if name != pkg && !strings.HasPrefix(name, pkg+":") {
+			continue
+		}
+		cpu, err := strconv.ParseFloat(strings.TrimSuffix(fields[cpuCol], "%"), 64)
+		if err == nil {
+			total += cpu
+		}
+	}
+	return total
+}
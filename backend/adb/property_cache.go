@@ -0,0 +1,147 @@
+package adb
+
+import (
+	"androidcontrol/models"
+	"sync"
+)
+
+// StaticProps are device properties that don't change while a device stays connected
+type StaticProps struct {
+	AndroidVersion string
+	Manufacturer   string
+	SDKInt         int
+	CPUABI         string
+	Resolution     string
+}
+
+// PropertyCache remembers hardware serials and static properties between scans
+// so a scan only has to query volatile values (battery). A nil cache disables caching.
+type PropertyCache struct {
+	mu      sync.Mutex
+	serials map[string]string      // ADB device ID -> hardware serial
+	props   map[string]StaticProps // Hardware serial -> static properties
+
+	// Last serial ever read per ADB device ID. Survives Clear and USB disconnects so a
+	// transient getprop failure doesn't split one phone into USB + WiFi duplicates.
+	// Network IDs lose it when they drop (see retain).
+	lastKnown map[string]string
+}
+
+func NewPropertyCache() *PropertyCache {
+	return &PropertyCache{
+		serials:   make(map[string]string),
+		props:     make(map[string]StaticProps),
+		lastKnown: make(map[string]string),
+	}
+}
+
+// Remember seeds the last-known serial of an ADB device ID (e.g. from the devices table)
+func (pc *PropertyCache) Remember(adbDeviceID, hwSerial string) {
+	if pc == nil || adbDeviceID == "" || hwSerial == "" {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.lastKnown[adbDeviceID] = hwSerial
+}
+
+// lastKnownSerial returns the last serial read for an ADB device ID, even if it has since disconnected
+func (pc *PropertyCache) lastKnownSerial(adbDeviceID string) (string, bool) {
+	if pc == nil {
+		return "", false
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	hwSerial, ok := pc.lastKnown[adbDeviceID]
+	return hwSerial, ok
+}
+
+// Clear drops everything so the next scan re-queries all properties
+func (pc *PropertyCache) Clear() {
+	if pc == nil {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.serials = make(map[string]string)
+	pc.props = make(map[string]StaticProps)
+}
+
+// Invalidate drops the cached properties of one device (e.g. after changing its screen size)
+func (pc *PropertyCache) Invalidate(hwSerial string) {
+	if pc == nil {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	delete(pc.props, hwSerial)
+}
+
+// serial returns the cached hardware serial for an ADB device ID
+func (pc *PropertyCache) serial(adbDeviceID string) (string, bool) {
+	if pc == nil {
+		return "", false
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	hwSerial, ok := pc.serials[adbDeviceID]
+	return hwSerial, ok
+}
+
+func (pc *PropertyCache) setSerial(adbDeviceID, hwSerial string) {
+	if pc == nil {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.serials[adbDeviceID] = hwSerial
+	pc.lastKnown[adbDeviceID] = hwSerial
+}
+
+// retain forgets serial mappings for ADB IDs that are no longer connected
+// A network ip:port that disconnects or goes offline also loses its last-known serial:
+// the address can come back as a different phone, which must not merge into the old one
+func (pc *PropertyCache) retain(devices []models.Device) {
+	if pc == nil {
+		return
+	}
+	listed := make(map[string]bool, len(devices))
+	online := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		listed[device.ADBDeviceID] = true
+		online[device.ADBDeviceID] = device.Status == models.DeviceStatusOnline
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for id := range pc.serials {
+		if !listed[id] || (isWiFiConnection(id) && !online[id]) {
+			delete(pc.serials, id)
+		}
+	}
+	for id := range pc.lastKnown {
+		if isWiFiConnection(id) && !online[id] {
+			delete(pc.lastKnown, id)
+		}
+	}
+}
+
+// get returns the cached static properties for a hardware serial
+func (pc *PropertyCache) get(hwSerial string) (StaticProps, bool) {
+	if pc == nil {
+		return StaticProps{}, false
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	props, ok := pc.props[hwSerial]
+	return props, ok
+}
+
+func (pc *PropertyCache) set(hwSerial string, props StaticProps) {
+	if pc == nil {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.props[hwSerial] = props
+}
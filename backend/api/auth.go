@@ -0,0 +1,67 @@
+package api
+
+import (
+	"androidcontrol/models"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenMatches compares tokens in constant time
+func tokenMatches(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// AuthMiddleware requires "Authorization: Bearer <token>" when a token is configured
+func AuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		header := c.GetHeader("Authorization")
+		got, found := strings.CutPrefix(header, "Bearer ")
+		if !found || !tokenMatches(strings.TrimSpace(got), token) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse("unauthorized"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// wsTokenProtocol checks the WebSocket token from ?token= or Sec-WebSocket-Protocol
+// Browsers can't set headers on WebSocket, so the token may be passed as a subprotocol.
+// Returns the matched subprotocol (to echo back, "" if the query param was used) and ok.
+func wsTokenProtocol(r *http.Request, token string) (string, bool) {
+	if token == "" {
+		return "", true
+	}
+
+	if tokenMatches(r.URL.Query().Get("token"), token) {
+		return "", true
+	}
+
+	for _, proto := range websocketSubprotocols(r) {
+		if tokenMatches(proto, token) {
+			return proto, true
+		}
+	}
+	return "", false
+}
+
+// websocketSubprotocols splits the Sec-WebSocket-Protocol header values
+func websocketSubprotocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, proto := range strings.Split(header, ",") {
+			if proto = strings.TrimSpace(proto); proto != "" {
+				protocols = append(protocols, proto)
+			}
+		}
+	}
+	return protocols
+}
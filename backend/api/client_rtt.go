@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/binary"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Ping payloads carry the send time (UnixNano, big-endian) so the pong yields the RTT
+const rttPayloadSize = 8
+
+// clientRTT holds a client's ping round-trip times, written by readPump's pong handler
+type clientRTT struct {
+	last    atomic.Int64 // Latest sample in nanoseconds
+	smooth  atomic.Int64 // EWMA (1/8 gain, like TCP's SRTT) in nanoseconds
+	samples atomic.Int64
+}
+
+// pingPayload returns the ping body for now
+func pingPayload(now time.Time) []byte {
+	payload := make([]byte, rttPayloadSize)
+	binary.BigEndian.PutUint64(payload, uint64(now.UnixNano()))
+	return payload
+}
+
+// observePong records the RTT of a pong echoing a pingPayload
+// Pongs without a timestamp (unsolicited, or from older pings) are ignored
+func (r *clientRTT) observePong(appData string, now time.Time) {
+	if len(appData) != rttPayloadSize {
+		return
+	}
+	sent := int64(binary.BigEndian.Uint64([]byte(appData)))
+	rtt := now.UnixNano() - sent
+	if rtt < 0 || rtt > int64(pongWait) {
+		return // Not one of our pings
+	}
+
+	r.last.Store(rtt)
+	if r.samples.Add(1) == 1 {
+		r.smooth.Store(rtt)
+		return
+	}
+	srtt := r.smooth.Load()
+	r.smooth.Store(srtt + (rtt-srtt)/8)
+}
+
+// ClientRTTStats is one client's WebSocket round-trip time in /api/metrics
+type ClientRTTStats struct {
+	ClientID   string  `json:"client_id"`
+	RemoteAddr string  `json:"remote_addr"`
+	RTTMs      float64 `json:"rtt_ms"`
+	SmoothMs   float64 `json:"srtt_ms"`
+	Samples    int64   `json:"samples"`
+}
+
+// RTTSummary aggregates client round-trip times (clients without samples are listed but not aggregated)
+type RTTSummary struct {
+	AvgMs   float64          `json:"avg_ms"`
+	MaxMs   float64          `json:"max_ms"`
+	Clients []ClientRTTStats `json:"clients"`
+}
+
+// RTTSummary reports per-client and aggregate ping round-trip times
+func (h *WebSocketHub) RTTSummary() RTTSummary {
+	h.mu.RLock()
+	summary := RTTSummary{Clients: make([]ClientRTTStats, 0, len(h.clients))}
+	for client := range h.clients {
+		summary.Clients = append(summary.Clients, ClientRTTStats{
+			ClientID:   client.id,
+			RemoteAddr: client.conn.RemoteAddr().String(),
+			RTTMs:      nanosToMs(client.rtt.last.Load()),
+			SmoothMs:   nanosToMs(client.rtt.smooth.Load()),
+			Samples:    client.rtt.samples.Load(),
+		})
+	}
+	h.mu.RUnlock()
+
+	// Slowest first - that's who the lag report is about
+	sort.Slice(summary.Clients, func(i, j int) bool {
+		return summary.Clients[i].SmoothMs > summary.Clients[j].SmoothMs
+	})
+
+	var total float64
+	measured := 0
+	for _, c := range summary.Clients {
+		if c.Samples == 0 {
+			continue
+		}
+		total += c.SmoothMs
+		measured++
+		summary.MaxMs = max(summary.MaxMs, c.SmoothMs)
+	}
+	if measured > 0 {
+		summary.AvgMs = total / float64(measured)
+	}
+	return summary
+}
+
+func nanosToMs(n int64) float64 {
+	return float64(n) / float64(time.Millisecond)
+}
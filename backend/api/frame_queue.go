@@ -0,0 +1,156 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// perDeviceFrameQueue bounds pending binary frames per device on one client
+// Real-time mode: a slow client drops that device's oldest frames, not other devices'
+const perDeviceFrameQueue = 8
+
+// frameQueue holds pending binary frames per device and hands them out round-robin,
+// so one high-FPS device can't starve the others sharing a client connection
+type frameQueue struct {
+	mu     sync.Mutex
+	queues map[string][][]byte
+	order  []string // Devices with a queue, in round-robin order
+	next   int
+	ready  chan struct{} // Wakes writePump (1-buffered, coalesced)
+	space  chan struct{} // Closed by pop to wake blocked pushers (nil when nobody waits)
+}
+
+func newFrameQueue() *frameQueue {
+	return &frameQueue{
+		queues: make(map[string][][]byte),
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// push appends a frame for a device, making room according to policy when full
+// Returns false if a frame was dropped
+func (q *frameQueue) push(deviceID string, frame []byte, policy SendPolicy) bool {
+	q.mu.Lock()
+	if _, ok := q.queues[deviceID]; !ok {
+		q.order = append(q.order, deviceID)
+		q.queues[deviceID] = nil
+	}
+
+	if policy == SendPolicyBlock && !q.waitForSpace(deviceID, time.Now().Add(sendBlockDeadline)) {
+		q.mu.Unlock()
+		return true // Unsubscribed while waiting - nothing to deliver
+	}
+
+	delivered := true
+	queue := q.queues[deviceID]
+	if len(queue) >= perDeviceFrameQueue {
+		delivered = false
+		if policy == SendPolicyDropNewest {
+			q.mu.Unlock()
+			return false
+		}
+		// Drop oldest (also the fallback once a blocked push hits its deadline)
+		queue[0] = nil // Release for GC
+		queue = queue[1:]
+	}
+	q.queues[deviceID] = append(queue, frame)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return delivered
+}
+
+// waitForSpace waits until the device's queue has room or the deadline passes (must hold mu)
+// Returns false if the device was removed while waiting
+func (q *frameQueue) waitForSpace(deviceID string, deadline time.Time) bool {
+	for len(q.queues[deviceID]) >= perDeviceFrameQueue {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return true
+		}
+		if q.space == nil {
+			q.space = make(chan struct{})
+		}
+		space := q.space
+		q.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-space:
+		case <-timer.C:
+		}
+		timer.Stop()
+
+		q.mu.Lock()
+		if _, ok := q.queues[deviceID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// wakePushers releases pushers blocked in waitForSpace (must hold mu)
+func (q *frameQueue) wakePushers() {
+	if q.space != nil {
+		close(q.space)
+		q.space = nil
+	}
+}
+
+// pop returns the next frame, rotating across devices
+func (q *frameQueue) pop() ([]byte, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for range q.order {
+		if q.next >= len(q.order) {
+			q.next = 0
+		}
+		deviceID := q.order[q.next]
+		q.next++
+
+		if queue := q.queues[deviceID]; len(queue) > 0 {
+			frame := queue[0]
+			queue[0] = nil
+			q.queues[deviceID] = queue[1:]
+			q.wakePushers()
+			return frame, true
+		}
+	}
+	return nil, false
+}
+
+// reset drops pending frames for a device (stale for a fresh set of headers)
+func (q *frameQueue) reset(deviceID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.queues[deviceID]; ok {
+		q.queues[deviceID] = nil
+		q.wakePushers()
+	}
+}
+
+// remove forgets a device entirely (on unsubscribe)
+func (q *frameQueue) remove(deviceID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.queues[deviceID]; !ok {
+		return
+	}
+	delete(q.queues, deviceID)
+	q.wakePushers()
+	for i, id := range q.order {
+		if id == deviceID {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			if q.next > i {
+				q.next--
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func frame(i int) []byte {
+	return []byte(fmt.Sprintf("frame-%02d", i))
+}
+
+// drain pops every pending frame
+func drain(q *frameQueue) []string {
+	var frames []string
+	for {
+		f, ok := q.pop()
+		if !ok {
+			return frames
+		}
+		frames = append(frames, string(f))
+	}
+}
+
+func frameNames(from, to int) []string {
+	var names []string
+	for i := from; i < to; i++ {
+		names = append(names, string(frame(i)))
+	}
+	return names
+}
+
+func assertFrames(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("frames = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("frames = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFrameQueueDropOldest(t *testing.T) {
+	const total = perDeviceFrameQueue + 5
+	q := newFrameQueue()
+	for i := 0; i < total; i++ {
+		delivered := q.push("dev", frame(i), SendPolicyDropOldest)
+		if want := i < perDeviceFrameQueue; delivered != want {
+			t.Errorf("push %d delivered = %t, want %t", i, delivered, want)
+		}
+	}
+	assertFrames(t, drain(q), frameNames(total-perDeviceFrameQueue, total))
+}
+
+func TestFrameQueueDropNewest(t *testing.T) {
+	const total = perDeviceFrameQueue + 5
+	q := newFrameQueue()
+	for i := 0; i < total; i++ {
+		delivered := q.push("dev", frame(i), SendPolicyDropNewest)
+		if want := i < perDeviceFrameQueue; delivered != want {
+			t.Errorf("push %d delivered = %t, want %t", i, delivered, want)
+		}
+	}
+	assertFrames(t, drain(q), frameNames(0, perDeviceFrameQueue))
+}
+
+func TestFrameQueueBlockUnblocksOnPop(t *testing.T) {
+	q := newFrameQueue()
+	for i := 0; i < perDeviceFrameQueue; i++ {
+		q.push("dev", frame(i), SendPolicyBlock)
+	}
+
+	done := make(chan bool)
+	go func() { done <- q.push("dev", frame(perDeviceFrameQueue), SendPolicyBlock) }()
+
+	select {
+	case <-done:
+		t.Fatal("push on a full queue returned without waiting")
+	case <-time.After(sendBlockDeadline / 5):
+	}
+
+	if f, _ := q.pop(); string(f) != string(frame(0)) {
+		t.Fatalf("pop = %s, want %s", f, frame(0))
+	}
+	select {
+	case delivered := <-done:
+		if !delivered {
+			t.Error("blocked push dropped a frame after pop made room")
+		}
+	case <-time.After(sendBlockDeadline / 2):
+		t.Fatal("push still blocked after pop made room")
+	}
+	assertFrames(t, drain(q), frameNames(1, perDeviceFrameQueue+1))
+}
+
+func TestFrameQueueBlockFallsBackToDropOldest(t *testing.T) {
+	q := newFrameQueue()
+	for i := 0; i < perDeviceFrameQueue; i++ {
+		q.push("dev", frame(i), SendPolicyBlock)
+	}
+
+	start := time.Now()
+	if q.push("dev", frame(perDeviceFrameQueue), SendPolicyBlock) {
+		t.Error("push past the deadline reported delivered without dropping")
+	}
+	if waited := time.Since(start); waited < sendBlockDeadline {
+		t.Errorf("push gave up after %v, before sendBlockDeadline (%v)", waited, sendBlockDeadline)
+	}
+	assertFrames(t, drain(q), frameNames(1, perDeviceFrameQueue+1))
+}
+
+func TestFrameQueueBlockReturnsOnRemove(t *testing.T) {
+	q := newFrameQueue()
+	for i := 0; i < perDeviceFrameQueue; i++ {
+		q.push("dev", frame(i), SendPolicyBlock)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		q.push("dev", frame(perDeviceFrameQueue), SendPolicyBlock)
+		close(done)
+	}()
+	time.Sleep(sendBlockDeadline / 5)
+	q.remove("dev")
+
+	select {
+	case <-done:
+	case <-time.After(sendBlockDeadline / 2):
+		t.Fatal("push still blocked after the device was removed")
+	}
+	if frames := drain(q); len(frames) != 0 {
+		t.Errorf("removed device still has frames: %v", frames)
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"androidcontrol/models"
+	"androidcontrol/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGroups returns all device groups
+func GetGroups(c *gin.Context, gm *service.GroupManager) {
+	c.JSON(http.StatusOK, models.SuccessResponse(gm.GetAllGroups()))
+}
+
+// GetGroup returns a single device group
+func GetGroup(c *gin.Context, gm *service.GroupManager) {
+	group := gm.GetGroup(c.Param("id"))
+	if group == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("group not found"))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(group))
+}
+
+// CreateGroup creates a device group
+func CreateGroup(c *gin.Context, gm *service.GroupManager) {
+	var req models.GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request: name is required"))
+		return
+	}
+
+	group, err := gm.CreateGroup(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, models.SuccessResponse(group))
+}
+
+// UpdateGroup replaces a device group's name, description and members
+func UpdateGroup(c *gin.Context, gm *service.GroupManager) {
+	var req models.GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request: name is required"))
+		return
+	}
+
+	id := c.Param("id")
+	if gm.GetGroup(id) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("group not found"))
+		return
+	}
+
+	group, err := gm.UpdateGroup(id, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(group))
+}
+
+// DeleteGroup removes a device group
+func DeleteGroup(c *gin.Context, gm *service.GroupManager) {
+	id := c.Param("id")
+	if gm.GetGroup(id) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("group not found"))
+		return
+	}
+
+	if err := gm.DeleteGroup(id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse("group deleted"))
+}
+
+// ExecuteGroupAction dispatches an action to every device in a group
+// Returns one entry per member, like the batch endpoint (offline/unknown members are "skipped")
+func ExecuteGroupAction(c *gin.Context, gm *service.GroupManager, ad *service.ActionDispatcher) {
+	group := gm.GetGroup(c.Param("id"))
+	if group == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("group not found"))
+		return
+	}
+
+	var req models.ActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Action.Type == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+
+	action := &models.Action{
+		ID:        generateActionID(),
+		Type:      req.Action.Type,
+		Params:    req.Action.Params,
+		Timestamp: time.Now().Unix(),
+	}
+
+	if req.Validate {
+		c.JSON(http.StatusOK, models.SuccessResponse(ad.ValidateBatch(group.DeviceIDs, action)))
+		return
+	}
+	if err := service.ValidateParams(action); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(ad.DispatchToGroup(group, action)))
+}
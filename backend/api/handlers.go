@@ -3,8 +3,15 @@ package api
 import (
 	"androidcontrol/models"
 	"androidcontrol/service"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +28,22 @@ func SetupHandlers(router *gin.Engine, dm *service.DeviceManager, ad *service.Ac
 	})
 }
 
+// GetMetrics returns a monitoring snapshot (devices, streams, clients, throughput, goroutines)
+func GetMetrics(c *gin.Context, dm *service.DeviceManager, ss *service.StreamingService, wsHub *WebSocketHub) {
+	total, online := dm.DeviceCounts()
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"devices": gin.H{
+			"total":  total,
+			"online": online,
+		},
+		"streams":           ss.GetStreamSummary(),
+		"websocket_clients": wsHub.ClientCount(),
+		"websocket_rtt":     wsHub.RTTSummary(),
+		"goroutines":        runtime.NumGoroutine(),
+		"timestamp":         time.Now().Unix(),
+	}))
+}
+
 // GetDevices returns all devices
 func GetDevices(c *gin.Context, dm *service.DeviceManager) {
 	devices := dm.GetAllDevices()
@@ -28,8 +51,9 @@ func GetDevices(c *gin.Context, dm *service.DeviceManager) {
 }
 
 // ScanDevices scans for new devices
+// A manual scan re-queries all device properties (bypasses the property cache)
 func ScanDevices(c *gin.Context, dm *service.DeviceManager) {
-	if err := dm.ScanDevices(); err != nil {
+	if err := dm.ScanDevicesWithOpts(service.ScanDevicesOpts{ForceRefresh: true}); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
 		return
 	}
@@ -37,6 +61,450 @@ func ScanDevices(c *gin.Context, dm *service.DeviceManager) {
 	c.JSON(http.StatusOK, models.SuccessResponse(devices))
 }
 
+// defaultWirelessPort is the adb tcpip port used when the request omits one
+const defaultWirelessPort = 5555
+
+// ConnectDevice connects to a device over WiFi and rescans
+func ConnectDevice(c *gin.Context, dm *service.DeviceManager) {
+	var req models.WirelessConnectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+	if req.Port == 0 {
+		req.Port = defaultWirelessPort
+	}
+
+	if err := dm.GetADBClient().Connect(req.IP, req.Port); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	// Rescan so the new device shows up in GetAllDevices
+	if err := dm.ScanDevices(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(dm.GetAllDevices()))
+}
+
+// PairDevice pairs with a device using Android 11+ wireless debugging
+func PairDevice(c *gin.Context, dm *service.DeviceManager) {
+	var req models.PairRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+
+	if err := dm.GetADBClient().Pair(req.Address, req.Code); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse("Paired with "+req.Address))
+}
+
+// DisconnectDevice disconnects a WiFi device and rescans
+func DisconnectDevice(c *gin.Context, dm *service.DeviceManager) {
+	var req models.WirelessConnectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+	if req.Port == 0 {
+		req.Port = defaultWirelessPort
+	}
+
+	// An explicit disconnect shouldn't be undone by the WiFi auto-reconnect
+	dm.ForgetWiFiEndpoint(fmt.Sprintf("%s:%d", req.IP, req.Port))
+
+	if err := dm.GetADBClient().Disconnect(req.IP, req.Port); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	if err := dm.ScanDevices(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(dm.GetAllDevices()))
+}
+
+// GetDeviceFile pulls a file from the device and streams it back
+// Query: remote=<absolute path on device>
+func GetDeviceFile(c *gin.Context, dm *service.DeviceManager) {
+	device := dm.GetDevice(c.Param("device_id"))
+	if device == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	remote := c.Query("remote")
+	if err := validateRemotePath(remote); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	tmpFile, err := os.CreateTemp("", "adb-pull-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	if err := dm.GetADBClient().PullFile(device.ADBDeviceID, remote, tmpPath); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	f, err := os.Open(tmpPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(remote))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, info.Size(), contentType, f, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, path.Base(remote)),
+	})
+}
+
+// validateRemotePath rejects relative paths and ".." traversal in device paths
+func validateRemotePath(remote string) error {
+	if remote == "" {
+		return fmt.Errorf("remote path is required")
+	}
+	if !strings.HasPrefix(remote, "/") {
+		return fmt.Errorf("remote path must be absolute")
+	}
+	if strings.ContainsRune(remote, 0) {
+		return fmt.Errorf("invalid remote path")
+	}
+	for _, part := range strings.Split(remote, "/") {
+		if part == ".." {
+			return fmt.Errorf("remote path must not contain '..'")
+		}
+	}
+	return nil
+}
+
+// GetScreenshot returns a PNG screenshot of the device
+func GetScreenshot(c *gin.Context, dm *service.DeviceManager) {
+	device := dm.GetDevice(c.Param("device_id"))
+	if device == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	png, err := dm.GetADBClient().ScreenCapture(device.ADBDeviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// GetPackages lists installed packages on a device
+// Query: third_party=true to exclude system packages
+func GetPackages(c *gin.Context, dm *service.DeviceManager) {
+	device := dm.GetDevice(c.Param("device_id"))
+	if device == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	packages, err := dm.GetADBClient().ListPackages(device.ADBDeviceID, c.Query("third_party") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(packages))
+}
+
+// GetProcessStats samples an app's CPU and memory once (?package=com.example.app)
+// For a live feed subscribe to stats:<device_id>:<package> over WebSocket
+func GetProcessStats(c *gin.Context, dm *service.DeviceManager, ss *service.StreamingService) {
+	if dm.GetDevice(c.Param("device_id")) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+	pkg := c.Query("package")
+	if pkg == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("package is required"))
+		return
+	}
+
+	stats, err := ss.GetProcessStats(c.Param("device_id"), pkg)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(stats))
+}
+
+// SetDeviceAlias sets a device's friendly name (keyed by hardware serial)
+// Body: {"alias": "Rack 2 - left"} - empty alias restores the model name
+func SetDeviceAlias(c *gin.Context, dm *service.DeviceManager) {
+	if dm.GetDevice(c.Param("device_id")) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	var req models.AliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+
+	device, err := dm.SetAlias(c.Param("device_id"), req.Alias)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(device))
+}
+
+// SetDisplay overrides a device's screen size and/or density (wm size / wm density)
+func SetDisplay(c *gin.Context, dm *service.DeviceManager) {
+	device := dm.GetDevice(c.Param("device_id"))
+	if device == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	var req models.DisplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+	if !req.Reset && req.Size == "" && req.Density == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("size, density or reset is required"))
+		return
+	}
+
+	adbClient := dm.GetADBClient()
+	var err error
+	switch {
+	case req.Reset:
+		err = adbClient.ResetDisplay(device.ADBDeviceID)
+	default:
+		if req.Size != "" {
+			err = adbClient.SetScreenSize(device.ADBDeviceID, req.Size)
+		}
+		if err == nil && req.Density != 0 {
+			err = adbClient.SetDensity(device.ADBDeviceID, req.Density)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	dm.RefreshDisplayInfo(device.ID)
+	c.JSON(http.StatusOK, models.SuccessResponse(dm.GetDevice(device.ID)))
+}
+
+// RotateDevice forces the screen orientation, pins the current one, or restores auto-rotation
+// The stream's SPS changes with the rotation, so clients get a {type:"resolution"} broadcast
+func RotateDevice(c *gin.Context, dm *service.DeviceManager) {
+	device := dm.GetDevice(c.Param("device_id"))
+	if device == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	var req models.RotateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+
+	if req.Mode == "" && req.Rotation == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("rotation (0-3) or mode (lock/unlock) is required"))
+		return
+	}
+
+	rotation := 0
+	if req.Rotation != nil {
+		rotation = *req.Rotation
+	}
+	if err := dm.GetADBClient().Rotate(device.ADBDeviceID, req.Mode, rotation); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse("rotation applied"))
+}
+
+// reverseSocketName is what the device side of a reverse tunnel may be called (localabstract:<name>)
+var reverseSocketName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// AddReverse opens an adb reverse tunnel so an on-device agent can connect back to the backend host
+// Tunnels are removed when the device goes offline or the server shuts down
+func AddReverse(c *gin.Context, dm *service.DeviceManager) {
+	device := dm.GetDevice(c.Param("device_id"))
+	if device == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+	if device.Status != models.DeviceStatusOnline {
+		c.JSON(http.StatusConflict, models.ErrorResponse("device not online"))
+		return
+	}
+
+	var req models.ReverseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("socket and port are required"))
+		return
+	}
+	if !reverseSocketName.MatchString(req.Socket) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("socket must be 1-64 letters, digits, '.', '_' or '-'"))
+		return
+	}
+	if req.Port < 1 || req.Port > 65535 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("port must be between 1 and 65535"))
+		return
+	}
+
+	if err := dm.AddReverse(device.ID, req.Socket, req.Port); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse(fmt.Sprintf("reverse localabstract:%s -> tcp:%d established", req.Socket, req.Port)))
+}
+
+// RemoveReverse tears down an adb reverse tunnel opened by AddReverse
+func RemoveReverse(c *gin.Context, dm *service.DeviceManager) {
+	device := dm.GetDevice(c.Param("device_id"))
+	if device == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	socket := c.Param("socket")
+	if err := dm.RemoveReverse(device.ID, socket); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse(fmt.Sprintf("reverse localabstract:%s removed", socket)))
+}
+
+// PressKey presses a key by name, over the control socket when streaming, else via adb
+func PressKey(c *gin.Context, dm *service.DeviceManager, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+	if dm.GetDevice(deviceID) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	var req models.KeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+
+	keycode, ok := service.KeycodeByName(req.Key)
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(fmt.Sprintf("unknown key: %s (supported: %s)", req.Key, strings.Join(service.KeyNames(), ", "))))
+		return
+	}
+
+	via, err := ss.PressKey(deviceID, keycode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"key": req.Key, "keycode": keycode, "via": via}))
+}
+
+// CleanupDevice kills scrcpy servers and forwards orphaned on a device by crashed sessions
+func CleanupDevice(c *gin.Context, dm *service.DeviceManager, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+	if dm.GetDevice(deviceID) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	if err := ss.CleanupScrcpy(deviceID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrStreamActive) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse("scrcpy leftovers cleaned up on device "+deviceID))
+}
+
+// GetClipboard reads the device clipboard over the scrcpy control socket
+func GetClipboard(c *gin.Context, dm *service.DeviceManager, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+	if dm.GetDevice(deviceID) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	if !ss.HasControl(deviceID) {
+		c.JSON(http.StatusConflict, models.ErrorResponse("control socket not connected (start streaming first)"))
+		return
+	}
+
+	text, err := ss.GetClipboard(deviceID)
+	if err != nil {
+		c.JSON(http.StatusGatewayTimeout, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"text": text}))
+}
+
+// SetClipboard sets the device clipboard (optionally pasting) and waits for the device's ack
+func SetClipboard(c *gin.Context, dm *service.DeviceManager, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+	if dm.GetDevice(deviceID) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	var req models.ClipboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+
+	if !ss.HasControl(deviceID) {
+		c.JSON(http.StatusConflict, models.ErrorResponse("control socket not connected (start streaming first)"))
+		return
+	}
+
+	if err := ss.SendClipboard(deviceID, req.Text, req.Paste, true); err != nil {
+		c.JSON(http.StatusGatewayTimeout, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse("clipboard set"))
+}
+
+// actionErrorStatus maps a dispatch error to an HTTP status (400 for malformed params)
+func actionErrorStatus(err error) int {
+	if errors.Is(err, service.ErrInvalidParams) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 // ExecuteAction executes a single action on a device
 func ExecuteAction(c *gin.Context, dm *service.DeviceManager, ad *service.ActionDispatcher) {
 	var req models.ActionRequest
@@ -53,9 +521,15 @@ func ExecuteAction(c *gin.Context, dm *service.DeviceManager, ad *service.Action
 		Timestamp: time.Now().Unix(),
 	}
 
+	// Dry run: report what would fail without touching the device
+	if req.Validate {
+		c.JSON(http.StatusOK, models.SuccessResponse(ad.ValidateAction(req.DeviceID, action)))
+		return
+	}
+
 	// Dispatch to device
 	if err := ad.DispatchToDevice(req.DeviceID, action); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		c.JSON(actionErrorStatus(err), models.ErrorResponse(err.Error()))
 		return
 	}
 
@@ -78,16 +552,73 @@ func ExecuteBatchAction(c *gin.Context, dm *service.DeviceManager, ad *service.A
 		Timestamp: time.Now().Unix(),
 	}
 
+	if req.Validate {
+		c.JSON(http.StatusOK, models.SuccessResponse(ad.ValidateBatch(req.DeviceIDs, action)))
+		return
+	}
+
 	// Dispatch to all devices
 	actions, err := ad.DispatchBatch(req.DeviceIDs, action)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		c.JSON(actionErrorStatus(err), models.ErrorResponse(err.Error()))
 		return
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse(actions))
 }
 
+// GetAction returns the current status/result of a dispatched action
+func GetAction(c *gin.Context, ad *service.ActionDispatcher) {
+	action := ad.GetAction(c.Param("id"))
+	if action == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("action not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(action))
+}
+
+// CancelAction cancels a queued action or kills a running install/push/swipe
+func CancelAction(c *gin.Context, ad *service.ActionDispatcher) {
+	id := c.Param("id")
+	if err := ad.CancelAction(id); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, service.ErrActionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(ad.GetAction(id)))
+}
+
+// Action history page size bounds for GET /api/actions/history
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// GetActionHistory returns recently completed actions (?device_id=&limit=)
+func GetActionHistory(c *gin.Context, ad *service.ActionDispatcher) {
+	limit := defaultHistoryLimit
+	if val := c.Query("limit"); val != "" {
+		if _, err := fmt.Sscanf(val, "%d", &limit); err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid limit"))
+			return
+		}
+	}
+	limit = min(limit, maxHistoryLimit)
+
+	history, err := ad.GetActionHistory(c.Query("device_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(history))
+}
+
 // generateActionID generates a unique action ID
 func generateActionID() string {
 	return fmt.Sprintf("action_%d", time.Now().UnixNano())
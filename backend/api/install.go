@@ -0,0 +1,138 @@
+package api
+
+import (
+	"androidcontrol/adb"
+	"androidcontrol/models"
+	"androidcontrol/service"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APK downloads and uploads larger than this are rejected
+const maxAPKSize = 1 << 30 // 1GB
+
+// apkDownloadTimeout bounds fetching an APK from a URL
+const apkDownloadTimeout = 5 * time.Minute
+
+// InstallAPK installs an APK on a device from a multipart upload or a URL
+// Multipart: file=<apk>, optional reinstall=true, grant_all=true
+// JSON: {"url": "https://...", "reinstall": true, "grant_all": true}
+func InstallAPK(c *gin.Context, dm *service.DeviceManager) {
+	device := dm.GetDevice(c.Param("device_id"))
+	if device == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+	if device.Status != models.DeviceStatusOnline {
+		c.JSON(http.StatusConflict, models.ErrorResponse("device not online"))
+		return
+	}
+
+	// adb install insists on an .apk extension
+	tmpFile, err := os.CreateTemp("", "adb-install-*.apk")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	var opts adb.InstallOpts
+	var source string
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		source, err = saveUploadedAPK(c, tmpFile)
+		opts.Reinstall, _ = strconv.ParseBool(c.PostForm("reinstall"))
+		opts.GrantAll, _ = strconv.ParseBool(c.PostForm("grant_all"))
+	} else {
+		var req struct {
+			URL string `json:"url" binding:"required"`
+			adb.InstallOpts
+		}
+		if bindErr := c.ShouldBindJSON(&req); bindErr != nil {
+			tmpFile.Close()
+			c.JSON(http.StatusBadRequest, models.ErrorResponse("url or multipart file is required"))
+			return
+		}
+		opts = req.InstallOpts
+		source = req.URL
+		err = downloadAPK(req.URL, tmpFile)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	log.Printf("📦 [%s] Installing %s (reinstall=%t, grant_all=%t)", device.ID, source, opts.Reinstall, opts.GrantAll)
+	if err := dm.GetADBClient().InstallAPK(device.ADBDeviceID, tmpPath, opts); err != nil {
+		log.Printf("❌ [%s] Install failed: %v", device.ID, err)
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse("APK installed on device "+device.ID))
+}
+
+// saveUploadedAPK copies the multipart "file" field into dst and returns its name
+func saveUploadedAPK(c *gin.Context, dst io.Writer) (string, error) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return "", fmt.Errorf("file is required")
+	}
+	if header.Size > maxAPKSize {
+		return "", fmt.Errorf("apk too large (max %d bytes)", maxAPKSize)
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", fmt.Errorf("failed to save upload: %w", err)
+	}
+	return header.Filename, nil
+}
+
+// downloadAPK fetches an http(s) URL into dst, capped at maxAPKSize
+func downloadAPK(rawURL string, dst io.Writer) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url: must be http or https")
+	}
+
+	client := &http.Client{Timeout: apkDownloadTimeout}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: %s", resp.Status)
+	}
+	if resp.ContentLength > maxAPKSize {
+		return fmt.Errorf("apk too large (max %d bytes)", maxAPKSize)
+	}
+
+	n, err := io.Copy(dst, io.LimitReader(resp.Body, maxAPKSize+1))
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if n > maxAPKSize {
+		return fmt.Errorf("apk too large (max %d bytes)", maxAPKSize)
+	}
+	return nil
+}
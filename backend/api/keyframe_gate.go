@@ -0,0 +1,68 @@
+package api
+
+import (
+	"androidcontrol/service"
+	"sync"
+)
+
+// keyframeGate withholds a new subscriber's video until the next IDR
+// The cached SPS/PPS/IDR bundle lets the decoder start, but the live P-frames that follow
+// reference pictures the client never got; dropping them until a fresh IDR passes gives
+// a clean picture instead of a burst of corruption. Config, audio and legacy-layout
+// frames (no keyframe flag to wait for) always pass.
+type keyframeGate struct {
+	mu      sync.Mutex
+	waiting map[string]bool // Devices whose next IDR hasn't reached this client yet
+	waitAll bool            // Subscribed to "all": every device waits until passed
+	passed  map[string]bool // Devices that delivered an IDR since the "all" subscription
+}
+
+func newKeyframeGate() *keyframeGate {
+	return &keyframeGate{
+		waiting: make(map[string]bool),
+		passed:  make(map[string]bool),
+	}
+}
+
+// wait starts withholding a device's P-frames ("all" = every device)
+func (g *keyframeGate) wait(deviceID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if deviceID == "all" {
+		g.waitAll = true
+		clear(g.passed)
+		return
+	}
+	g.waiting[deviceID] = true
+}
+
+// release stops gating a device (unsubscribed)
+func (g *keyframeGate) release(deviceID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if deviceID == "all" {
+		g.waitAll = false
+		clear(g.passed)
+		return
+	}
+	delete(g.waiting, deviceID)
+}
+
+// allow reports whether a binary frame of a device may be sent; an IDR opens the gate
+func (g *keyframeGate) allow(deviceID string, flags byte, isVideo bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	gated := g.waiting[deviceID] || (g.waitAll && !g.passed[deviceID])
+	if !gated || !isVideo || flags&service.FrameFlagConfig != 0 {
+		return true
+	}
+	if flags&service.FrameFlagKeyframe == 0 {
+		return false
+	}
+	delete(g.waiting, deviceID)
+	if g.waitAll {
+		g.passed[deviceID] = true
+	}
+	return true
+}
@@ -1,14 +1,25 @@
 package api
 
 import (
+	"androidcontrol/config"
 	"androidcontrol/service"
+	"log"
+
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, dm *service.DeviceManager, ad *service.ActionDispatcher, wsHub *WebSocketHub, ss *service.StreamingService) {
+func SetupRoutes(router *gin.Engine, dm *service.DeviceManager, ad *service.ActionDispatcher, wsHub *WebSocketHub, ss *service.StreamingService, gm *service.GroupManager, bs *service.BaselineStore) {
 	// Enable CORS
 	router.Use(CORSMiddleware())
 
+	// Bearer token auth (disabled when API_TOKEN is unset)
+	token := config.APIToken()
+	if token == "" {
+		log.Println("⚠️ API_TOKEN not set - API and WebSocket are unauthenticated")
+	}
+
+	shellPolicy := newShellPolicy(config.ShellAllowlist(), config.ShellDenylist())
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
@@ -16,7 +27,16 @@ func SetupRoutes(router *gin.Engine, dm *service.DeviceManager, ad *service.Acti
 
 	// API routes
 	api := router.Group("/api")
+	api.Use(AuthMiddleware(token))
 	{
+		// Monitoring
+		api.GET("/metrics", func(c *gin.Context) {
+			GetMetrics(c, dm, ss, wsHub)
+		})
+		api.GET("/capabilities", func(c *gin.Context) {
+			GetCapabilities(c, ss)
+		})
+
 		// Device routes
 		devices := api.Group("/devices")
 		{
@@ -26,6 +46,69 @@ func SetupRoutes(router *gin.Engine, dm *service.DeviceManager, ad *service.Acti
 			devices.POST("/scan", func(c *gin.Context) {
 				ScanDevices(c, dm)
 			})
+			devices.POST("/connect", func(c *gin.Context) {
+				ConnectDevice(c, dm)
+			})
+			devices.POST("/pair", func(c *gin.Context) {
+				PairDevice(c, dm)
+			})
+			devices.POST("/disconnect", func(c *gin.Context) {
+				DisconnectDevice(c, dm)
+			})
+			devices.GET("/:device_id/file", func(c *gin.Context) {
+				GetDeviceFile(c, dm)
+			})
+			devices.GET("/:device_id/screenshot", func(c *gin.Context) {
+				GetScreenshot(c, dm)
+			})
+			devices.POST("/:device_id/screenshot/compare", func(c *gin.Context) {
+				CompareScreenshot(c, dm, bs)
+			})
+			devices.GET("/:device_id/packages", func(c *gin.Context) {
+				GetPackages(c, dm)
+			})
+			devices.GET("/:device_id/stats", func(c *gin.Context) {
+				GetProcessStats(c, dm, ss)
+			})
+			devices.GET("/:device_id/encoders", func(c *gin.Context) {
+				GetEncoders(c, dm, ss)
+			})
+			devices.GET("/:device_id/displays", func(c *gin.Context) {
+				GetDisplays(c, dm, ss)
+			})
+			devices.GET("/:device_id/clipboard", func(c *gin.Context) {
+				GetClipboard(c, dm, ss)
+			})
+			devices.POST("/:device_id/clipboard", func(c *gin.Context) {
+				SetClipboard(c, dm, ss)
+			})
+			devices.PUT("/:device_id/alias", func(c *gin.Context) {
+				SetDeviceAlias(c, dm)
+			})
+			devices.PUT("/:device_id/display", func(c *gin.Context) {
+				SetDisplay(c, dm)
+			})
+			devices.POST("/:device_id/rotate", func(c *gin.Context) {
+				RotateDevice(c, dm)
+			})
+			devices.POST("/:device_id/key", func(c *gin.Context) {
+				PressKey(c, dm, ss)
+			})
+			devices.POST("/:device_id/cleanup", func(c *gin.Context) {
+				CleanupDevice(c, dm, ss)
+			})
+			devices.POST("/:device_id/install", func(c *gin.Context) {
+				InstallAPK(c, dm)
+			})
+			devices.POST("/:device_id/reverse", func(c *gin.Context) {
+				AddReverse(c, dm)
+			})
+			devices.DELETE("/:device_id/reverse/:socket", func(c *gin.Context) {
+				RemoveReverse(c, dm)
+			})
+			devices.POST("/:device_id/shell", func(c *gin.Context) {
+				ExecuteShell(c, dm, token != "", shellPolicy)
+			})
 		}
 
 		// Action routes
@@ -37,6 +120,55 @@ func SetupRoutes(router *gin.Engine, dm *service.DeviceManager, ad *service.Acti
 			actions.POST("/batch", func(c *gin.Context) {
 				ExecuteBatchAction(c, dm, ad)
 			})
+			actions.GET("/history", func(c *gin.Context) {
+				GetActionHistory(c, ad)
+			})
+			actions.GET("/:id", func(c *gin.Context) {
+				GetAction(c, ad)
+			})
+			actions.DELETE("/:id", func(c *gin.Context) {
+				CancelAction(c, ad)
+			})
+		}
+
+		// Device group routes
+		groups := api.Group("/groups")
+		{
+			groups.GET("", func(c *gin.Context) {
+				GetGroups(c, gm)
+			})
+			groups.POST("", func(c *gin.Context) {
+				CreateGroup(c, gm)
+			})
+			groups.GET("/:id", func(c *gin.Context) {
+				GetGroup(c, gm)
+			})
+			groups.PUT("/:id", func(c *gin.Context) {
+				UpdateGroup(c, gm)
+			})
+			groups.DELETE("/:id", func(c *gin.Context) {
+				DeleteGroup(c, gm)
+			})
+			groups.POST("/:id/actions", func(c *gin.Context) {
+				ExecuteGroupAction(c, gm, ad)
+			})
+		}
+
+		// Screenshot baseline routes
+		baselines := api.Group("/baselines")
+		{
+			baselines.GET("", func(c *gin.Context) {
+				GetBaselines(c, bs)
+			})
+			baselines.POST("", func(c *gin.Context) {
+				CreateBaseline(c, dm, bs)
+			})
+			baselines.GET("/:id", func(c *gin.Context) {
+				GetBaselineImage(c, bs)
+			})
+			baselines.DELETE("/:id", func(c *gin.Context) {
+				DeleteBaseline(c, bs)
+			})
 		}
 
 		// Streaming routes
@@ -57,12 +189,63 @@ func SetupRoutes(router *gin.Engine, dm *service.DeviceManager, ad *service.Acti
 			streaming.GET("/status", func(c *gin.Context) {
 				GetStreamingStatus(c, ss)
 			})
+			streaming.GET("/viewers", func(c *gin.Context) {
+				GetViewerCounts(c, ss)
+			})
+			streaming.GET("/session/:device_id", func(c *gin.Context) {
+				GetStreamSession(c, ss)
+			})
+			streaming.GET("/logs/:device_id", func(c *gin.Context) {
+				GetServerLogs(c, ss)
+			})
+			streaming.PUT("/config/:device_id", func(c *gin.Context) {
+				SetStreamConfig(c, ss)
+			})
+			streaming.GET("/profiles", func(c *gin.Context) {
+				GetStreamProfiles(c, ss)
+			})
+			streaming.POST("/profile", func(c *gin.Context) {
+				SetGlobalStreamProfile(c, ss)
+			})
+			streaming.POST("/profile/:device_id", func(c *gin.Context) {
+				SetStreamProfile(c, ss)
+			})
+			streaming.PUT("/warm-ttl/:device_id", func(c *gin.Context) {
+				SetWarmTTL(c, ss)
+			})
+			streaming.GET("/reconnect/:device_id", func(c *gin.Context) {
+				GetReconnectPolicy(c, ss)
+			})
+			streaming.PUT("/reconnect/:device_id", func(c *gin.Context) {
+				SetReconnectPolicy(c, ss)
+			})
+			streaming.PUT("/broadcast-fps/:device_id", func(c *gin.Context) {
+				SetBroadcastFPS(c, ss)
+			})
+			streaming.PUT("/display-power/:device_id", func(c *gin.Context) {
+				SetDisplayPower(c, ss)
+			})
+			streaming.POST("/control/reconnect/:device_id", func(c *gin.Context) {
+				ReconnectControl(c, ss)
+			})
+			streaming.POST("/typekeys/:device_id", func(c *gin.Context) {
+				TypeKeys(c, ss)
+			})
+			streaming.POST("/record/start/:device_id", func(c *gin.Context) {
+				StartRecording(c, ss)
+			})
+			streaming.POST("/record/stop/:device_id", func(c *gin.Context) {
+				StopRecording(c, ss)
+			})
+			streaming.GET("/snapshot/:device_id", func(c *gin.Context) {
+				GetSnapshot(c, ss)
+			})
 		}
 	}
 
 	// WebSocket route
 	router.GET("/ws", func(c *gin.Context) {
-		HandleWebSocket(wsHub, ss, c) // Truyền thêm ss
+		HandleWebSocket(wsHub, ss, dm, token, c) // Truyền thêm ss
 	})
 }
 
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"androidcontrol/models"
+	"androidcontrol/service"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Uploaded baseline PNGs larger than this are rejected
+const maxBaselineSize = 32 << 20 // 32MB
+
+// CompareScreenshot captures the device screen and compares it to a baseline
+// JSON: {"baseline_id": "...", "threshold": 0.99, "tolerance": 8, "diff": true}
+// Multipart: baseline=<png> (or baseline_id) plus the same fields as form values
+// Returns the scores, pass (similarity >= threshold) and the diff image as base64 PNG
+func CompareScreenshot(c *gin.Context, dm *service.DeviceManager, bs *service.BaselineStore) {
+	device := dm.GetDevice(c.Param("device_id"))
+	if device == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	var req models.CompareRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request: "+err.Error()))
+		return
+	}
+	threshold := req.Threshold
+	if threshold == 0 {
+		threshold = service.DefaultCompareThreshold
+	}
+	tolerance := service.DefaultCompareTolerance
+	if req.Tolerance != nil {
+		tolerance = *req.Tolerance
+	}
+	if threshold < 0 || threshold > 1 || tolerance < 0 || tolerance > 255 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("threshold must be 0-1 and tolerance 0-255"))
+		return
+	}
+
+	var baseline []byte
+	var err error
+	switch {
+	case strings.HasPrefix(c.ContentType(), "multipart/") && req.BaselineID == "":
+		baseline, err = readUploadedPNG(c, "baseline")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+			return
+		}
+	case req.BaselineID != "":
+		if bs.Get(req.BaselineID) == nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse("baseline not found"))
+			return
+		}
+		baseline, err = bs.Image(req.BaselineID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("baseline_id or multipart baseline file is required"))
+		return
+	}
+
+	current, err := dm.GetADBClient().ScreenCapture(device.ADBDeviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	result, diff, err := service.ComparePNG(baseline, current, tolerance)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrImageSize) {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	pass := result.Similarity >= threshold
+	response := gin.H{
+		"device_id":     device.ID,
+		"baseline_id":   req.BaselineID,
+		"width":         result.Width,
+		"height":        result.Height,
+		"similarity":    result.Similarity,
+		"diff_pixels":   result.DiffPixels,
+		"mse":           result.MSE,
+		"hash_distance": result.HashDistance,
+		"threshold":     threshold,
+		"tolerance":     tolerance,
+		"pass":          pass,
+	}
+	if req.Diff == nil || *req.Diff {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, diff); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+			return
+		}
+		response["diff_png"] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+
+	log.Printf("🔍 [%s] Screenshot compare: similarity=%.4f diff_pixels=%d hash_distance=%d pass=%t",
+		device.ID, result.Similarity, result.DiffPixels, result.HashDistance, pass)
+	c.JSON(http.StatusOK, models.SuccessResponse(response))
+}
+
+// GetBaselines lists stored screenshot baselines
+func GetBaselines(c *gin.Context, bs *service.BaselineStore) {
+	c.JSON(http.StatusOK, models.SuccessResponse(bs.GetAll()))
+}
+
+// GetBaselineImage returns a baseline's PNG
+func GetBaselineImage(c *gin.Context, bs *service.BaselineStore) {
+	id := c.Param("id")
+	if bs.Get(id) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("baseline not found"))
+		return
+	}
+
+	data, err := bs.Image(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// CreateBaseline stores a baseline from an upload or a device's current screen
+// Multipart: file=<png>, name=...
+// JSON: {"name": "...", "device_id": "..."} captures the device screen
+func CreateBaseline(c *gin.Context, dm *service.DeviceManager, bs *service.BaselineStore) {
+	var name, deviceID string
+	var data []byte
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		var err error
+		data, err = readUploadedPNG(c, "file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+			return
+		}
+		name = c.PostForm("name")
+	} else {
+		var req models.BaselineRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse("name and device_id (or a multipart file) are required"))
+			return
+		}
+		device := dm.GetDevice(req.DeviceID)
+		if device == nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+			return
+		}
+
+		var err error
+		data, err = dm.GetADBClient().ScreenCapture(device.ADBDeviceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+			return
+		}
+		name, deviceID = req.Name, device.ID
+	}
+
+	baseline, err := bs.Create(name, deviceID, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, models.SuccessResponse(baseline))
+}
+
+// DeleteBaseline removes a baseline
+func DeleteBaseline(c *gin.Context, bs *service.BaselineStore) {
+	id := c.Param("id")
+	if bs.Get(id) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("baseline not found"))
+		return
+	}
+
+	if err := bs.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse("baseline deleted"))
+}
+
+// readUploadedPNG reads a multipart file field, capped at maxBaselineSize
+func readUploadedPNG(c *gin.Context, field string) ([]byte, error) {
+	header, err := c.FormFile(field)
+	if err != nil {
+		return nil, fmt.Errorf("%s file is required", field)
+	}
+	if header.Size > maxBaselineSize {
+		return nil, fmt.Errorf("%s too large (max %d bytes)", field, maxBaselineSize)
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxBaselineSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	return data, nil
+}
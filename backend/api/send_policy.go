@@ -0,0 +1,62 @@
+package api
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// SendPolicy decides what happens when a client's outgoing queue is full
+//
+//   - drop_oldest (default): the queued frame is dropped for the new one. Lowest
+//     latency for live view - the client always gets the freshest picture, at
+//     the cost of a gap until the next keyframe.
+//   - drop_newest: the new frame is discarded and the queue keeps what it has.
+//     Bounded latency without reordering; good when the queued frames are
+//     still worth showing (short bursts), but a long stall shows stale video.
+//   - block: wait up to sendBlockDeadline for the writer to make room, then
+//     fall back to drop_oldest. Best completeness (recording clients), but
+//     the wait delays the broadcast to every other client of that device.
+type SendPolicy int32
+
+const (
+	SendPolicyDropOldest SendPolicy = iota
+	SendPolicyDropNewest
+	SendPolicyBlock
+)
+
+// sendBlockDeadline caps how long SendPolicyBlock stalls a broadcast per frame
+const sendBlockDeadline = 50 * time.Millisecond
+
+var sendPolicyNames = map[string]SendPolicy{
+	"drop_oldest": SendPolicyDropOldest,
+	"drop_newest": SendPolicyDropNewest,
+	"block":       SendPolicyBlock,
+}
+
+// ParseSendPolicy maps a policy name (drop_oldest, drop_newest, block) to a SendPolicy
+func ParseSendPolicy(name string) (SendPolicy, bool) {
+	p, ok := sendPolicyNames[name]
+	return p, ok
+}
+
+func (p SendPolicy) String() string {
+	for name, policy := range sendPolicyNames {
+		if policy == p {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// sendPolicyValue is a SendPolicy shared between readPump and broadcasters
+type sendPolicyValue struct {
+	v atomic.Int32
+}
+
+func (s *sendPolicyValue) Load() SendPolicy {
+	return SendPolicy(s.v.Load())
+}
+
+func (s *sendPolicyValue) Store(p SendPolicy) {
+	s.v.Store(int32(p))
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"androidcontrol/models"
+	"androidcontrol/service"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shellSeparators splits a shell command line into the commands it runs
+var shellSeparators = regexp.MustCompile("&&|\\|\\||[;&|\\n()`]|\\$\\(")
+
+// shellWrappers run other commands, so a denylist alone can't vet them
+var shellWrappers = map[string]bool{
+	"sh": true, "su": true, "busybox": true, "toybox": true, "xargs": true,
+	"env": true, "eval": true, "exec": true, "nohup": true, "timeout": true,
+	"command": true, "builtin": true,
+}
+
+// shellQuoting removes the quotes and backslashes the shell drops from a word, so quoted
+// or escaped spellings of a name ("rm", r\m, 'r'm) match it too
+var shellQuoting = strings.NewReplacer(`"`, "", "'", "", `\`, "")
+
+// shellPolicy decides which commands the shell endpoint may run
+// With an allowlist only listed commands run; otherwise the denylist (and wrappers that
+// could bypass it) is refused. Matching is on each command's name, so only the allowlist
+// is a real control: a denylist is a guard rail against mistakes, and shell tricks
+// (variables, globs, aliases) can still reach a denied program.
+type shellPolicy struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+func newShellPolicy(allow, deny []string) *shellPolicy {
+	p := &shellPolicy{allow: make(map[string]bool), deny: make(map[string]bool)}
+	for _, name := range allow {
+		p.allow[name] = true
+	}
+	for _, name := range deny {
+		p.deny[name] = true
+	}
+	return p
+}
+
+// shellCommandNames returns the program name of every command in a command line
+func shellCommandNames(command string) []string {
+	var names []string
+	for _, segment := range shellSeparators.Split(command, -1) {
+		for _, field := range strings.Fields(segment) {
+			if strings.Contains(field, "=") && !strings.HasPrefix(field, "=") {
+				continue // Leading VAR=value assignment
+			}
+			names = append(names, path.Base(shellQuoting.Replace(field)))
+			break
+		}
+	}
+	return names
+}
+
+// check returns an error naming the first command the policy forbids
+func (p *shellPolicy) check(command string) error {
+	names := shellCommandNames(command)
+	if len(names) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	for _, name := range names {
+		switch {
+		case len(p.allow) > 0:
+			if !p.allow[name] {
+				return fmt.Errorf("command not allowed: %s", name)
+			}
+		case p.deny[name]:
+			return fmt.Errorf("command denied: %s", name)
+		case len(p.deny) > 0 && shellWrappers[name]:
+			return fmt.Errorf("command denied: %s (can bypass the denylist)", name)
+		}
+	}
+	return nil
+}
+
+// ExecuteShell runs a shell command on a device and returns stdout, stderr and exit code
+// Only available when API_TOKEN is set, since it gives full shell access
+func ExecuteShell(c *gin.Context, dm *service.DeviceManager, authEnabled bool, policy *shellPolicy) {
+	if !authEnabled {
+		c.JSON(http.StatusForbidden, models.ErrorResponse("shell endpoint requires API_TOKEN to be set"))
+		return
+	}
+
+	device := dm.GetDevice(c.Param("device_id"))
+	if device == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+	if device.Status != models.DeviceStatusOnline {
+		c.JSON(http.StatusConflict, models.ErrorResponse("device not online"))
+		return
+	}
+
+	var req struct {
+		Command string `json:"command" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("command is required"))
+		return
+	}
+
+	if err := policy.check(req.Command); err != nil {
+		c.JSON(http.StatusForbidden, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := dm.GetADBClient().ExecuteCommand(device.ADBDeviceID, req.Command)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(result))
+}
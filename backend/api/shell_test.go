@@ -0,0 +1,66 @@
+package api
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestShellCommandNames(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"ls -la /sdcard", []string{"ls"}},
+		{"/system/bin/rm -rf /sdcard/x", []string{"rm"}},
+		{"FOO=1 rm x", []string{"rm"}},
+		{"echo a && rm b; reboot | cat", []string{"echo", "rm", "reboot", "cat"}},
+		{"echo $(rm x)", []string{"echo", "rm"}},
+		{`r''m x`, []string{"rm"}},
+		{`"rm" x`, []string{"rm"}},
+		{`r\m x`, []string{"rm"}},
+		{`'/system/bin/re'boot`, []string{"reboot"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.command, func(t *testing.T) {
+			if got := shellCommandNames(tc.command); !slices.Equal(got, tc.want) {
+				t.Errorf("shellCommandNames(%q) = %v, want %v", tc.command, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestShellPolicyDenylist(t *testing.T) {
+	p := newShellPolicy(nil, []string{"rm", "reboot"})
+	denied := []string{
+		"rm -rf /sdcard",
+		`r''m -rf /sdcard`,
+		`"reboot"`,
+		"ls; rm x",
+		"command rm x",
+		"builtin eval rm",
+		"exec reboot",
+		"sh -c 'rm x'",
+	}
+	for _, command := range denied {
+		if err := p.check(command); err == nil {
+			t.Errorf("check(%q) allowed a denied command", command)
+		}
+	}
+	for _, command := range []string{"ls /sdcard", "getprop ro.product.model", "echo rm"} {
+		if err := p.check(command); err != nil {
+			t.Errorf("check(%q) = %v, want allowed", command, err)
+		}
+	}
+}
+
+func TestShellPolicyAllowlist(t *testing.T) {
+	p := newShellPolicy([]string{"ls", "getprop"}, nil)
+	if err := p.check("ls /sdcard && getprop"); err != nil {
+		t.Errorf("allowed commands refused: %v", err)
+	}
+	for _, command := range []string{"rm x", "ls; command rm x", "$(rm x)", ""} {
+		if err := p.check(command); err == nil {
+			t.Errorf("check(%q) allowed a command outside the allowlist", command)
+		}
+	}
+}
@@ -3,42 +3,69 @@ package api
 import (
 	"androidcontrol/models"
 	"androidcontrol/service"
+	"errors"
+	"fmt"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// errorStatus maps features disabled by the startup preflight to 501, other errors to fallback
+func errorStatus(err error, fallback int) int {
+	if errors.Is(err, service.ErrUnavailable) {
+		return http.StatusNotImplemented
+	}
+	return fallback
+}
+
+// GetCapabilities returns which external dependencies (adb, scrcpy-server, ffmpeg) were found at startup
+func GetCapabilities(c *gin.Context, ss *service.StreamingService) {
+	c.JSON(http.StatusOK, models.SuccessResponse(ss.Capabilities()))
+}
+
 // StartStreaming starts screen streaming for a device
 func StartStreaming(c *gin.Context, ss *service.StreamingService) {
 	deviceID := c.Param("device_id")
-	
-	if err := ss.StartStreaming(deviceID); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+
+	outcome, err := ss.StartStreaming(deviceID)
+	if errors.Is(err, service.ErrStreamStopping) {
+		c.JSON(http.StatusConflict, models.ErrorResponse(err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), models.ErrorResponse(err.Error()))
 		return
 	}
-	
-	c.JSON(http.StatusOK, models.MessageResponse("Streaming started for device "+deviceID))
+
+	// outcome: "started", "already_running" or "starting"
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"device_id": deviceID,
+		"outcome":   outcome,
+	}))
 }
 
 // StopStreaming stops screen streaming for a device
 func StopStreaming(c *gin.Context, ss *service.StreamingService) {
 	deviceID := c.Param("device_id")
-	
+
 	if err := ss.StopStreaming(deviceID); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
 		return
 	}
-	
+
 	c.JSON(http.StatusOK, models.MessageResponse("Streaming stopped for device "+deviceID))
 }
 
 // StartAllStreaming starts streaming for all online devices
 func StartAllStreaming(c *gin.Context, ss *service.StreamingService) {
 	if err := ss.StartAllStreaming(); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		c.JSON(errorStatus(err, http.StatusInternalServerError), models.ErrorResponse(err.Error()))
 		return
 	}
-	
+
 	c.JSON(http.StatusOK, models.MessageResponse("Streaming started for all devices"))
 }
 
@@ -53,3 +80,354 @@ func GetStreamingStatus(c *gin.Context, ss *service.StreamingService) {
 	status := ss.GetStreamingStatus()
 	c.JSON(http.StatusOK, models.SuccessResponse(status))
 }
+
+// GetStreamSession returns detailed session info for one device's stream
+func GetStreamSession(c *gin.Context, ss *service.StreamingService) {
+	session, err := ss.GetStreamSession(c.Param("device_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(session))
+}
+
+// GetViewerCounts returns how many clients watch each device: {"device_id": count}
+func GetViewerCounts(c *gin.Context, ss *service.StreamingService) {
+	c.JSON(http.StatusOK, models.SuccessResponse(ss.GetViewerCounts()))
+}
+
+// defaultServerLogLines is how many scrcpy server log lines GetServerLogs returns without ?lines=
+const defaultServerLogLines = 200
+
+// GetServerLogs returns the latest scrcpy server output for a device (?lines=N, 0 = all kept)
+func GetServerLogs(c *gin.Context, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+
+	n := defaultServerLogLines
+	if val := c.Query("lines"); val != "" {
+		if _, err := fmt.Sscanf(val, "%d", &n); err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid lines"))
+			return
+		}
+	}
+
+	lines, err := ss.ServerLogs(deviceID, n)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"device_id": deviceID,
+		"lines":     lines,
+	}))
+}
+
+// GetStreamProfiles lists the named stream profiles and the global selection
+func GetStreamProfiles(c *gin.Context, ss *service.StreamingService) {
+	profiles, global := ss.StreamProfiles()
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"profiles": profiles,
+		"global":   global,
+	}))
+}
+
+// SetStreamProfile selects a named profile for one device ({"profile": ""} follows the global one)
+func SetStreamProfile(c *gin.Context, ss *service.StreamingService) {
+	var req models.StreamProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+
+	deviceID := c.Param("device_id")
+	if err := ss.SetStreamProfile(deviceID, req.Profile); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(ss.GetStreamConfig(deviceID)))
+}
+
+// SetGlobalStreamProfile selects the profile for every device without its own
+func SetGlobalStreamProfile(c *gin.Context, ss *service.StreamingService) {
+	var req models.StreamProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+
+	if err := ss.SetGlobalProfile(req.Profile); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse(fmt.Sprintf("global stream profile set to %q", req.Profile)))
+}
+
+// SetStreamConfig updates scrcpy encoder settings for a device
+func SetStreamConfig(c *gin.Context, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+
+	var cfg service.StreamConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request"))
+		return
+	}
+
+	if err := ss.SetStreamConfig(deviceID, cfg); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(ss.GetStreamConfig(deviceID)))
+}
+
+// SetWarmTTL sets how long a device's stream stays warm after its last viewer leaves
+// Body: {"ttl_seconds": 30} - 0 stops immediately, negative never stops
+func SetWarmTTL(c *gin.Context, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+
+	var req struct {
+		TTLSeconds *float64 `json:"ttl_seconds"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.TTLSeconds == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("ttl_seconds is required"))
+		return
+	}
+
+	ttl := time.Duration(*req.TTLSeconds * float64(time.Second))
+	if err := ss.SetWarmTTL(deviceID, ttl); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse("Warm session TTL updated for device "+deviceID))
+}
+
+// GetReconnectPolicy returns the scrcpy retry policy a device's stream uses
+func GetReconnectPolicy(c *gin.Context, ss *service.StreamingService) {
+	c.JSON(http.StatusOK, models.SuccessResponse(reconnectPolicyJSON(ss.ReconnectPolicyFor(c.Param("device_id")))))
+}
+
+// SetReconnectPolicy overrides a device's scrcpy retry policy; omitted fields keep their current value
+// Body: {"max_attempts": 8, "backoff_seconds": 1, "multiplier": 1.5, "max_backoff_seconds": 20}
+// or {"reset": true} to go back to the RECONNECT_* defaults
+func SetReconnectPolicy(c *gin.Context, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+
+	var req struct {
+		MaxAttempts       *int     `json:"max_attempts"`
+		BackoffSeconds    *float64 `json:"backoff_seconds"`
+		Multiplier        *float64 `json:"multiplier"`
+		MaxBackoffSeconds *float64 `json:"max_backoff_seconds"`
+		Reset             bool     `json:"reset"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid request: "+err.Error()))
+		return
+	}
+
+	var override *service.ReconnectPolicy
+	if !req.Reset {
+		policy := ss.ReconnectPolicyFor(deviceID)
+		if req.MaxAttempts != nil {
+			policy.MaxAttempts = *req.MaxAttempts
+		}
+		if req.BackoffSeconds != nil {
+			policy.BaseBackoff = time.Duration(*req.BackoffSeconds * float64(time.Second))
+		}
+		if req.Multiplier != nil {
+			policy.Multiplier = *req.Multiplier
+		}
+		if req.MaxBackoffSeconds != nil {
+			policy.MaxBackoff = time.Duration(*req.MaxBackoffSeconds * float64(time.Second))
+		}
+		override = &policy
+	}
+
+	if err := ss.SetDeviceReconnectPolicy(deviceID, override); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(reconnectPolicyJSON(ss.ReconnectPolicyFor(deviceID))))
+}
+
+// reconnectPolicyJSON renders a policy with durations in seconds and the resulting waits
+func reconnectPolicyJSON(p service.ReconnectPolicy) gin.H {
+	schedule := make([]float64, 0, p.MaxAttempts)
+	for _, wait := range p.Schedule() {
+		schedule = append(schedule, wait.Seconds())
+	}
+	return gin.H{
+		"max_attempts":        p.MaxAttempts,
+		"backoff_seconds":     p.BaseBackoff.Seconds(),
+		"multiplier":          p.Multiplier,
+		"max_backoff_seconds": p.MaxBackoff.Seconds(),
+		"schedule_seconds":    schedule,
+	}
+}
+
+// SetBroadcastFPS caps the frames per second a device's viewers receive, without restarting scrcpy
+// Body: {"fps": 15} - 0 removes the cap
+func SetBroadcastFPS(c *gin.Context, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+
+	var req struct {
+		FPS *int `json:"fps"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.FPS == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("fps is required"))
+		return
+	}
+
+	if err := ss.SetBroadcastFPS(deviceID, *req.FPS); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse("Broadcast FPS updated for device "+deviceID))
+}
+
+// SetDisplayPower turns a streaming device's screen off (mirroring continues) or back on
+// Body: {"on": false} - kept across scrcpy restarts until turned back on
+func SetDisplayPower(c *gin.Context, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+
+	var req struct {
+		On *bool `json:"on"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.On == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("on is required"))
+		return
+	}
+
+	if err := ss.SetDisplayPower(deviceID, *req.On); err != nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse("Display power updated for device "+deviceID))
+}
+
+// ReconnectControl restores the control socket of a stream that runs video-only
+// by restarting its scrcpy session (202 while it restarts, 200 if control is already up)
+func ReconnectControl(c *gin.Context, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+
+	restarting, err := ss.ReconnectControl(deviceID)
+	if err != nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse(err.Error()))
+		return
+	}
+	if restarting {
+		c.JSON(http.StatusAccepted, models.SuccessResponse(gin.H{"device_id": deviceID, "has_control": false, "restarting": true}))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"device_id": deviceID, "has_control": true, "restarting": false}))
+}
+
+// TypeKeys types text on a device as real key events
+// Body: {"text": "hunter2"} - responds once every key has been sent
+func TypeKeys(c *gin.Context, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Text == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("text is required"))
+		return
+	}
+
+	if err := ss.TypeAsKeys(deviceID, req.Text); err != nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse("Text typed on device "+deviceID))
+}
+
+// recordingsDir is where MP4 recordings are saved
+const recordingsDir = "recordings"
+
+// StartRecording starts recording a device's live stream to an MP4 file
+// Optional body: {"filename": "name.mp4"} (saved under recordings/, ".mp4" added if missing)
+func StartRecording(c *gin.Context, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+
+	var req struct {
+		Filename string `json:"filename"`
+	}
+	c.ShouldBindJSON(&req) // Body is optional
+
+	filename := filepath.Base(req.Filename)
+	if req.Filename == "" || filename == "." || filename == string(filepath.Separator) {
+		// device_192.168.1.5:5555 -> device_192.168.1.5_5555_2025-12-08_21-52-35.mp4
+		safeID := strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(deviceID)
+		filename = fmt.Sprintf("%s_%s.mp4", safeID, time.Now().Format("2006-01-02_15-04-05"))
+	}
+	outputPath, err := ss.StartRecording(deviceID, filepath.Join(recordingsDir, filename))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"path": outputPath}))
+}
+
+// StopRecording stops recording and returns the saved file path
+func StopRecording(c *gin.Context, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+
+	path, err := ss.StopRecording(deviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"path": path}))
+}
+
+// GetSnapshot returns a still image of the live stream (JPEG, or PNG from screencap fallback)
+func GetSnapshot(c *gin.Context, ss *service.StreamingService) {
+	image, err := ss.Snapshot(c.Param("device_id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(http.StatusOK, http.DetectContentType(image), image)
+}
+
+// GetEncoders lists the device's video/audio encoders by running scrcpy-server in list mode
+// Check for an h265 encoder here before switching a device's codec
+func GetEncoders(c *gin.Context, dm *service.DeviceManager, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+	if dm.GetDevice(deviceID) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	encoders, err := ss.ListEncoders(deviceID)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(encoders))
+}
+
+// GetDisplays lists the device's displays (IDs and sizes) by running scrcpy-server in list mode
+func GetDisplays(c *gin.Context, dm *service.DeviceManager, ss *service.StreamingService) {
+	deviceID := c.Param("device_id")
+	if dm.GetDevice(deviceID) == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse("device not found"))
+		return
+	}
+
+	displays, err := ss.ListDisplays(deviceID)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), models.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(displays))
+}
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"androidcontrol/config"
+	"androidcontrol/events"
 	"androidcontrol/service"
+	"compress/flate"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,26 +19,63 @@ import (
 )
 
 const (
-	writeWait  = 10 * time.Second
-	pongWait   = 60 * time.Second
-	pingPeriod = (pongWait * 9) / 10 // 54 seconds
+	writeWait = 10 * time.Second
+	pongWait  = 60 * time.Second
+
+	keyframeTimeout = 500 * time.Millisecond // Wait for a fresh IDR before sending cached one
 )
 
+// pingPeriod is the keepalive and RTT sampling rate, well inside pongWait (a var for tests)
+var pingPeriod = 5 * time.Second
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins for development
 	},
 	ReadBufferSize:  1024,
 	WriteBufferSize: 2 * 1024 * 1024, // 2MB for H.264 frames
+	// permessage-deflate for JSON control messages (gorilla only negotiates no_context_takeover,
+	// so each message is compressed independently). Binary frames opt out in writePump.
+	EnableCompression: true,
 }
 
 type Client struct {
 	hub        *WebSocketHub
 	conn       *websocket.Conn
-	send       chan []byte // Buffered channel for binary frames
+	send       chan []byte   // Buffered channel for JSON messages
+	frames     *frameQueue   // Per-device binary frames, drained round-robin
+	keyframes  *keyframeGate // Holds a new subscriber's P-frames until the next IDR
 	subscribed map[string]bool
 	ss         *service.StreamingService // Reference tới StreamingService để lấy cached headers
+	dm         *service.DeviceManager    // Device list for "list_devices"
 	closed     atomic.Bool               // Cờ đóng an toàn - tránh race condition
+
+	maxSubscriptions int // Device subscription cap (config.MaxSubscriptions)
+
+	// What to do when the send queue is full (?policy= or a "policy" message)
+	policy sendPolicyValue
+
+	// Ping round-trip times (GET /api/metrics)
+	rtt clientRTT
+
+	// Input lock ownership (readPump goroutine only)
+	id         string          // Identifies this client as a control owner
+	controlled map[string]bool // Devices whose input lock this client holds
+
+	// Stable ID the browser keeps across reconnects (?client_id=, defaults to id)
+	// Viewer counts are kept per clientID, so a reconnect doesn't double-count
+	clientID string
+}
+
+// maxClientIDLength bounds ?client_id= (a UUID is 36 characters)
+const maxClientIDLength = 64
+
+// inputMessageTypes are the messages gated by a device's input lock
+var inputMessageTypes = map[string]bool{
+	"key": true, "longpress": true, "back": true, "home": true, "appswitch": true,
+	"touch": true, "stylus": true, "swipe": true, "pinch": true, "drag": true, "scroll": true,
+	"text": true, "typekeys": true, "clipboard": true, "display_power": true,
+	"pause": true, "resume": true,
 }
 
 type WebSocketHub struct {
@@ -41,19 +83,78 @@ type WebSocketHub struct {
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
+
+	// Per-device binary frame delivery counters, flushed every backpressureWindow
+	delivery       map[string]*deliveryStats
+	onBackpressure func(deviceID string, sent, dropped int)
+	deliveryMu     sync.Mutex
+}
+
+// deliveryStats counts binary frames queued vs dropped for one device
+type deliveryStats struct {
+	sent    int
+	dropped int
 }
 
+// backpressureWindow is how often delivery stats are reported
+const backpressureWindow = 2 * time.Second
+
 func NewWebSocketHub() *WebSocketHub {
 	return &WebSocketHub{
 		clients:    make(map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		delivery:   make(map[string]*deliveryStats),
+	}
+}
+
+// SetBackpressureHandler registers the receiver of per-device drop stats
+func (h *WebSocketHub) SetBackpressureHandler(handler func(deviceID string, sent, dropped int)) {
+	h.deliveryMu.Lock()
+	defer h.deliveryMu.Unlock()
+	h.onBackpressure = handler
+}
+
+// recordDelivery adds frame delivery results for a device
+func (h *WebSocketHub) recordDelivery(deviceID string, sent, dropped int) {
+	h.deliveryMu.Lock()
+	defer h.deliveryMu.Unlock()
+
+	stats, ok := h.delivery[deviceID]
+	if !ok {
+		stats = &deliveryStats{}
+		h.delivery[deviceID] = stats
+	}
+	stats.sent += sent
+	stats.dropped += dropped
+}
+
+// flushDelivery reports and resets the delivery counters
+func (h *WebSocketHub) flushDelivery() {
+	h.deliveryMu.Lock()
+	handler := h.onBackpressure
+	stats := h.delivery
+	h.delivery = make(map[string]*deliveryStats)
+	h.deliveryMu.Unlock()
+
+	if handler == nil {
+		return
+	}
+	for deviceID, st := range stats {
+		handler(deviceID, st.sent, st.dropped)
 	}
 }
 
 func (h *WebSocketHub) Run() {
+	ticker := time.NewTicker(backpressureWindow)
+	defer ticker.Stop()
+
 	for {
 		select {
+		case <-ticker.C:
+			// Handler may restart a stream - don't block register/unregister on it
+			go h.flushDelivery()
+
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
@@ -73,44 +174,128 @@ func (h *WebSocketHub) Run() {
 	}
 }
 
-// trySend sends message with drop-oldest policy, safe for concurrent use
-func (c *Client) trySend(msg []byte) {
+// trySend sends a JSON message under the client's SendPolicy, safe for concurrent use
+// Returns false if a message had to be dropped
+func (c *Client) trySend(msg []byte) bool {
 	if c.closed.Load() {
-		return
+		return true
 	}
 	select {
 	case c.send <- msg:
-		return
+		return true
 	default:
-		// Channel full - drop oldest frame(s)
+	}
+
+	switch c.policy.Load() {
+	case SendPolicyDropNewest:
+		return false // Keep what's queued
+	case SendPolicyBlock:
+		timer := time.NewTimer(sendBlockDeadline)
+		defer timer.Stop()
 		select {
-		case <-c.send: // Drop oldest
-			select {
-			case c.send <- msg:
-			default:
-			}
+		case c.send <- msg:
+			return true
+		case <-timer.C:
+			// Deadline hit - fall through to drop-oldest
+		}
+	}
+
+	// Channel full - drop oldest frame(s)
+	select {
+	case <-c.send: // Drop oldest
+		select {
+		case c.send <- msg:
 		default:
 		}
+	default:
 	}
+	return false
 }
 
-// drainAndSend clears all pending frames then sends the message
-// Used for critical data like SPS/PPS/IDR on subscribe to ensure delivery
-func (c *Client) drainAndSend(msg []byte) {
+// queueFrame queues a binary frame for a device, safe for concurrent use
+// Returns false if an older frame of that device had to be dropped
+func (c *Client) queueFrame(deviceID string, frame []byte) bool {
 	if c.closed.Load() {
+		return true
+	}
+	return c.frames.push(deviceID, frame, c.policy.Load())
+}
+
+// sendCachedHeaders sends cached [VPS] + SPS + PPS + IDR for a device, one NAL per message
+// Pending frames of that device are dropped first. Returns false if nothing was cached
+func (c *Client) sendCachedHeaders(deviceID string) bool {
+	pkts := c.ss.GetStreamData(deviceID)
+	if len(pkts) == 0 {
+		return false
+	}
+
+	c.frames.reset(deviceID) // Old frames are stale for a fresh decoder anyway
+	for _, pkt := range pkts {
+		c.queueFrame(deviceID, pkt)
+	}
+	return true
+}
+
+// sendDeviceInfo pushes the device's metadata (name, resolution, battery, version) so a new tile
+// is labeled from the first frame without a REST round-trip; unknown devices send nothing
+func (c *Client) sendDeviceInfo(deviceID string) {
+	device := c.ss.GetDevice(deviceID)
+	if device == nil {
 		return
 	}
-	// Drain old frames (they're stale for a new subscriber anyway)
-	for len(c.send) > 0 {
-		select {
-		case <-c.send:
-		default:
+	data, err := json.Marshal(map[string]interface{}{
+		"type":      "device_info",
+		"device_id": deviceID,
+		"device":    device,
+	})
+	if err == nil {
+		c.trySend(data)
+	}
+}
+
+// deviceSubscriptionCount counts video subscriptions ("all" counts as one, topics don't count)
+func (c *Client) deviceSubscriptionCount() int {
+	count := 0
+	for key := range c.subscribed {
+		if !service.IsTopic(key) {
+			count++
 		}
 	}
-	// Send critical data
-	select {
-	case c.send <- msg:
-	default:
+	return count
+}
+
+// sendError sends a JSON error frame to this client
+func (c *Client) sendError(deviceID, message string) {
+	data, err := json.Marshal(map[string]interface{}{
+		"type":      "error",
+		"device_id": deviceID,
+		"error":     message,
+	})
+	if err == nil {
+		c.trySend(data)
+	}
+}
+
+// sendDeviceList replies with {type:"device_list", devices} (the GET /api/devices payload)
+func (c *Client) sendDeviceList() {
+	data, err := json.Marshal(map[string]interface{}{
+		"type":    "device_list",
+		"devices": c.dm.GetAllDevices(),
+	})
+	if err == nil {
+		c.trySend(data)
+	}
+}
+
+// sendControlRejected tells the client its input was dropped because another client holds the lock
+func (c *Client) sendControlRejected(deviceID string) {
+	data, err := json.Marshal(map[string]interface{}{
+		"type":      "control_rejected",
+		"device_id": deviceID,
+		"owner":     c.ss.ControlOwner(deviceID),
+	})
+	if err == nil {
+		c.trySend(data)
 	}
 }
 
@@ -137,22 +322,60 @@ func (h *WebSocketHub) BroadcastToDevice(deviceID string, message interface{}) {
 		}
 	}
 
-	subscribedCount := 0
+	// Topics (e.g. logcat) only go to exact subscribers, never to "all"
+	isTopic := service.IsTopic(deviceID)
+
+	_, isBinary := message.([]byte)
+	var flags byte
+	var isVideo bool
+	if isBinary {
+		flags, isVideo = service.VideoFrameFlags(messageBytes)
+	}
+
+	subscribedCount, dropped := 0, 0
 	for client := range h.clients {
 		// Send to clients subscribed to this device or subscribed to all
-		if client.subscribed[deviceID] || client.subscribed["all"] {
+		if client.subscribed[deviceID] || (!isTopic && client.subscribed["all"]) {
 			subscribedCount++
-			client.trySend(messageBytes) // Sử dụng trySend an toàn
+			if !isBinary {
+				client.trySend(messageBytes) // Sử dụng trySend an toàn
+			} else if !client.keyframes.allow(deviceID, flags, isVideo) {
+				continue // Joined mid-GOP: wait for the next IDR
+			} else if !client.queueFrame(deviceID, messageBytes) {
+				dropped++
+			}
 		}
 	}
 
-	// Only log non-H.264 frames to reduce spam
-	if _, isBinary := message.([]byte); !isBinary {
+	// Track media frame drops for adaptive bitrate
+	if isBinary && subscribedCount > 0 {
+		h.recordDelivery(deviceID, subscribedCount-dropped, dropped)
+	}
+
+	// Only log non-H.264 frames to reduce spam (topic lines are spam too)
+	if !isBinary && !isTopic {
 		log.Printf("📡 WebSocket: Sent %d bytes to %d/%d clients for device %s",
 			len(messageBytes), subscribedCount, len(h.clients), deviceID)
 	}
 }
 
+// ClientCount returns the number of connected WebSocket clients
+func (h *WebSocketHub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// PublishEvent delivers an event from the bus: topic events go to that device's subscribers,
+// the rest to every client
+func (h *WebSocketHub) PublishEvent(event events.Event) {
+	if event.Topic != "" {
+		h.BroadcastToDevice(event.Topic, event.Payload)
+		return
+	}
+	h.BroadcastToAll(event.Payload)
+}
+
 // BroadcastToAll sends a message to all connected clients
 func (h *WebSocketHub) BroadcastToAll(message interface{}) {
 	h.mu.RLock()
@@ -169,19 +392,68 @@ func (h *WebSocketHub) BroadcastToAll(message interface{}) {
 	}
 }
 
-func HandleWebSocket(hub *WebSocketHub, ss *service.StreamingService, c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+// Close sends a "going away" close frame to every client and closes the connections
+// Used on shutdown - http.Server.Shutdown doesn't track hijacked WebSocket connections
+func (h *WebSocketHub) Close() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for client := range h.clients {
+		client.closed.Store(true)
+		client.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		client.conn.Close()
+	}
+	log.Printf("🔌 WebSocket hub closed (%d clients)", len(h.clients))
+}
+
+func HandleWebSocket(hub *WebSocketHub, ss *service.StreamingService, dm *service.DeviceManager, token string, c *gin.Context) {
+	// Validate token before upgrading
+	protocol, ok := wsTokenProtocol(c.Request, token)
+	if !ok {
+		log.Printf("WebSocket rejected: invalid token from %s", c.ClientIP())
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	var responseHeader http.Header
+	if protocol != "" {
+		// Echo the subprotocol back, otherwise browsers drop the connection
+		responseHeader = http.Header{"Sec-WebSocket-Protocol": {protocol}}
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, responseHeader)
 	if err != nil {
 		log.Printf("WebSocket upgrade failed: %v", err)
 		return
 	}
+	conn.SetCompressionLevel(flate.BestSpeed) // Only used if the client negotiated deflate
 
 	client := &Client{
 		hub:        hub,
 		conn:       conn,
-		send:       make(chan []byte, 16), // Real-time mode: small buffer, drop old frames
+		send:       make(chan []byte, 16), // JSON messages: small buffer, drop oldest
+		frames:     newFrameQueue(),
+		keyframes:  newKeyframeGate(),
 		subscribed: make(map[string]bool),
 		ss:         ss, // Gán service
+		dm:         dm,
+
+		maxSubscriptions: config.MaxSubscriptions(),
+
+		id:         fmt.Sprintf("client_%d", time.Now().UnixNano()),
+		controlled: make(map[string]bool),
+	}
+	client.clientID = client.id
+	if id := c.Query("client_id"); id != "" && len(id) <= maxClientIDLength {
+		client.clientID = id
+	}
+	if name := c.Query("policy"); name != "" {
+		if policy, ok := ParseSendPolicy(name); ok {
+			client.policy.Store(policy)
+		} else {
+			log.Printf("⚠️ Unknown send policy %q, using drop_oldest", name)
+		}
 	}
 
 	client.hub.register <- client
@@ -191,13 +463,90 @@ func HandleWebSocket(hub *WebSocketHub, ss *service.StreamingService, c *gin.Con
 	go client.readPump()
 }
 
+// subscribeLogcat starts (or joins) logcat streaming for a logcat:<deviceID> key
+// Subscribing again with "filters" restarts the device's logcat with them
+func (c *Client) subscribeLogcat(key string, msg map[string]interface{}) {
+	if c.ss == nil {
+		return
+	}
+	deviceID := strings.TrimPrefix(key, service.LogcatTopicPrefix)
+
+	// nil (no "filters" field) joins with the current filters; [] clears them
+	var filters []string
+	if raw, ok := msg["filters"].([]interface{}); ok {
+		filters = make([]string, 0, len(raw))
+		for _, f := range raw {
+			if fs, ok := f.(string); ok {
+				filters = append(filters, fs)
+			}
+		}
+	}
+
+	if c.subscribed[key] {
+		if filters == nil {
+			return
+		}
+		if err := c.ss.SetLogcatFilters(deviceID, filters); err != nil {
+			log.Printf("⚠️ Logcat filter update failed: %v", err)
+			c.sendError(key, err.Error())
+		}
+		return
+	}
+
+	if err := c.ss.StartLogcat(deviceID, filters); err != nil {
+		log.Printf("⚠️ Logcat subscribe failed: %v", err)
+		c.sendError(key, err.Error())
+		return
+	}
+	c.subscribed[key] = true
+	log.Printf("Client subscribed to %s", key)
+}
+
+// subscribeStats starts (or joins) CPU/memory sampling for a stats:<deviceID>:<pkg> key
+func (c *Client) subscribeStats(key string) {
+	if c.ss == nil || c.subscribed[key] {
+		return
+	}
+
+	deviceID, pkg, ok := service.ParseStatsTopic(key)
+	if !ok {
+		c.sendError(key, "expected stats:<device_id>:<package>")
+		return
+	}
+	if err := c.ss.StartStatsSampling(deviceID, pkg); err != nil {
+		log.Printf("⚠️ Stats subscribe failed: %v", err)
+		c.sendError(deviceID, err.Error())
+		return
+	}
+	c.subscribed[key] = true
+	log.Printf("Client subscribed to %s", key)
+}
+
+// releaseSubscription undoes the service-side effect of a subscription
+// Video subscriptions drop a viewer, logcat/stats subscriptions drop a topic subscriber
+func (c *Client) releaseSubscription(key string) {
+	if strings.HasPrefix(key, service.LogcatTopicPrefix) {
+		c.ss.StopLogcat(strings.TrimPrefix(key, service.LogcatTopicPrefix))
+		return
+	}
+	if deviceID, pkg, ok := service.ParseStatsTopic(key); ok {
+		c.ss.StopStatsSampling(deviceID, pkg)
+		return
+	}
+	c.ss.RemoveViewer(key, c.clientID)
+}
+
 // readPump handles incoming messages from the client (subscriptions)
 func (c *Client) readPump() {
 	defer func() {
 		// Warm session: decrement viewer count for all subscribed devices
 		if c.ss != nil {
 			for deviceID := range c.subscribed {
-				c.ss.RemoveViewer(deviceID)
+				c.releaseSubscription(deviceID)
+			}
+			// Input lock: hand control back so others aren't locked out
+			for deviceID := range c.controlled {
+				c.ss.ReleaseControl(deviceID, c.id)
 			}
 		}
 		c.hub.unregister <- c
@@ -206,8 +555,10 @@ func (c *Client) readPump() {
 
 	c.conn.SetReadLimit(1 << 20) // 1MB max message size
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(appData string) error {
+		now := time.Now()
+		c.rtt.observePong(appData, now)
+		c.conn.SetReadDeadline(now.Add(pongWait))
 		return nil
 	})
 
@@ -224,38 +575,113 @@ func (c *Client) readPump() {
 		var msg map[string]interface{}
 		if err := json.Unmarshal(message, &msg); err == nil {
 			if msgType, ok := msg["type"].(string); ok {
+				// Input lock: drop input from clients that don't hold control
+				if inputMessageTypes[msgType] && c.ss != nil {
+					deviceID, _ := msg["device_id"].(string)
+					if err := c.ss.CheckControl(deviceID, c.id); err != nil {
+						c.sendControlRejected(deviceID)
+						continue
+					}
+				}
+
 				switch msgType {
 				case "subscribe":
-					if deviceID, ok := msg["device_id"].(string); ok {
+					if deviceID, ok := msg["device_id"].(string); ok && strings.HasPrefix(deviceID, service.LogcatTopicPrefix) {
+						// Logcat subscription: logcat:<deviceID> with optional filters
+						c.subscribeLogcat(deviceID, msg)
+					} else if ok && strings.HasPrefix(deviceID, service.StatsTopicPrefix) {
+						// App CPU/memory samples: stats:<deviceID>:<pkg>
+						c.subscribeStats(deviceID)
+					} else if ok {
+						if !c.subscribed[deviceID] && c.deviceSubscriptionCount() >= c.maxSubscriptions {
+							log.Printf("⚠️ Subscription to %s rejected: limit %d reached", deviceID, c.maxSubscriptions)
+							c.sendError(deviceID, fmt.Sprintf("subscription limit reached (%d devices)", c.maxSubscriptions))
+							break
+						}
 						c.subscribed[deviceID] = true
 						log.Printf("Client subscribed to device %s", deviceID)
 
+						// Live P-frames reference pictures this client never got: hold them until an IDR
+						c.keyframes.wait(deviceID)
+
 						// Warm session: increment viewer count
 						if c.ss != nil {
-							c.ss.AddViewer(deviceID)
+							c.ss.AddViewer(deviceID, c.clientID)
+							c.sendDeviceInfo(deviceID)
 
-							// Send cached SPS + PPS + IDR separately (frontend expects 1 NAL per message)
-							sps, pps, idr := c.ss.GetStreamData(deviceID)
-							if sps != nil {
-								c.drainAndSend(sps) // Drain old frames, send SPS
-							}
-							if pps != nil {
-								c.trySend(pps)
-							}
-							if idr != nil {
-								log.Printf("⚡ Sending cached SPS+PPS+IDR to new subscriber for %s", deviceID)
-								c.trySend(idr)
+							// Send cached headers + IDR separately (frontend expects 1 NAL per message)
+							if c.sendCachedHeaders(deviceID) {
+								log.Printf("⚡ Sending cached headers+IDR to new subscriber for %s", deviceID)
+								// The gate opens at the next IDR; ask for one now rather than wait out the GOP
+								go c.ss.RequestKeyframe(deviceID, keyframeTimeout)
 							}
 						}
 					}
 				case "unsubscribe":
-					if deviceID, ok := msg["device_id"].(string); ok {
+					if deviceID, ok := msg["device_id"].(string); ok && c.subscribed[deviceID] {
 						delete(c.subscribed, deviceID)
+						c.frames.remove(deviceID)
+						c.keyframes.release(deviceID)
 						log.Printf("Client unsubscribed from device %s", deviceID)
 
 						// Warm session: decrement viewer count
 						if c.ss != nil {
-							c.ss.RemoveViewer(deviceID)
+							c.releaseSubscription(deviceID)
+						}
+					}
+
+				case "list_devices":
+					// Same payload as GET /api/devices, so a WebSocket-only client can bootstrap
+					if c.dm != nil {
+						c.sendDeviceList()
+					}
+
+				case "policy":
+					// Frame-drop policy when this client falls behind (see SendPolicy)
+					name, _ := msg["policy"].(string)
+					policy, ok := ParseSendPolicy(name)
+					if !ok {
+						c.sendError("", fmt.Sprintf("unknown policy %q (drop_oldest, drop_newest, block)", name))
+						break
+					}
+					c.policy.Store(policy)
+					log.Printf("Client %s send policy: %s", c.id, policy)
+
+				case "pause", "resume":
+					// Explicitly stop/restart a device's capture (independent of viewer counting)
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						var err error
+						if msgType == "pause" {
+							err = c.ss.PauseStreaming(deviceID)
+						} else {
+							err = c.ss.ResumeStreaming(deviceID)
+						}
+						if err != nil {
+							log.Printf("⚠️ Stream %s failed: %v", msgType, err)
+							c.sendError(deviceID, err.Error())
+						}
+					}
+
+				case "take_control", "release_control":
+					// Input lock: only the owner's input reaches the device
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						if msgType == "release_control" {
+							c.ss.ReleaseControl(deviceID, c.id)
+							delete(c.controlled, deviceID)
+						} else if c.ss.AcquireControl(deviceID, c.id) {
+							c.controlled[deviceID] = true
+							// Owner broadcasts carry client IDs - tell the client which one is its own
+							if data, err := json.Marshal(map[string]interface{}{
+								"type":      "control_granted",
+								"device_id": deviceID,
+								"client_id": c.id,
+							}); err == nil {
+								c.trySend(data)
+							}
+						} else {
+							c.sendControlRejected(deviceID)
 						}
 					}
 
@@ -269,11 +695,208 @@ func (c *Client) readPump() {
 						if m, ok := msg["meta"].(float64); ok {
 							meta = int(m)
 						}
-						if err := c.ss.SendKeyEvent(deviceID, action, keycode, meta); err != nil {
+						repeat, _ := msg["repeat"].(float64) // Auto-repeat count for held keys
+						if err := c.ss.SendKeyEvent(deviceID, action, keycode, int(repeat), meta); err != nil {
 							log.Printf("⚠️ Key event failed: %v", err)
 						}
 					}
 
+				case "longpress":
+					// Held key: DOWN, auto-repeats, UP after duration ms (default 1000)
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						keycode, ok := msg["keycode"].(float64)
+						duration := 1000.0
+						if d, ok := msg["duration"].(float64); ok {
+							duration = d
+						}
+						if !ok {
+							c.sendError(deviceID, "longpress requires keycode")
+						} else if err := c.ss.SendLongPress(deviceID, int(keycode), int(duration)); err != nil {
+							log.Printf("⚠️ Long press failed: %v", err)
+							c.sendError(deviceID, err.Error())
+						}
+					}
+
+				case "back":
+					// BACK (or screen on): full press unless a single action is given
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						actions := []int{service.ActionDown, service.ActionUp}
+						if a, ok := msg["action"].(float64); ok {
+							actions = []int{int(a)}
+						}
+						for _, action := range actions {
+							if err := c.ss.SendBackOrScreenOn(deviceID, action); err != nil {
+								log.Printf("⚠️ Back failed: %v", err)
+								break
+							}
+						}
+					}
+
+				case "display_power":
+					// Screen off while mirroring (unattended farms), or back on
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						on, ok := msg["on"].(bool)
+						if !ok {
+							c.sendError(deviceID, "display_power requires on (boolean)")
+						} else if err := c.ss.SetDisplayPower(deviceID, on); err != nil {
+							log.Printf("⚠️ Display power failed: %v", err)
+							c.sendError(deviceID, err.Error())
+						}
+					}
+
+				case "home":
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						if err := c.ss.SendHome(deviceID); err != nil {
+							log.Printf("⚠️ Home failed: %v", err)
+						}
+					}
+
+				case "appswitch":
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						if err := c.ss.SendAppSwitch(deviceID); err != nil {
+							log.Printf("⚠️ App switch failed: %v", err)
+						}
+					}
+
+				case "touch":
+					// Low-latency touch over the scrcpy control socket
+					// width/height must be the current video size, unless normalized:
+					// then x/y are 0.0-1.0 and the backend scales them to the device
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						action, _ := msg["action"].(float64) // 0=down, 1=up, 2=move
+						x, _ := msg["x"].(float64)
+						y, _ := msg["y"].(float64)
+						width, _ := msg["width"].(float64)
+						height, _ := msg["height"].(float64)
+						normalized, _ := msg["normalized"].(bool)
+
+						pointerID := service.PointerIDGenericFinger
+						if p, ok := msg["pointer_id"].(float64); ok {
+							pointerID = uint64(p)
+						}
+						pressure := float32(1.0)
+						if int(action) == service.MotionActionUp {
+							pressure = 0
+						}
+						if p, ok := msg["pressure"].(float64); ok {
+							pressure = float32(p)
+						}
+						buttons := 0
+						if b, ok := msg["buttons"].(float64); ok {
+							buttons = int(b)
+						}
+
+						if err := c.ss.SendTouch(deviceID, int(action), pointerID, x, y, int(width), int(height), normalized, pressure, buttons); err != nil {
+							log.Printf("⚠️ Touch event failed: %v", err)
+						}
+					}
+
+				case "stylus":
+					// Stylus point with pressure 0.0-1.0 (required), same coordinates as touch
+					// buttons: 32 = primary barrel button, 64 = secondary
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						action, _ := msg["action"].(float64) // 0=down, 1=up, 2=move
+						x, _ := msg["x"].(float64)
+						y, _ := msg["y"].(float64)
+						width, _ := msg["width"].(float64)
+						height, _ := msg["height"].(float64)
+						normalized, _ := msg["normalized"].(bool)
+						buttons, _ := msg["buttons"].(float64)
+
+						pressure, ok := msg["pressure"].(float64)
+						if !ok {
+							c.sendError(deviceID, "stylus requires pressure (0.0-1.0)")
+						} else if err := c.ss.SendStylus(deviceID, int(action), x, y, int(width), int(height), normalized, float32(pressure), int(buttons)); err != nil {
+							log.Printf("⚠️ Stylus event failed: %v", err)
+							c.sendError(deviceID, err.Error())
+						}
+					}
+
+				case "swipe":
+					// Swipe as interpolated touch events (adb fallback without control socket)
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						x1, _ := msg["x1"].(float64)
+						y1, _ := msg["y1"].(float64)
+						x2, _ := msg["x2"].(float64)
+						y2, _ := msg["y2"].(float64)
+						duration := 300.0 // default, matches the swipe action
+						if d, ok := msg["duration"].(float64); ok && d > 0 {
+							duration = d
+						}
+
+						if err := c.ss.SendSwipeGesture(deviceID, int(x1), int(y1), int(x2), int(y2), int(duration)); err != nil {
+							log.Printf("⚠️ Swipe failed: %v", err)
+						}
+					}
+
+				case "pinch":
+					// Two-finger pinch (control socket only)
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						cx, _ := msg["center_x"].(float64)
+						cy, _ := msg["center_y"].(float64)
+						startSpread, _ := msg["start_spread"].(float64)
+						endSpread, _ := msg["end_spread"].(float64)
+						duration := 300.0
+						if d, ok := msg["duration"].(float64); ok && d > 0 {
+							duration = d
+						}
+
+						if err := c.ss.SendPinch(deviceID, int(cx), int(cy), int(startSpread), int(endSpread), int(duration)); err != nil {
+							log.Printf("⚠️ Pinch failed: %v", err)
+						}
+					}
+
+				case "drag":
+					// Long-press then drag (control socket only), e.g. reordering icons
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						x1, _ := msg["x1"].(float64)
+						y1, _ := msg["y1"].(float64)
+						x2, _ := msg["x2"].(float64)
+						y2, _ := msg["y2"].(float64)
+						hold := 600.0 // Longer than Android's long-press timeout (400-500ms)
+						if h, ok := msg["hold"].(float64); ok && h >= 0 {
+							hold = h
+						}
+						move := 500.0
+						if m, ok := msg["duration"].(float64); ok && m > 0 {
+							move = m
+						}
+
+						if err := c.ss.SendDrag(deviceID, int(x1), int(y1), int(x2), int(y2), int(hold), int(move)); err != nil {
+							log.Printf("⚠️ Drag failed: %v", err)
+						}
+					}
+
+				case "scroll":
+					// Mouse wheel scroll (h_scroll/v_scroll in wheel steps, positive = left/up)
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						x, _ := msg["x"].(float64)
+						y, _ := msg["y"].(float64)
+						width, _ := msg["width"].(float64)
+						height, _ := msg["height"].(float64)
+						hScroll, _ := msg["h_scroll"].(float64)
+						vScroll, _ := msg["v_scroll"].(float64)
+						buttons := 0
+						if b, ok := msg["buttons"].(float64); ok {
+							buttons = int(b)
+						}
+
+						if err := c.ss.SendScroll(deviceID, int(x), int(y), int(width), int(height), float32(hScroll), float32(vScroll), buttons); err != nil {
+							log.Printf("⚠️ Scroll event failed: %v", err)
+						}
+					}
+
 				case "text":
 					// Direct text injection
 					if c.ss != nil {
@@ -284,6 +907,20 @@ func (c *Client) readPump() {
 						}
 					}
 
+				case "typekeys":
+					// Type text as real key events (fields that ignore injected text)
+					if c.ss != nil {
+						deviceID, _ := msg["device_id"].(string)
+						text, _ := msg["text"].(string)
+						// Typing sleeps between keys - don't hold up the read loop
+						go func() {
+							if err := c.ss.TypeAsKeys(deviceID, text); err != nil {
+								log.Printf("⚠️ Key typing failed: %v", err)
+								c.sendError(deviceID, err.Error())
+							}
+						}()
+					}
+
 				case "clipboard":
 					// Clipboard set/paste
 					if c.ss != nil {
@@ -293,7 +930,20 @@ func (c *Client) readPump() {
 						if p, ok := msg["paste"].(bool); ok {
 							paste = p
 						}
-						if err := c.ss.SendClipboard(deviceID, text, paste); err != nil {
+						wait, _ := msg["wait"].(bool) // Confirm with the device's ack: {type:"clipboard_ack"} or an error
+						if wait {
+							// Blocks up to the ack timeout - don't hold up the read loop
+							go func() {
+								if err := c.ss.SendClipboard(deviceID, text, paste, true); err != nil {
+									log.Printf("⚠️ Clipboard operation failed: %v", err)
+									c.sendError(deviceID, err.Error())
+									return
+								}
+								if data, err := json.Marshal(map[string]interface{}{"type": "clipboard_ack", "device_id": deviceID}); err == nil {
+									c.trySend(data)
+								}
+							}()
+						} else if err := c.ss.SendClipboard(deviceID, text, paste, false); err != nil {
 							log.Printf("⚠️ Clipboard operation failed: %v", err)
 						} else {
 							log.Printf("📋 Clipboard %s for %s (%d chars)", map[bool]string{true: "pasted", false: "set"}[paste], deviceID, len(text))
@@ -307,17 +957,16 @@ func (c *Client) readPump() {
 						if deviceID == "" {
 							break
 						}
-						// Send SPS+PPS+IDR as separate packets
-						sps, pps, idr := c.ss.GetStreamData(deviceID)
-						if sps != nil {
-							c.drainAndSend(sps) // Drain old frames, send SPS
-						}
-						if pps != nil {
-							c.trySend(pps)
-						}
-						if idr != nil {
-							c.trySend(idr)
-						}
+						// Ask the encoder for a fresh IDR - it reaches subscribers via the normal broadcast.
+						// Fall back to cached headers + IDR if it doesn't arrive in time
+						subscribed := c.subscribed[deviceID] || c.subscribed["all"]
+						go func() {
+							if subscribed && c.ss.RequestKeyframe(deviceID, keyframeTimeout) {
+								return
+							}
+							c.keyframes.wait(deviceID) // Decoder restarts from the cached IDR
+							c.sendCachedHeaders(deviceID)
+						}()
 					}
 				}
 			}
@@ -355,29 +1004,70 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case frame, ok := <-c.send:
+		case msg, ok := <-c.send:
 			if !ok || c.closed.Load() {
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
-
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-
-			// Detect Binary vs JSON using robust check
-			msgType := websocket.BinaryMessage
-			if isJSONPayload(frame) {
-				msgType = websocket.TextMessage
+			if err := c.writeFrame(msg); err != nil {
+				return
 			}
 
-			if err := c.conn.WriteMessage(msgType, frame); err != nil {
-				return
+		case <-c.frames.ready:
+			// Drain binary frames round-robin across devices, letting JSON and pings cut in
+			// between (a client that is always busy must still be pinged or its read deadline expires)
+			for {
+				if c.closed.Load() {
+					c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+					return
+				}
+				select {
+				case msg := <-c.send:
+					if err := c.writeFrame(msg); err != nil {
+						return
+					}
+				case <-ticker.C:
+					if err := c.writePing(); err != nil {
+						return
+					}
+				default:
+				}
+
+				frame, ok := c.frames.pop()
+				if !ok {
+					break
+				}
+				if err := c.writeFrame(frame); err != nil {
+					return
+				}
 			}
 
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := c.writePing(); err != nil {
 				return
 			}
 		}
 	}
 }
+
+// writePing sends a ping stamped with the current time (the pong carries it back for RTT)
+func (c *Client) writePing() error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(websocket.PingMessage, pingPayload(time.Now()))
+}
+
+// writeFrame writes one message, as text (compressed) for JSON and binary otherwise
+func (c *Client) writeFrame(frame []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+	// Detect Binary vs JSON using robust check
+	msgType := websocket.BinaryMessage
+	if isJSONPayload(frame) {
+		msgType = websocket.TextMessage
+	}
+
+	// Compress JSON only - H.264/audio payloads don't shrink and cost CPU
+	c.conn.EnableWriteCompression(msgType == websocket.TextMessage)
+
+	return c.conn.WriteMessage(msgType, frame)
+}
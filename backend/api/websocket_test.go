@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWritePumpPingsWhileFramesKeepArriving(t *testing.T) {
+	orig := pingPeriod
+	pingPeriod = 20 * time.Millisecond
+	t.Cleanup(func() { pingPeriod = orig })
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := &Client{conn: conn, send: make(chan []byte, 16), frames: newFrameQueue()}
+		go client.writePump()
+
+		// Keep the frame queue full so the drain loop never runs dry
+		frame := make([]byte, 512*1024)
+		frame[3], frame[4] = 1, 0x41
+		for {
+			select {
+			case <-stop:
+				client.closed.Store(true)
+				return
+			default:
+			}
+			for i := 0; i < perDeviceFrameQueue; i++ {
+				client.frames.push("dev", frame, SendPolicyDropOldest)
+			}
+			runtime.Gosched()
+		}
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var pings atomic.Int32
+	conn.SetPingHandler(func(string) error {
+		pings.Add(1)
+		return nil
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	frames := 0
+	for time.Now().Before(deadline) && pings.Load() < 3 {
+		conn.SetReadDeadline(deadline)
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+		frames++
+		time.Sleep(time.Millisecond) // A slow reader: the server's queue is always backed up
+	}
+	if pings.Load() < 3 {
+		t.Errorf("got %d pings alongside %d frames, want pings every %v", pings.Load(), frames, pingPeriod)
+	}
+}
+
+func TestInputLockGatesCaptureControl(t *testing.T) {
+	// Another client pausing the stream would blank the owner's session
+	for _, msgType := range []string{"pause", "resume", "display_power", "touch", "key"} {
+		if !inputMessageTypes[msgType] {
+			t.Errorf("%s is not gated by the input lock", msgType)
+		}
+	}
+	for _, msgType := range []string{"subscribe", "unsubscribe", "take_control", "release_control"} {
+		if inputMessageTypes[msgType] {
+			t.Errorf("%s must work without holding the input lock", msgType)
+		}
+	}
+}
@@ -1,5 +1,13 @@
 package config
 
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
 const (
 	// Server configuration
 	HTTPPort = ":8080"
@@ -12,3 +20,263 @@ const (
 	ScreenRefreshRate = 30 // FPS
 	ScreenQuality     = 80 // JPEG quality 1-100
 )
+
+// DefaultScanInterval is how often devices are rescanned in the background
+const DefaultScanInterval = 10 * time.Second
+
+// ScanInterval returns the auto-scan interval from SCAN_INTERVAL (e.g. "5s")
+// Returns 0 (disabled) for "0" or "off"
+func ScanInterval() time.Duration {
+	val := os.Getenv("SCAN_INTERVAL")
+	switch val {
+	case "":
+		return DefaultScanInterval
+	case "0", "off":
+		return 0
+	}
+
+	interval, err := time.ParseDuration(val)
+	if err != nil || interval < 0 {
+		log.Printf("Warning: Invalid SCAN_INTERVAL %q, using %v", val, DefaultScanInterval)
+		return DefaultScanInterval
+	}
+	return interval
+}
+
+// DefaultMaxSubscriptions caps simultaneous device subscriptions per WebSocket client
+const DefaultMaxSubscriptions = 64
+
+// MaxSubscriptions returns the per-client subscription cap (env WS_MAX_SUBSCRIPTIONS)
+func MaxSubscriptions() int {
+	val := os.Getenv("WS_MAX_SUBSCRIPTIONS")
+	if val == "" {
+		return DefaultMaxSubscriptions
+	}
+
+	limit, err := strconv.Atoi(val)
+	if err != nil || limit < 1 {
+		log.Printf("Warning: Invalid WS_MAX_SUBSCRIPTIONS %q, using %d", val, DefaultMaxSubscriptions)
+		return DefaultMaxSubscriptions
+	}
+	return limit
+}
+
+// DefaultInputRate caps touch MOVE events per device per second
+const DefaultInputRate = 60
+
+// InputRate returns the per-device touch MOVE cap (env INPUT_MAX_RATE, 0 = unlimited)
+func InputRate() int {
+	val := os.Getenv("INPUT_MAX_RATE")
+	if val == "" {
+		return DefaultInputRate
+	}
+
+	rate, err := strconv.Atoi(val)
+	if err != nil || rate < 0 {
+		log.Printf("Warning: Invalid INPUT_MAX_RATE %q, using %d", val, DefaultInputRate)
+		return DefaultInputRate
+	}
+	return rate
+}
+
+// DefaultActionWorkers is how many devices run queued actions at the same time
+const DefaultActionWorkers = 4
+
+// ActionWorkers returns the action worker count (env ACTION_WORKERS)
+func ActionWorkers() int {
+	val := os.Getenv("ACTION_WORKERS")
+	if val == "" {
+		return DefaultActionWorkers
+	}
+
+	workers, err := strconv.Atoi(val)
+	if err != nil || workers < 1 {
+		log.Printf("Warning: Invalid ACTION_WORKERS %q, using %d", val, DefaultActionWorkers)
+		return DefaultActionWorkers
+	}
+	return workers
+}
+
+// DefaultWarmSessionTTL keeps a stream alive after its last viewer leaves
+const DefaultWarmSessionTTL = 120 * time.Second
+
+// WarmSessionTTL returns the default warm session TTL (env WARM_SESSION_TTL, e.g. "30s")
+// "0" stops streams as soon as viewers hit zero; "never" or a negative value keeps them running
+func WarmSessionTTL() time.Duration {
+	val := os.Getenv("WARM_SESSION_TTL")
+	switch val {
+	case "":
+		return DefaultWarmSessionTTL
+	case "0":
+		return 0
+	case "never":
+		return -1
+	}
+
+	ttl, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("Warning: Invalid WARM_SESSION_TTL %q, using %v", val, DefaultWarmSessionTTL)
+		return DefaultWarmSessionTTL
+	}
+	return ttl
+}
+
+// Reconnect defaults: 3 retries after 2s, 4s and 8s
+const (
+	DefaultReconnectAttempts   = 3
+	DefaultReconnectBackoff    = 2 * time.Second
+	DefaultReconnectMultiplier = 2.0
+	DefaultReconnectMaxBackoff = 30 * time.Second
+)
+
+// ReconnectPolicy returns the scrcpy retry policy (env RECONNECT_MAX_ATTEMPTS, RECONNECT_BACKOFF,
+// RECONNECT_BACKOFF_MULTIPLIER, RECONNECT_MAX_BACKOFF); retry n waits backoff * multiplier^(n-1)
+func ReconnectPolicy() (maxAttempts int, backoff time.Duration, multiplier float64, maxBackoff time.Duration) {
+	maxAttempts = DefaultReconnectAttempts
+	if val := os.Getenv("RECONNECT_MAX_ATTEMPTS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			maxAttempts = n
+		} else {
+			log.Printf("Warning: Invalid RECONNECT_MAX_ATTEMPTS %q, using %d", val, DefaultReconnectAttempts)
+		}
+	}
+
+	multiplier = DefaultReconnectMultiplier
+	if val := os.Getenv("RECONNECT_BACKOFF_MULTIPLIER"); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil && f >= 1 && f <= 100 {
+			multiplier = f
+		} else {
+			log.Printf("Warning: Invalid RECONNECT_BACKOFF_MULTIPLIER %q, using %v", val, DefaultReconnectMultiplier)
+		}
+	}
+
+	return maxAttempts, durationEnv("RECONNECT_BACKOFF", DefaultReconnectBackoff),
+		multiplier, durationEnv("RECONNECT_MAX_BACKOFF", DefaultReconnectMaxBackoff)
+}
+
+// durationEnv reads a non-negative duration ("500ms", "2s") from env with a fallback default
+func durationEnv(key string, defaultVal time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d < 0 {
+		log.Printf("Warning: Invalid %s %q, using %v", key, val, defaultVal)
+		return defaultVal
+	}
+	return d
+}
+
+// LegacyFrames reports whether binary WebSocket frames use the pre-v2 layout (env WS_LEGACY_FRAMES)
+// Keep enabled until every frontend understands the v2 header
+func LegacyFrames() bool {
+	val := os.Getenv("WS_LEGACY_FRAMES")
+	if val == "" {
+		return false
+	}
+
+	enabled, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Printf("Warning: Invalid WS_LEGACY_FRAMES %q, using v2 frames", val)
+		return false
+	}
+	return enabled
+}
+
+// ShellAllowlist returns the commands the shell endpoint may run (env SHELL_ALLOWLIST, comma-separated)
+// Empty means any command not in the denylist
+func ShellAllowlist() []string {
+	return splitList(os.Getenv("SHELL_ALLOWLIST"))
+}
+
+// ShellDenylist returns commands the shell endpoint refuses (env SHELL_DENYLIST, e.g. "rm,reboot")
+// Best effort only: use SHELL_ALLOWLIST to actually restrict what runs
+func ShellDenylist() []string {
+	return splitList(os.Getenv("SHELL_DENYLIST"))
+}
+
+// DeviceAllowlist returns the only devices to track (env DEVICE_ALLOWLIST, comma-separated
+// hardware serials or ADB IDs); empty means every device not in the denylist
+func DeviceAllowlist() []string {
+	return splitList(os.Getenv("DEVICE_ALLOWLIST"))
+}
+
+// DeviceDenylist returns devices that must never be tracked, streamed or actioned (env DEVICE_DENYLIST)
+func DeviceDenylist() []string {
+	return splitList(os.Getenv("DEVICE_DENYLIST"))
+}
+
+// splitList splits a comma-separated env value, dropping empty entries
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// BatteryThresholds returns the battery alert levels (env BATTERY_LOW_THRESHOLD / BATTERY_HIGH_THRESHOLD)
+// Defaults: alert below 15% and above 95%; 0 disables that side
+func BatteryThresholds() (low, high int) {
+	return percentEnv("BATTERY_LOW_THRESHOLD", 15), percentEnv("BATTERY_HIGH_THRESHOLD", 95)
+}
+
+// percentEnv reads a 0-100 integer from env with a fallback default
+func percentEnv(key string, defaultVal int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+
+	percent, err := strconv.Atoi(val)
+	if err != nil || percent < 0 || percent > 100 {
+		log.Printf("Warning: Invalid %s %q, using %d", key, val, defaultVal)
+		return defaultVal
+	}
+	return percent
+}
+
+// DefaultStreamProfilesFile is read at startup when STREAM_PROFILES_FILE is unset (optional)
+const DefaultStreamProfilesFile = "stream_profiles.json"
+
+// StreamProfilesFile returns the JSON file with named stream profiles (env STREAM_PROFILES_FILE)
+func StreamProfilesFile() string {
+	if val := strings.TrimSpace(os.Getenv("STREAM_PROFILES_FILE")); val != "" {
+		return val
+	}
+	return DefaultStreamProfilesFile
+}
+
+// StreamProfile returns the global stream profile name (env STREAM_PROFILE, empty = built-in defaults)
+func StreamProfile() string {
+	return strings.TrimSpace(os.Getenv("STREAM_PROFILE"))
+}
+
+// TLSFiles returns the certificate and key paths for HTTPS/WSS (env TLS_CERT / TLS_KEY)
+// Both empty means plain HTTP
+func TLSFiles() (certFile, keyFile string) {
+	return strings.TrimSpace(os.Getenv("TLS_CERT")), strings.TrimSpace(os.Getenv("TLS_KEY"))
+}
+
+// APIToken returns the bearer token required by the API (env API_TOKEN)
+// Empty means auth is disabled (local dev)
+func APIToken() string {
+	return strings.TrimSpace(os.Getenv("API_TOKEN"))
+}
+
+// LogFormat returns the log output format (env LOG_FORMAT): "text" (default) or "json"
+func LogFormat() string {
+	switch val := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_FORMAT"))); val {
+	case "", "text":
+		return "text"
+	case "json":
+		return "json"
+	default:
+		log.Printf("Warning: Invalid LOG_FORMAT %q, using text", val)
+		return "text"
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 
@@ -11,6 +12,7 @@ import (
 const (
 	DatabasePath   = "./data/androidcontrol.db"
 	MigrationsPath = "./scripts/migrations.sql"
+	BaselinesDir   = "./data/baselines" // Screenshot baseline PNGs
 )
 
 // InitDatabase initializes the SQLite database
@@ -48,6 +50,51 @@ func runMigrations(db *sql.DB) error {
 	}
 
 	// Execute migrations
-	_, err = db.Exec(string(migrations))
-	return err
+	if _, err := db.Exec(string(migrations)); err != nil {
+		return err
+	}
+
+	// CREATE TABLE IF NOT EXISTS skips existing tables, so later columns are added here
+	for _, c := range addedColumns {
+		if err := ensureColumn(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addedColumns are columns added to tables after their first release
+var addedColumns = []struct{ table, column, definition string }{
+	{"devices", "hardware_serial", "TEXT"},
+}
+
+// ensureColumn adds a column to a table unless it already exists
+func ensureColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s.%s: %w", table, column, err)
+	}
+	log.Printf("💾 Added column %s.%s", table, column)
+	return nil
 }
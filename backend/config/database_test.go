@@ -0,0 +1,29 @@
+package config
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestEnsureColumnAddsMissingColumnOnce(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // One connection, one in-memory database
+
+	// A devices table from before hardware_serial existed
+	if _, err := db.Exec(`CREATE TABLE devices (id TEXT PRIMARY KEY, name TEXT NOT NULL, adb_device_id TEXT UNIQUE)`); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ensureColumn(db, "devices", "hardware_serial", "TEXT"); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO devices (id, name, hardware_serial) VALUES ('d1', 'Pixel', 'ABC123')`); err != nil {
+		t.Fatalf("insert with hardware_serial: %v", err)
+	}
+}
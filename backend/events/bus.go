@@ -0,0 +1,70 @@
+// Package events is a small in-process bus between services and the WebSocket hub
+// Services publish client-facing events here instead of importing the api package.
+package events
+
+import "sync"
+
+// Event is one message for WebSocket clients
+type Event struct {
+	Type    string      // Message type, mirrors the payload's "type" field
+	Topic   string      // Subscription key (device ID) it's delivered to; "" = every client
+	Payload interface{} // Sent to clients as JSON
+}
+
+// Handler receives published events on the publisher's goroutine, so it must not block
+type Handler func(Event)
+
+// subscription is one registered handler
+type subscription struct {
+	id      int
+	handler Handler
+}
+
+// Bus fans events out to its subscribers; a nil *Bus drops everything
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []subscription
+	nextID int
+}
+
+// NewBus creates an empty bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler and returns a function that removes it
+func (b *Bus) Subscribe(handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	b.subs = append(b.subs, subscription{id: id, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, sub := range b.subs {
+			if sub.id == id {
+				b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers an event to every subscriber in subscription order
+// Handlers run outside the lock, so they may subscribe or unsubscribe
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.handler(event)
+	}
+}
@@ -0,0 +1,167 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Log formats (env LOG_FORMAT)
+const (
+	FormatText = "text" // Emoji console lines via the stdlib logger (default)
+	FormatJSON = "json" // One JSON object per line for Loki/ELK
+)
+
+// Levels
+const (
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// entry is one structured log line
+type entry struct {
+	Level    string `json:"level"`
+	Time     string `json:"time"`
+	DeviceID string `json:"device_id,omitempty"`
+	Event    string `json:"event,omitempty"`
+	Message  string `json:"message"`
+}
+
+// output is where JSON lines go (nil = text mode, everything goes through the stdlib logger)
+var (
+	mu     sync.Mutex
+	output io.Writer
+)
+
+// Setup routes logging to out in the given format
+// In JSON mode plain log.Printf lines from the rest of the code are converted too:
+// the level comes from the leading emoji and device_id from a "[id]" tag
+func Setup(format string, out io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if format == FormatJSON {
+		output = out
+		log.SetOutput(lineWriter{})
+		log.SetFlags(0)
+		return
+	}
+	output = nil
+	log.SetOutput(out)
+	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
+}
+
+// Logger tags lines with a device ID; the zero value logs without one
+// Messages keep the console convention of a leading emoji: in text mode the
+// device tag is inserted after it ("📹 [id] msg"), in JSON mode it is dropped
+type Logger struct {
+	deviceID string
+}
+
+// Device returns a logger for one device
+func Device(deviceID string) Logger {
+	return Logger{deviceID: deviceID}
+}
+
+// Info logs a routine event
+func (l Logger) Info(event, format string, args ...interface{}) {
+	l.write(LevelInfo, event, fmt.Sprintf(format, args...))
+}
+
+// Warn logs a recoverable problem
+func (l Logger) Warn(event, format string, args ...interface{}) {
+	l.write(LevelWarn, event, fmt.Sprintf(format, args...))
+}
+
+// Error logs a failure
+func (l Logger) Error(event, format string, args ...interface{}) {
+	l.write(LevelError, event, fmt.Sprintf(format, args...))
+}
+
+func (l Logger) write(level, event, msg string) {
+	icon, text := splitIcon(msg)
+
+	mu.Lock()
+	out := output
+	mu.Unlock()
+
+	if out == nil {
+		switch {
+		case l.deviceID == "":
+			log.Print(msg)
+		case icon == "":
+			log.Printf("[%s] %s", l.deviceID, text)
+		default:
+			log.Printf("%s [%s] %s", icon, l.deviceID, text)
+		}
+		return
+	}
+	writeJSON(out, entry{Level: level, DeviceID: l.deviceID, Event: event, Message: text})
+}
+
+func writeJSON(out io.Writer, e entry) {
+	e.Time = time.Now().Format(time.RFC3339Nano)
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	out.Write(append(line, '\n'))
+}
+
+// splitIcon separates a leading emoji token from the message text
+func splitIcon(msg string) (icon, text string) {
+	r, _ := utf8.DecodeRuneInString(msg)
+	if r < utf8.RuneSelf {
+		return "", msg
+	}
+	icon, text, _ = strings.Cut(msg, " ")
+	return icon, text
+}
+
+// lineWriter converts stdlib log lines to JSON in JSON mode
+type lineWriter struct{}
+
+func (lineWriter) Write(p []byte) (int, error) {
+	mu.Lock()
+	out := output
+	mu.Unlock()
+	if out == nil {
+		return len(p), nil
+	}
+
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		writeJSON(out, parseLine(string(line)))
+	}
+	return len(p), nil
+}
+
+// parseLine derives level and device_id from a console line like "⚠️ [id] msg" or "Warning: msg"
+func parseLine(line string) entry {
+	e := entry{Level: LevelInfo}
+	icon, text := splitIcon(line)
+	switch {
+	case strings.HasPrefix(icon, "⚠"), strings.HasPrefix(text, "Warning:"):
+		e.Level = LevelWarn
+	case strings.HasPrefix(icon, "❌"):
+		e.Level = LevelError
+	}
+
+	if strings.HasPrefix(text, "[") {
+		if id, rest, ok := strings.Cut(text[1:], "] "); ok && !strings.ContainsAny(id, " ") {
+			e.DeviceID = id
+			text = rest
+		}
+	}
+	e.Message = text
+	return e
+}
@@ -2,12 +2,20 @@ package main
 
 import (
 	"androidcontrol/api"
+	"androidcontrol/config"
+	"androidcontrol/events"
+	"androidcontrol/logging"
 	"androidcontrol/service"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -31,10 +39,8 @@ func setupLogging() (*os.File, error) {
 		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
 
-	// Write to both console and file
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
-	log.SetOutput(multiWriter)
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
+	// Write to both console and file (JSON lines with LOG_FORMAT=json)
+	logging.Setup(config.LogFormat(), io.MultiWriter(os.Stdout, logFile))
 
 	log.Printf("📝 Logging to: %s", logPath)
 	return logFile, nil
@@ -44,6 +50,7 @@ func main() {
 	// Setup file logging
 	logFile, err := setupLogging()
 	if err != nil {
+		logging.Setup(config.LogFormat(), os.Stdout)
 		log.Printf("Warning: Failed to setup file logging: %v", err)
 	} else {
 		defer logFile.Close()
@@ -51,47 +58,145 @@ func main() {
 
 	log.Println("Starting Android Control Backend...")
 
-	// Initialize services (without database for now)
-	deviceManager := service.NewDeviceManager(nil)
-	actionDispatcher := service.NewActionDispatcher(deviceManager)
+	// Initialize database (optional - devices are kept in memory only without it)
+	db, err := config.InitDatabase()
+	if err != nil {
+		log.Printf("Warning: Failed to initialize database, running without persistence: %v", err)
+		db = nil
+	} else {
+		defer db.Close()
+	}
+
+	// Initialize services
+	deviceManager := service.NewDeviceManager(db)
+	deviceManager.SetDeviceFilter(service.DeviceFilter{Allow: config.DeviceAllowlist(), Deny: config.DeviceDenylist()})
+	actionDispatcher := service.NewActionDispatcher(deviceManager, db, config.ActionWorkers())
+	groupManager := service.NewGroupManager(db)
+	baselineStore := service.NewBaselineStore(db, config.BaselinesDir)
 
 	// Initialize WebSocket hub
 	wsHub := api.NewWebSocketHub()
 	go wsHub.Run()
 
+	// Device and stream status events reach clients through the bus, not the hub directly
+	bus := events.NewBus()
+	bus.Subscribe(wsHub.PublishEvent)
+	deviceManager.SetEventBus(bus)
+
 	// Initialize streaming service
 	streamingService := service.NewStreamingService(deviceManager, wsHub)
+	streamingService.SetEventBus(bus)
+	// Preflight: missing adb/scrcpy-server/ffmpeg disables the features that need them (501) instead of failing later
+	streamingService.SetCapabilities(service.Preflight(deviceManager.GetADBClient(), service.DefaultServerConfig()))
+	wsHub.SetBackpressureHandler(streamingService.ReportFrameDrops) // Adaptive bitrate feedback
+	streamingService.SetInputRate(config.InputRate())
+	streamingService.SetDefaultWarmTTL(config.WarmSessionTTL())
+	maxAttempts, backoff, multiplier, maxBackoff := config.ReconnectPolicy()
+	if err := streamingService.SetReconnectPolicy(service.ReconnectPolicy{
+		MaxAttempts: maxAttempts, BaseBackoff: backoff, Multiplier: multiplier, MaxBackoff: maxBackoff,
+	}); err != nil {
+		log.Printf("Warning: reconnect policy: %v, using defaults", err)
+	}
+	service.SetLegacyFrames(config.LegacyFrames())
+	profiles, err := service.LoadStreamProfiles(config.StreamProfilesFile())
+	if err != nil {
+		log.Printf("Warning: %v, using built-in stream profiles", err)
+	}
+	streamingService.SetStreamProfiles(profiles)
+	if err := streamingService.SetGlobalProfile(config.StreamProfile()); err != nil {
+		log.Printf("Warning: STREAM_PROFILE: %v", err)
+	}
 	log.Println("Streaming service initialized")
 
 	// Setup HTTP server
 	router := gin.Default()
-	api.SetupRoutes(router, deviceManager, actionDispatcher, wsHub, streamingService)
+	api.SetupRoutes(router, deviceManager, actionDispatcher, wsHub, streamingService, groupManager, baselineStore)
 
-	// Start server
-	log.Println("Server starting on http://localhost:8080")
-	log.Println("WebSocket server on ws://localhost:8080/ws")
+	// Start server (HTTPS + WSS on the same port when a certificate is configured)
+	certFile, keyFile := config.TLSFiles()
+	useTLS := certFile != "" || keyFile != ""
+	if useTLS && (certFile == "" || keyFile == "") {
+		log.Fatal("TLS_CERT and TLS_KEY must be set together")
+	}
+	if useTLS {
+		log.Printf("🔒 TLS enabled (cert: %s)", certFile)
+		log.Println("Server starting on https://localhost:8080")
+		log.Println("WebSocket server on wss://localhost:8080/ws")
+	} else {
+		log.Println("Server starting on http://localhost:8080 (TLS disabled, set TLS_CERT/TLS_KEY for https/wss)")
+		log.Println("WebSocket server on ws://localhost:8080/ws")
+	}
 	log.Println("Ready to stream screens @ 30 FPS")
 
+	// Device online/offline events -> auto start/stop streaming (clients get them from the bus)
+	deviceManager.SetEventHandler(streamingService.HandleDeviceEvent)
+	deviceManager.SetBatteryThresholds(config.BatteryThresholds())
+
 	// Auto-start streaming for all devices in background
 	go func() {
 		log.Println("🚀 Scanning devices for auto-streaming...")
-		// Scan devices first
+		// Initial scan emits "online" events, which start the H.264 streams
 		if err := deviceManager.ScanDevices(); err != nil {
 			log.Printf("Warning: Failed to scan devices: %v", err)
-			return
+		} else {
+			devices := deviceManager.GetAllDevices()
+			log.Printf("📱 Found %d devices, starting H.264 streams...", len(devices))
 		}
 
-		devices := deviceManager.GetAllDevices()
-		log.Printf("📱 Found %d devices, starting H.264 streams...", len(devices))
+		// Keep rescanning so devices plugged in later show up
+		if interval := config.ScanInterval(); interval > 0 {
+			deviceManager.StartAutoScan(interval)
+		}
+	}()
 
-		if err := streamingService.StartAllStreaming(); err != nil {
-			log.Printf("Warning: Failed to auto-start streaming: %v", err)
+	server := &http.Server{
+		Addr:    config.HTTPPort,
+		Handler: router,
+	}
+
+	go func() {
+		var err error
+		if useTLS {
+			err = server.ListenAndServeTLS(certFile, keyFile)
 		} else {
-			log.Println("✅ Auto-streaming started successfully for all devices")
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
 		}
 	}()
 
-	if err := router.Run(":8080"); err != nil {
-		log.Fatal("Failed to start server:", err)
+	// Wait for Ctrl-C / SIGTERM, then tear down scrcpy servers and ADB forwards
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+
+	shutdown(server, deviceManager, streamingService, wsHub)
+}
+
+// shutdownTimeout bounds the whole graceful shutdown
+const shutdownTimeout = 10 * time.Second
+
+// shutdown stops all streams (each cleanup kills scrcpy and removes its forward),
+// closes WebSocket clients and then the HTTP server
+func shutdown(server *http.Server, dm *service.DeviceManager, ss *service.StreamingService, wsHub *api.WebSocketHub) {
+	log.Println("🛑 Shutting down...")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	dm.StopAutoScan()
+	ss.StopAllStreaming()
+	ss.StopAllLogcats()
+	ss.StopAllStatsSampling()
+	if err := ss.WaitAllStopped(ctx); err != nil {
+		log.Printf("⚠️ Streams did not stop in time: %v", err)
+	}
+	dm.CleanupReverses()
+
+	wsHub.Close()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("⚠️ HTTP server shutdown: %v", err)
 	}
+	log.Println("👋 Shutdown complete")
 }
@@ -3,10 +3,10 @@ package models
 type Action struct {
 	ID        string                 `json:"id"`
 	DeviceID  string                 `json:"device_id"`
-	Type      string                 `json:"type"` // tap, swipe, input, key, open_app
+	Type      string                 `json:"type"` // tap, swipe, input, key, open_app, uninstall, force_stop, clear_data, reboot, screen_power, set_size, set_density, reset_display
 	Params    map[string]interface{} `json:"params"`
 	Timestamp int64                  `json:"timestamp"`
-	Status    string                 `json:"status"` // pending, executing, done, failed
+	Status    string                 `json:"status"` // pending, executing, done, failed, skipped, cancelled
 	Result    string                 `json:"result,omitempty"`
 }
 
@@ -14,6 +14,14 @@ type ActionRequest struct {
 	DeviceID  string     `json:"device_id,omitempty"`
 	DeviceIDs []string   `json:"device_ids,omitempty"` // For batch operations
 	Action    ActionData `json:"action"`
+	Validate  bool       `json:"validate,omitempty"` // Dry run: check params against each device, execute nothing
+}
+
+// ActionValidation is the dry-run result of an action for one device
+type ActionValidation struct {
+	DeviceID string   `json:"device_id"`
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors,omitempty"`
 }
 
 type ActionData struct {
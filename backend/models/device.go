@@ -2,17 +2,53 @@ package models
 
 type Device struct {
 	ID             string `json:"id"`
-	Name           string `json:"name"`
+	Name           string `json:"name"`            // Alias when set, otherwise the model (or serial)
+	Model          string `json:"model,omitempty"` // Model reported by adb (model:), kept when an alias overrides Name
 	ADBDeviceID    string `json:"adb_device_id"`
 	HardwareSerial string `json:"hardware_serial,omitempty"` // Actual device serial for dedup
-	Status         string `json:"status"`                    // online, offline
+	ConnectionType string `json:"connection_type"`           // usb, wifi or emulator (see ConnectionType* - clients must handle all three)
+	IP             string `json:"ip,omitempty"`              // WiFi only: host of the ip:port ADB ID
+	Port           int    `json:"port,omitempty"`            // WiFi only: adb port
+	Status         string `json:"status"`                    // online, offline, unauthorized, no_permissions
 	Resolution     string `json:"resolution"`
 	Battery        int    `json:"battery"`
 	AndroidVersion string `json:"android_version"`
+	Manufacturer   string `json:"manufacturer,omitempty"` // ro.product.manufacturer
+	SDKInt         int    `json:"sdk_int,omitempty"`      // ro.build.version.sdk (API level)
+	CPUABI         string `json:"cpu_abi,omitempty"`      // ro.product.cpu.abi
 	LastSeen       int64  `json:"last_seen"`
 	Frame          string `json:"frame,omitempty"` // Base64 encoded screen frame
 }
 
+// Device status values. Only online devices can stream or run actions;
+// unauthorized means the USB debugging (RSA) prompt hasn't been accepted yet
+const (
+	DeviceStatusOnline        = "online"
+	DeviceStatusOffline       = "offline"
+	DeviceStatusUnauthorized  = "unauthorized"
+	DeviceStatusNoPermissions = "no_permissions"
+)
+
+// Connection types: WiFi devices have an ip:port ADB ID (adb connect / wireless debugging),
+// emulators an emulator-<port> one; anything else is attached over USB.
+// "emulator" is reported separately so an emulator isn't badged as a USB phone; a client
+// that only distinguishes wired from wireless can treat it like "usb".
+const (
+	ConnectionTypeUSB      = "usb"
+	ConnectionTypeWiFi     = "wifi"
+	ConnectionTypeEmulator = "emulator"
+)
+
+// ProcessStats is one CPU/memory sample of an app on a device
+type ProcessStats struct {
+	DeviceID   string  `json:"device_id"`
+	Package    string  `json:"package"`
+	CPUPercent float64 `json:"cpu_pct"` // Summed over the app's processes, relative to one core
+	MemKB      int     `json:"mem_kb"`  // Total PSS
+	Timestamp  int64   `json:"timestamp"`
+	Error      string  `json:"error,omitempty"` // Set when sampling failed (e.g. app not running)
+}
+
 type DeviceGroup struct {
 	ID          string   `json:"id"`
 	Name        string   `json:"name"`
@@ -20,3 +56,79 @@ type DeviceGroup struct {
 	DeviceIDs   []string `json:"device_ids"`
 	CreatedAt   int64    `json:"created_at"`
 }
+
+// GroupRequest is the body for creating/updating a device group
+type GroupRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	DeviceIDs   []string `json:"device_ids"`
+}
+
+// DisplayRequest is the body for overriding screen size/density
+// Empty size and zero density leave that setting unchanged; reset restores both physical defaults
+type DisplayRequest struct {
+	Size    string `json:"size,omitempty"`    // "WxH" or "reset"
+	Density int    `json:"density,omitempty"` // dpi
+	Reset   bool   `json:"reset,omitempty"`
+}
+
+// RotateRequest is the body for forcing screen orientation
+// rotation 0-3 pins that rotation; mode "lock" pins the current one, "unlock" restores auto-rotation
+type RotateRequest struct {
+	Rotation *int   `json:"rotation,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+}
+
+// ClipboardRequest is the body for setting the device clipboard; paste also pastes it into the focused field
+type ClipboardRequest struct {
+	Text  string `json:"text"`
+	Paste bool   `json:"paste,omitempty"`
+}
+
+// ReverseRequest opens an adb reverse tunnel: device localabstract:<socket> -> backend host tcp:<port>
+type ReverseRequest struct {
+	Socket string `json:"socket" binding:"required"`
+	Port   int    `json:"port" binding:"required"`
+}
+
+// KeyRequest presses a key by name ("HOME", "BACK", "VOLUME_UP", ...)
+type KeyRequest struct {
+	Key string `json:"key" binding:"required"`
+}
+
+// CompareRequest compares the current screen to a baseline (JSON or multipart form fields)
+// Multipart uploads may send the baseline PNG as "baseline" instead of baseline_id
+type CompareRequest struct {
+	BaselineID string  `json:"baseline_id" form:"baseline_id"`
+	Threshold  float64 `json:"threshold" form:"threshold"` // Share of matching pixels to pass (0-1, default 0.99)
+	Tolerance  *int    `json:"tolerance" form:"tolerance"` // Per-channel difference ignored (0-255, default 8)
+	Diff       *bool   `json:"diff" form:"diff"`           // Include the diff PNG (default true)
+}
+
+// BaselineRequest captures a device's current screen as a baseline
+type BaselineRequest struct {
+	Name     string `json:"name" binding:"required"`
+	DeviceID string `json:"device_id" binding:"required"`
+}
+
+// AliasRequest is the body for naming a device; an empty alias removes it
+type AliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+// StreamProfileRequest selects a named stream profile; an empty profile clears the selection
+type StreamProfileRequest struct {
+	Profile string `json:"profile"`
+}
+
+// WirelessConnectRequest is the body for wireless connect/disconnect
+type WirelessConnectRequest struct {
+	IP   string `json:"ip" binding:"required"`
+	Port int    `json:"port"` // Defaults to 5555
+}
+
+// PairRequest is the body for Android 11+ wireless debugging pairing
+type PairRequest struct {
+	Address string `json:"address" binding:"required"` // ip:port shown in the pairing dialog
+	Code    string `json:"code" binding:"required"`    // 6-digit pairing code
+}
@@ -2,6 +2,7 @@ CREATE TABLE IF NOT EXISTS devices (
   id TEXT PRIMARY KEY,
   name TEXT NOT NULL,
   adb_device_id TEXT UNIQUE,
+  hardware_serial TEXT,
   status TEXT,
   resolution TEXT,  
   battery INTEGER DEFAULT 0,
@@ -10,6 +11,12 @@ CREATE TABLE IF NOT EXISTS devices (
   created_at INTEGER DEFAULT (strftime('%s', 'now'))
 );
 
+CREATE TABLE IF NOT EXISTS device_aliases (
+  hardware_serial TEXT PRIMARY KEY,
+  alias TEXT NOT NULL,
+  updated_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
 CREATE TABLE IF NOT EXISTS device_groups (
   id TEXT PRIMARY KEY,
   name TEXT NOT NULL,
@@ -52,6 +59,15 @@ CREATE TABLE IF NOT EXISTS macros (
   created_at INTEGER DEFAULT (strftime('%s', 'now'))
 );
 
+CREATE TABLE IF NOT EXISTS screenshot_baselines (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  device_id TEXT,
+  width INTEGER,
+  height INTEGER,
+  created_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
 CREATE INDEX IF NOT EXISTS idx_devices_status ON devices(status);
 CREATE INDEX IF NOT EXISTS idx_action_logs_device ON action_logs(device_id);
 CREATE INDEX IF NOT EXISTS idx_action_logs_status ON action_logs(status);
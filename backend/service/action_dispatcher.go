@@ -1,30 +1,92 @@
 package service
 
 import (
+	"androidcontrol/adb"
 	"androidcontrol/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"runtime/debug"
+	"sync"
+	"time"
 )
 
+// Tracked actions are purged this long after they complete
+const (
+	actionTTL             = 5 * time.Minute
+	actionCleanupInterval = time.Minute
+)
+
+// actionLogBuffer is how many finished actions may wait for the history writer
+// When full, entries are dropped rather than stalling the action queue
+const actionLogBuffer = 256
+
+// actionQueueSize bounds actions waiting across all devices before dispatch fails
+const actionQueueSize = 100
+
 type ActionDispatcher struct {
 	deviceManager *DeviceManager
-	actionQueue   chan *models.Action
+	lanes         *actionLanes // Per-device FIFO queues drained by the workers
+
+	// Action status tracking (keyed by action ID)
+	actions   map[string]*trackedAction
+	actionsMu sync.RWMutex
+
+	// Action history in action_logs (best-effort, written in the background)
+	db     *sql.DB
+	logged chan models.Action
+}
+
+// trackedAction is the dispatcher-owned copy of a queued action
+type trackedAction struct {
+	action      *models.Action
+	completedAt time.Time          // Zero while pending/executing
+	cancel      context.CancelFunc // Set while executing
 }
 
-func NewActionDispatcher(dm *DeviceManager) *ActionDispatcher {
+// ErrActionNotFound is returned for unknown or purged action IDs
+var ErrActionNotFound = errors.New("action not found")
+
+// cancellableActions run an adb process that can be killed mid-flight
+// Other actions are short enough that cancelling them once started isn't offered.
+var cancellableActions = map[string]bool{
+	"swipe":       true,
+	"install_apk": true,
+	"push_file":   true,
+}
+
+// NewActionDispatcher starts the given number of worker goroutines for queued actions
+// Actions on different devices run in parallel; each device's actions run one at a time, in order
+func NewActionDispatcher(dm *DeviceManager, db *sql.DB, workers int) *ActionDispatcher {
 	dispatcher := &ActionDispatcher{
 		deviceManager: dm,
-		actionQueue:   make(chan *models.Action, 100),
+		lanes:         newActionLanes(actionQueueSize),
+		actions:       make(map[string]*trackedAction),
+		db:            db,
+		logged:        make(chan models.Action, actionLogBuffer),
 	}
 
-	// Start action queue processor
-	go dispatcher.ProcessActionQueue()
+	// Start action queue workers
+	for range max(workers, 1) {
+		go dispatcher.ProcessActionQueue()
+	}
+	go dispatcher.cleanupActions()
+	if db != nil {
+		go dispatcher.writeActionLogs()
+	}
 
 	return dispatcher
 }
 
 // DispatchToDevice executes an action on a single device
 func (d *ActionDispatcher) DispatchToDevice(deviceID string, action *models.Action) error {
+	if err := ValidateParams(action); err != nil {
+		return err
+	}
+
 	device := d.deviceManager.GetDevice(deviceID)
 	if device == nil {
 		return fmt.Errorf("device not found: %s", deviceID)
@@ -37,22 +99,35 @@ func (d *ActionDispatcher) DispatchToDevice(deviceID string, action *models.Acti
 	action.DeviceID = deviceID
 	action.Status = "pending"
 
-	// Add to queue
-	select {
-	case d.actionQueue <- action:
-		return nil
-	default:
+	// Queue a copy so the caller's struct is never mutated concurrently
+	queued := *action
+
+	d.actionsMu.Lock()
+	d.actions[queued.ID] = &trackedAction{action: &queued}
+	d.actionsMu.Unlock()
+
+	// Add to the device's queue
+	if !d.lanes.push(&queued) {
+		d.actionsMu.Lock()
+		delete(d.actions, queued.ID)
+		d.actionsMu.Unlock()
 		return fmt.Errorf("action queue full")
 	}
+	return nil
 }
 
 // DispatchBatch executes an action on multiple devices
+// Params are checked once up front: a malformed action fails the whole batch (ErrInvalidParams)
 func (d *ActionDispatcher) DispatchBatch(deviceIDs []string, action *models.Action) ([]*models.Action, error) {
+	if err := ValidateParams(action); err != nil {
+		return nil, err
+	}
 	actions := make([]*models.Action, 0, len(deviceIDs))
 
 	for _, deviceID := range deviceIDs {
-		// Create a copy of the action for each device
+		// Create a copy of the action for each device (IDs must be unique for tracking)
 		deviceAction := *action
+		deviceAction.ID = fmt.Sprintf("%s_%s", action.ID, deviceID)
 		deviceAction.DeviceID = deviceID
 
 		if err := d.DispatchToDevice(deviceID, &deviceAction); err != nil {
@@ -66,24 +141,262 @@ func (d *ActionDispatcher) DispatchBatch(deviceIDs []string, action *models.Acti
 	return actions, nil
 }
 
-// ProcessActionQueue processes actions from the queue
-func (d *ActionDispatcher) ProcessActionQueue() {
-	for action := range d.actionQueue {
-		action.Status = "executing"
+// DispatchToGroup dispatches an action to every online device of a group
+// Unknown/offline members get a "skipped" entry so the result covers every member
+func (d *ActionDispatcher) DispatchToGroup(group *models.DeviceGroup, action *models.Action) []*models.Action {
+	skipped := make(map[string]string)
+	online := make([]string, 0, len(group.DeviceIDs))
+	for _, deviceID := range group.DeviceIDs {
+		device := d.deviceManager.GetDevice(deviceID)
+		switch {
+		case device == nil:
+			skipped[deviceID] = "device not found"
+		case device.Status != "online":
+			skipped[deviceID] = "device offline"
+		default:
+			online = append(online, deviceID)
+		}
+	}
+
+	dispatched, _ := d.DispatchBatch(online, action)
+	byDevice := make(map[string]*models.Action, len(dispatched))
+	for _, a := range dispatched {
+		byDevice[a.DeviceID] = a
+	}
+
+	// Same shape as the batch result, in group order
+	results := make([]*models.Action, 0, len(group.DeviceIDs))
+	for _, deviceID := range group.DeviceIDs {
+		if a, ok := byDevice[deviceID]; ok {
+			results = append(results, a)
+			continue
+		}
 
-		if err := d.executeAction(action); err != nil {
-			action.Status = "failed"
-			action.Result = err.Error()
-			log.Printf("Action failed: %v", err)
+		result := *action
+		result.ID = fmt.Sprintf("%s_%s", action.ID, deviceID)
+		result.DeviceID = deviceID
+		if reason, ok := skipped[deviceID]; ok {
+			result.Status = "skipped"
+			result.Result = reason
 		} else {
-			action.Status = "done"
-			action.Result = "success"
+			result.Status = "failed"
+			result.Result = "dispatch failed"
+		}
+		results = append(results, &result)
+	}
+
+	log.Printf("👥 Group %s: dispatched %s to %d/%d devices", group.Name, action.Type, len(dispatched), len(group.DeviceIDs))
+	return results
+}
+
+// GetAction returns a snapshot of a tracked action, or nil if unknown/purged
+func (d *ActionDispatcher) GetAction(id string) *models.Action {
+	d.actionsMu.RLock()
+	defer d.actionsMu.RUnlock()
+
+	tracked, ok := d.actions[id]
+	if !ok {
+		return nil
+	}
+	snapshot := *tracked.action
+	return &snapshot
+}
+
+// setActionStatus updates a tracked action under the lock
+func (d *ActionDispatcher) setActionStatus(action *models.Action, status, result string) {
+	d.actionsMu.Lock()
+	defer d.actionsMu.Unlock()
+
+	action.Status = status
+	action.Result = result
+	if tracked, ok := d.actions[action.ID]; ok && (status == "done" || status == "failed" || status == "cancelled") {
+		tracked.completedAt = time.Now()
+		tracked.cancel = nil
+	}
+}
+
+// startAction marks a dequeued action as executing, returning false if it was cancelled while queued
+func (d *ActionDispatcher) startAction(action *models.Action, cancel context.CancelFunc) bool {
+	d.actionsMu.Lock()
+	defer d.actionsMu.Unlock()
+
+	if action.Status == "cancelled" {
+		return false
+	}
+	action.Status = "executing"
+	action.Result = ""
+	if tracked, ok := d.actions[action.ID]; ok {
+		tracked.cancel = cancel
+	}
+	return true
+}
+
+// CancelAction cancels a queued action, or kills the adb process of a running install/push/swipe
+// Returns ErrActionNotFound for unknown IDs and an error if the action already completed.
+func (d *ActionDispatcher) CancelAction(id string) error {
+	d.actionsMu.Lock()
+	defer d.actionsMu.Unlock()
+
+	tracked, ok := d.actions[id]
+	if !ok {
+		return ErrActionNotFound
+	}
+
+	action := tracked.action
+	switch action.Status {
+	case "pending":
+		// Still in its device queue; the worker skips it when dequeued
+		action.Status = "cancelled"
+		action.Result = "cancelled before start"
+		tracked.completedAt = time.Now()
+		log.Printf("🛑 [%s] Cancelled queued %s action %s", action.DeviceID, action.Type, id)
+		return nil
+	case "executing":
+		if !cancellableActions[action.Type] || tracked.cancel == nil {
+			return fmt.Errorf("%s actions can't be cancelled once started", action.Type)
+		}
+		tracked.cancel()
+		log.Printf("🛑 [%s] Cancelling running %s action %s", action.DeviceID, action.Type, id)
+		return nil
+	default:
+		return fmt.Errorf("action already completed (%s)", action.Status)
+	}
+}
+
+// cleanupActions purges completed actions older than actionTTL
+func (d *ActionDispatcher) cleanupActions() {
+	ticker := time.NewTicker(actionCleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-actionTTL)
+
+		d.actionsMu.Lock()
+		for id, tracked := range d.actions {
+			if !tracked.completedAt.IsZero() && tracked.completedAt.Before(cutoff) {
+				delete(d.actions, id)
+			}
+		}
+		d.actionsMu.Unlock()
+	}
+}
+
+// ProcessActionQueue is one worker: it runs queued actions, one device lane at a time
+func (d *ActionDispatcher) ProcessActionQueue() {
+	for {
+		action := d.lanes.next()
+		d.runAction(action)
+		d.lanes.done(action.DeviceID)
+	}
+}
+
+// runAction executes one dequeued action and records the outcome
+func (d *ActionDispatcher) runAction(action *models.Action) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !d.startAction(action, cancel) {
+		cancel()
+		if snapshot := d.GetAction(action.ID); snapshot != nil {
+			d.logAction(*snapshot)
+		}
+		return
+	}
+
+	err := d.safeExecute(ctx, action)
+	switch {
+	case ctx.Err() != nil:
+		d.setActionStatus(action, "cancelled", "cancelled while executing")
+	case err != nil:
+		d.setActionStatus(action, "failed", err.Error())
+		log.Printf("Action failed: %v", err)
+	default:
+		d.setActionStatus(action, "done", "success")
+	}
+	cancel()
+
+	if snapshot := d.GetAction(action.ID); snapshot != nil {
+		d.logAction(*snapshot)
+	}
+}
+
+// safeExecute runs an action, turning a panic into a failure so one bad action
+// can't take down a queue worker (params are validated at dispatch; this is the net)
+func (d *ActionDispatcher) safeExecute(ctx context.Context, action *models.Action) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ [%s] Action %s (%s) panicked: %v\n%s", action.DeviceID, action.ID, action.Type, r, debug.Stack())
+			err = fmt.Errorf("action panicked: %v", r)
+		}
+	}()
+	return d.executeAction(ctx, action)
+}
+
+// logAction queues a finished action for the history table without blocking
+func (d *ActionDispatcher) logAction(action models.Action) {
+	if d.db == nil {
+		return
+	}
+
+	select {
+	case d.logged <- action:
+	default:
+		log.Printf("⚠️ Action history backlog full, not logging %s", action.ID)
+	}
+}
+
+// writeActionLogs inserts finished actions into action_logs
+func (d *ActionDispatcher) writeActionLogs() {
+	for action := range d.logged {
+		params, err := json.Marshal(action.Params)
+		if err != nil {
+			params = []byte("{}")
+		}
+
+		_, err = d.db.Exec(`INSERT OR REPLACE INTO action_logs
+			(id, device_id, action_type, params, status, result, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			action.ID, action.DeviceID, action.Type, string(params), action.Status, action.Result, time.Now().Unix())
+		if err != nil {
+			log.Printf("⚠️ Failed to log action %s: %v", action.ID, err)
 		}
 	}
 }
 
+// GetActionHistory returns the most recent logged actions, newest first
+// deviceID filters by device when non-empty
+func (d *ActionDispatcher) GetActionHistory(deviceID string, limit int) ([]models.Action, error) {
+	history := []models.Action{}
+	if d.db == nil {
+		return history, nil
+	}
+
+	rows, err := d.db.Query(`SELECT id, COALESCE(device_id, ''), COALESCE(action_type, ''), COALESCE(params, ''),
+			COALESCE(status, ''), COALESCE(result, ''), COALESCE(created_at, 0)
+		FROM action_logs
+		WHERE ? = '' OR device_id = ?
+		ORDER BY created_at DESC, rowid DESC
+		LIMIT ?`, deviceID, deviceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.Action
+		var params string
+		if err := rows.Scan(&a.ID, &a.DeviceID, &a.Type, &params, &a.Status, &a.Result, &a.Timestamp); err != nil {
+			return nil, err
+		}
+		if params != "" {
+			json.Unmarshal([]byte(params), &a.Params)
+		}
+		history = append(history, a)
+	}
+	return history, rows.Err()
+}
+
 // executeAction executes a single action using ADB
-func (d *ActionDispatcher) executeAction(action *models.Action) error {
+// Cancelling ctx kills the adb process of cancellableActions
+func (d *ActionDispatcher) executeAction(ctx context.Context, action *models.Action) error {
 	device := d.deviceManager.GetDevice(action.DeviceID)
 	if device == nil {
 		return fmt.Errorf("device not found")
@@ -115,7 +428,7 @@ func (d *ActionDispatcher) executeAction(action *models.Action) error {
 		if d, ok := action.Params["duration"].(float64); ok {
 			duration = int(d)
 		}
-		return adbClient.SendSwipe(device.ADBDeviceID, x1, y1, x2, y2, duration)
+		return adbClient.SendSwipeContext(ctx, device.ADBDeviceID, x1, y1, x2, y2, duration)
 
 	case "input":
 		text := action.Params["text"].(string)
@@ -131,12 +444,72 @@ func (d *ActionDispatcher) executeAction(action *models.Action) error {
 
 	case "install_apk":
 		apkPath := action.Params["apk_path"].(string)
-		return adbClient.InstallAPK(device.ADBDeviceID, apkPath)
+		reinstall, _ := action.Params["reinstall"].(bool)
+		grantAll, _ := action.Params["grant_all"].(bool)
+		return adbClient.InstallAPKContext(ctx, device.ADBDeviceID, apkPath, adb.InstallOpts{Reinstall: reinstall, GrantAll: grantAll})
 
 	case "push_file":
 		localPath := action.Params["local"].(string)
 		remotePath := action.Params["remote"].(string)
-		return adbClient.PushFile(device.ADBDeviceID, localPath, remotePath)
+		return adbClient.PushFileContext(ctx, device.ADBDeviceID, localPath, remotePath)
+
+	case "uninstall":
+		packageName, _ := action.Params["package"].(string)
+		return adbClient.UninstallApp(device.ADBDeviceID, packageName)
+
+	case "force_stop":
+		packageName, _ := action.Params["package"].(string)
+		return adbClient.ForceStop(device.ADBDeviceID, packageName)
+
+	case "clear_data":
+		packageName, _ := action.Params["package"].(string)
+		return adbClient.ClearData(device.ADBDeviceID, packageName)
+
+	case "reboot":
+		mode, _ := action.Params["mode"].(string) // "", "recovery", "bootloader"
+		if err := adbClient.Reboot(device.ADBDeviceID, mode); err != nil {
+			return err
+		}
+		// Device drops off ADB - stop its stream until the next scan sees it again
+		d.deviceManager.MarkOffline(device.ID)
+		return nil
+
+	case "screen_power":
+		on, ok := action.Params["on"].(bool)
+		if !ok {
+			return fmt.Errorf("invalid screen_power param: on must be a boolean")
+		}
+		return adbClient.ScreenPower(device.ADBDeviceID, on)
+
+	case "set_size":
+		size, _ := action.Params["size"].(string) // "WxH" or "reset"
+		if err := adbClient.SetScreenSize(device.ADBDeviceID, size); err != nil {
+			return err
+		}
+		d.deviceManager.RefreshDisplayInfo(device.ID)
+		return nil
+
+	case "set_density":
+		dpi, ok := action.Params["dpi"].(float64) // 0 = reset
+		if !ok {
+			return fmt.Errorf("invalid set_density param: dpi must be a number")
+		}
+		return adbClient.SetDensity(device.ADBDeviceID, int(dpi))
+
+	case "rotate":
+		mode, _ := action.Params["mode"].(string) // "lock", "unlock", or "" with rotation 0-3
+		rotation, ok := action.Params["rotation"].(float64)
+		if mode == "" && !ok {
+			return fmt.Errorf("invalid rotate params: rotation (0-3) or mode (lock/unlock) is required")
+		}
+		return adbClient.Rotate(device.ADBDeviceID, mode, int(rotation))
+
+	case "reset_display":
+		if err := adbClient.ResetDisplay(device.ADBDeviceID); err != nil {
+			return err
+		}
+		d.deviceManager.RefreshDisplayInfo(device.ID)
+		return nil
 
 	default:
 		return fmt.Errorf("unknown action type: %s", action.Type)
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"androidcontrol/models"
+	"sync"
+)
+
+// actionLanes queues actions per device for a pool of workers
+// Each device is a FIFO lane that at most one worker drains at a time, so actions on one
+// device never reorder or overlap while different devices run in parallel. A worker takes
+// one action, then puts the lane back at the end of the ready list so busy devices share.
+type actionLanes struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  map[string][]*models.Action // Pending actions per device, in dispatch order
+	claimed map[string]bool             // Device is in ready or has an action executing
+	ready   []string                    // Devices with pending actions and no worker
+	pending int                         // Actions across all lanes (bounded by limit)
+	limit   int
+}
+
+func newActionLanes(limit int) *actionLanes {
+	l := &actionLanes{
+		queues:  make(map[string][]*models.Action),
+		claimed: make(map[string]bool),
+		limit:   limit,
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// push appends an action to its device's lane; false when the total backlog is full
+func (l *actionLanes) push(action *models.Action) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.pending >= l.limit {
+		return false
+	}
+	l.pending++
+	l.queues[action.DeviceID] = append(l.queues[action.DeviceID], action)
+	if !l.claimed[action.DeviceID] {
+		l.claimed[action.DeviceID] = true
+		l.ready = append(l.ready, action.DeviceID)
+		l.cond.Signal()
+	}
+	return true
+}
+
+// next blocks until a device lane has work and returns its oldest action
+// The lane stays claimed until done, so no other worker picks up that device meanwhile.
+func (l *actionLanes) next() *models.Action {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(l.ready) == 0 {
+		l.cond.Wait()
+	}
+	deviceID := l.ready[0]
+	l.ready = l.ready[1:]
+
+	queue := l.queues[deviceID]
+	action := queue[0]
+	queue[0] = nil
+	if len(queue) == 1 {
+		delete(l.queues, deviceID)
+	} else {
+		l.queues[deviceID] = queue[1:]
+	}
+	l.pending--
+	return action
+}
+
+// done releases a device after its action finished, requeueing it if more are pending
+func (l *actionLanes) done(deviceID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.queues[deviceID]) > 0 {
+		l.ready = append(l.ready, deviceID)
+		l.cond.Signal()
+		return
+	}
+	delete(l.claimed, deviceID)
+}
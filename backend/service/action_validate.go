@@ -0,0 +1,248 @@
+package service
+
+import (
+	"androidcontrol/adb"
+	"androidcontrol/models"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxValidateWorkers bounds concurrent adb queries when validating a batch
+const maxValidateWorkers = 8
+
+// requiredParams lists the params each action type needs, by JSON kind
+var requiredParams = map[string]map[string]string{
+	"tap":           {"x": "number", "y": "number"},
+	"swipe":         {"x1": "number", "y1": "number", "x2": "number", "y2": "number"},
+	"input":         {"text": "string"},
+	"key":           {"keycode": "number"},
+	"open_app":      {"package": "string"},
+	"install_apk":   {"apk_path": "string"},
+	"push_file":     {"local": "string", "remote": "string"},
+	"uninstall":     {"package": "string"},
+	"force_stop":    {"package": "string"},
+	"clear_data":    {"package": "string"},
+	"reboot":        {},
+	"screen_power":  {"on": "bool"},
+	"set_size":      {"size": "string"},
+	"set_density":   {"dpi": "number"},
+	"reset_display": {},
+	"rotate":        {}, // rotation (number) or mode (string), checked below
+}
+
+// optionalParams lists params an action type accepts but doesn't need, by JSON kind
+var optionalParams = map[string]map[string]string{
+	"swipe":       {"duration": "number"},
+	"install_apk": {"reinstall": "bool", "grant_all": "bool"},
+	"reboot":      {"mode": "string"},
+}
+
+// ErrInvalidParams marks an action rejected before queueing (HTTP 400)
+var ErrInvalidParams = errors.New("invalid action params")
+
+// ValidateParams checks an action's type and param kinds, so executeAction's
+// type assertions can't fail; device-specific checks are left to ValidateAction
+func ValidateParams(action *models.Action) error {
+	if problems := paramErrors(action); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidParams, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// paramErrors lists what's wrong with an action's type and params
+func paramErrors(action *models.Action) []string {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	required, known := requiredParams[action.Type]
+	if !known {
+		fail("unknown action type: %s", action.Type)
+		return problems
+	}
+	for _, name := range slices.Sorted(maps.Keys(required)) {
+		if kind := required[name]; !hasParamKind(action.Params, name, kind) {
+			fail("param %s must be a %s", name, kind)
+		}
+	}
+	optional := optionalParams[action.Type]
+	for _, name := range slices.Sorted(maps.Keys(optional)) {
+		if _, set := action.Params[name]; set && !hasParamKind(action.Params, name, optional[name]) {
+			fail("param %s must be a %s", name, optional[name])
+		}
+	}
+	if len(problems) == 0 {
+		checkRanges(action, fail)
+	}
+	return problems
+}
+
+// ValidateAction dry-runs an action against one device without executing it
+// Checks the params, that tap/swipe coordinates fall on the device's screen and
+// that open_app packages are installed.
+func (d *ActionDispatcher) ValidateAction(deviceID string, action *models.Action) models.ActionValidation {
+	result := models.ActionValidation{DeviceID: deviceID}
+	fail := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	device := d.deviceManager.GetDevice(deviceID)
+	if device == nil {
+		fail("device not found")
+		return result
+	}
+	if device.Status != "online" {
+		fail("device offline")
+	}
+
+	if problems := paramErrors(action); len(problems) > 0 {
+		result.Errors = append(result.Errors, problems...)
+		return result
+	}
+
+	switch action.Type {
+	case "tap":
+		checkPoint(device, action.Params, "x", "y", fail)
+	case "swipe":
+		checkPoint(device, action.Params, "x1", "y1", fail)
+		checkPoint(device, action.Params, "x2", "y2", fail)
+	case "open_app":
+		pkg := action.Params["package"].(string)
+		packages, err := d.deviceManager.GetADBClient().ListPackages(device.ADBDeviceID, false)
+		if err != nil {
+			fail("%v", err)
+		} else if !slices.Contains(packages, pkg) {
+			fail("package not installed: %s", pkg)
+		}
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// ValidateBatch dry-runs an action against several devices, in input order
+func (d *ActionDispatcher) ValidateBatch(deviceIDs []string, action *models.Action) []models.ActionValidation {
+	results := make([]models.ActionValidation, len(deviceIDs))
+	sem := make(chan struct{}, maxValidateWorkers)
+	var wg sync.WaitGroup
+	for i, deviceID := range deviceIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = d.ValidateAction(deviceID, action)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// checkPoint reports coordinates outside the device's screen
+// wm size reports the natural (portrait) size, so the rotated bounds are accepted too
+func checkPoint(device *models.Device, params map[string]interface{}, xKey, yKey string, fail func(string, ...interface{})) {
+	width, height, ok := parseResolution(device.Resolution)
+	if !ok {
+		fail("device resolution unknown")
+		return
+	}
+
+	x := params[xKey].(float64)
+	y := params[yKey].(float64)
+	inPortrait := x >= 0 && y >= 0 && x < float64(width) && y < float64(height)
+	inLandscape := x >= 0 && y >= 0 && x < float64(height) && y < float64(width)
+	if !inPortrait && !inLandscape {
+		fail("%s,%s (%g,%g) outside screen %s", xKey, yKey, x, y, device.Resolution)
+	}
+}
+
+// checkRanges reports param values adb would reject or misread, once their kinds are known good
+// Screen bounds depend on the device and are left to ValidateAction.
+func checkRanges(action *models.Action, fail func(string, ...interface{})) {
+	params := action.Params
+	for _, name := range slices.Sorted(maps.Keys(requiredParams[action.Type])) {
+		if s, ok := params[name].(string); ok && s == "" {
+			fail("param %s must not be empty", name)
+		}
+	}
+
+	switch action.Type {
+	case "tap", "swipe":
+		for _, name := range []string{"x", "y", "x1", "y1", "x2", "y2", "duration"} {
+			if v, ok := params[name].(float64); ok && v < 0 {
+				fail("%s must be >= 0", name)
+			}
+		}
+	case "key":
+		if keycode := params["keycode"].(float64); keycode != float64(int(keycode)) || keycode <= 0 {
+			fail("keycode must be a positive whole number")
+		}
+	case "set_size":
+		if size := params["size"].(string); size != "reset" {
+			if _, _, ok := parseResolution(size); !ok {
+				fail("size must be WIDTHxHEIGHT or reset")
+			}
+		}
+	case "set_density":
+		if dpi := params["dpi"].(float64); dpi != float64(int(dpi)) || dpi < 0 {
+			fail("dpi must be a whole number >= 0 (0 resets)")
+		}
+	case "reboot":
+		if mode, ok := params["mode"].(string); ok && mode != "" && mode != "recovery" && mode != "bootloader" {
+			fail("mode must be recovery or bootloader")
+		}
+	case "rotate":
+		checkRotation(params, fail)
+	}
+}
+
+// checkRotation reports rotate params that are neither a rotation 0-3 nor a lock/unlock mode
+func checkRotation(params map[string]interface{}, fail func(string, ...interface{})) {
+	if mode, ok := params["mode"].(string); ok {
+		if mode != adb.RotationLock && mode != adb.RotationUnlock {
+			fail("mode must be %s or %s", adb.RotationLock, adb.RotationUnlock)
+		}
+		return
+	}
+	rotation, ok := params["rotation"].(float64)
+	if !ok {
+		fail("param rotation must be a number (or set mode)")
+		return
+	}
+	if rotation != float64(int(rotation)) || rotation < 0 || rotation > 3 {
+		fail("rotation must be 0, 1, 2 or 3")
+	}
+}
+
+// hasParamKind reports whether params[name] holds a JSON value of the given kind
+func hasParamKind(params map[string]interface{}, name, kind string) bool {
+	switch params[name].(type) {
+	case float64:
+		return kind == "number"
+	case string:
+		return kind == "string"
+	case bool:
+		return kind == "bool"
+	}
+	return false
+}
+
+// parseResolution parses a "WIDTHxHEIGHT" resolution string
+func parseResolution(resolution string) (width, height int, ok bool) {
+	w, h, found := strings.Cut(resolution, "x")
+	if !found {
+		return 0, 0, false
+	}
+	width, errW := strconv.Atoi(strings.TrimSpace(w))
+	height, errH := strconv.Atoi(strings.TrimSpace(h))
+	if errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"androidcontrol/models"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type paramCase struct {
+	name   string
+	params map[string]interface{}
+}
+
+// assertInvalid checks that every case fails ValidateParams with ErrInvalidParams
+func assertInvalid(t *testing.T, actionType string, cases []paramCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(actionType+"/"+tc.name, func(t *testing.T) {
+			err := ValidateParams(&models.Action{Type: actionType, Params: tc.params})
+			if !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("ValidateParams(%v) = %v, want ErrInvalidParams", tc.params, err)
+			}
+		})
+	}
+}
+
+func TestValidateParamsAccepts(t *testing.T) {
+	valid := []models.Action{
+		{Type: "tap", Params: map[string]interface{}{"x": 100.0, "y": 200.0}},
+		{Type: "swipe", Params: map[string]interface{}{"x1": 0.0, "y1": 0.0, "x2": 10.0, "y2": 10.0, "duration": 300.0}},
+		{Type: "input", Params: map[string]interface{}{"text": "hello"}},
+		{Type: "key", Params: map[string]interface{}{"keycode": 3.0}},
+		{Type: "open_app", Params: map[string]interface{}{"package": "com.android.settings"}},
+		{Type: "install_apk", Params: map[string]interface{}{"apk_path": "/tmp/app.apk", "reinstall": true}},
+		{Type: "push_file", Params: map[string]interface{}{"local": "/tmp/a", "remote": "/sdcard/a"}},
+		{Type: "uninstall", Params: map[string]interface{}{"package": "com.example"}},
+		{Type: "force_stop", Params: map[string]interface{}{"package": "com.example"}},
+		{Type: "clear_data", Params: map[string]interface{}{"package": "com.example"}},
+		{Type: "reboot", Params: map[string]interface{}{}},
+		{Type: "reboot", Params: map[string]interface{}{"mode": "recovery"}},
+		{Type: "screen_power", Params: map[string]interface{}{"on": true}},
+		{Type: "set_size", Params: map[string]interface{}{"size": "1080x1920"}},
+		{Type: "set_size", Params: map[string]interface{}{"size": "reset"}},
+		{Type: "set_density", Params: map[string]interface{}{"dpi": 0.0}},
+		{Type: "reset_display", Params: nil},
+		{Type: "rotate", Params: map[string]interface{}{"rotation": 1.0}},
+		{Type: "rotate", Params: map[string]interface{}{"mode": "lock"}},
+	}
+	for _, action := range valid {
+		if err := ValidateParams(&action); err != nil {
+			t.Errorf("ValidateParams(%s %v) = %v", action.Type, action.Params, err)
+		}
+	}
+}
+
+func TestValidateParamsUnknownType(t *testing.T) {
+	assertInvalid(t, "teleport", []paramCase{{"no params", nil}})
+	assertInvalid(t, "", []paramCase{{"empty type", map[string]interface{}{"x": 1.0}}})
+}
+
+func TestValidateParamsTap(t *testing.T) {
+	assertInvalid(t, "tap", []paramCase{
+		{"missing y", map[string]interface{}{"x": 100.0}},
+		{"nil params", nil},
+		{"string x", map[string]interface{}{"x": "100", "y": 200.0}},
+		{"negative y", map[string]interface{}{"x": 100.0, "y": -1.0}},
+	})
+}
+
+func TestValidateParamsSwipe(t *testing.T) {
+	assertInvalid(t, "swipe", []paramCase{
+		{"missing x2", map[string]interface{}{"x1": 0.0, "y1": 0.0, "y2": 10.0}},
+		{"string y1", map[string]interface{}{"x1": 0.0, "y1": "0", "x2": 10.0, "y2": 10.0}},
+		{"string duration", map[string]interface{}{"x1": 0.0, "y1": 0.0, "x2": 10.0, "y2": 10.0, "duration": "300"}},
+		{"negative x1", map[string]interface{}{"x1": -5.0, "y1": 0.0, "x2": 10.0, "y2": 10.0}},
+		{"negative duration", map[string]interface{}{"x1": 0.0, "y1": 0.0, "x2": 10.0, "y2": 10.0, "duration": -1.0}},
+	})
+}
+
+func TestValidateParamsInput(t *testing.T) {
+	assertInvalid(t, "input", []paramCase{
+		{"missing text", map[string]interface{}{}},
+		{"number text", map[string]interface{}{"text": 42.0}},
+		{"empty text", map[string]interface{}{"text": ""}},
+	})
+}
+
+func TestValidateParamsKey(t *testing.T) {
+	assertInvalid(t, "key", []paramCase{
+		{"missing keycode", map[string]interface{}{}},
+		{"string keycode", map[string]interface{}{"keycode": "HOME"}},
+		{"negative keycode", map[string]interface{}{"keycode": -1.0}},
+		{"zero keycode", map[string]interface{}{"keycode": 0.0}},
+		{"fractional keycode", map[string]interface{}{"keycode": 3.5}},
+	})
+}
+
+func TestValidateParamsPackageActions(t *testing.T) {
+	for _, actionType := range []string{"open_app", "uninstall", "force_stop", "clear_data"} {
+		assertInvalid(t, actionType, []paramCase{
+			{"missing package", map[string]interface{}{}},
+			{"number package", map[string]interface{}{"package": 1.0}},
+			{"empty package", map[string]interface{}{"package": ""}},
+		})
+	}
+}
+
+func TestValidateParamsInstallAPK(t *testing.T) {
+	assertInvalid(t, "install_apk", []paramCase{
+		{"missing apk_path", map[string]interface{}{"reinstall": true}},
+		{"string reinstall", map[string]interface{}{"apk_path": "/tmp/app.apk", "reinstall": "yes"}},
+		{"empty apk_path", map[string]interface{}{"apk_path": ""}},
+	})
+}
+
+func TestValidateParamsPushFile(t *testing.T) {
+	assertInvalid(t, "push_file", []paramCase{
+		{"missing remote", map[string]interface{}{"local": "/tmp/a"}},
+		{"number local", map[string]interface{}{"local": 1.0, "remote": "/sdcard/a"}},
+		{"empty remote", map[string]interface{}{"local": "/tmp/a", "remote": ""}},
+	})
+}
+
+func TestValidateParamsReboot(t *testing.T) {
+	assertInvalid(t, "reboot", []paramCase{
+		{"number mode", map[string]interface{}{"mode": 1.0}},
+		{"unknown mode", map[string]interface{}{"mode": "fastboot"}},
+	})
+}
+
+func TestValidateParamsScreenPower(t *testing.T) {
+	assertInvalid(t, "screen_power", []paramCase{
+		{"missing on", map[string]interface{}{}},
+		{"string on", map[string]interface{}{"on": "true"}},
+		{"number on", map[string]interface{}{"on": 1.0}},
+	})
+}
+
+func TestValidateParamsSetSize(t *testing.T) {
+	assertInvalid(t, "set_size", []paramCase{
+		{"missing size", map[string]interface{}{}},
+		{"number size", map[string]interface{}{"size": 1080.0}},
+		{"malformed size", map[string]interface{}{"size": "1080by1920"}},
+		{"zero size", map[string]interface{}{"size": "0x1920"}},
+	})
+}
+
+func TestValidateParamsSetDensity(t *testing.T) {
+	assertInvalid(t, "set_density", []paramCase{
+		{"missing dpi", map[string]interface{}{}},
+		{"string dpi", map[string]interface{}{"dpi": "420"}},
+		{"negative dpi", map[string]interface{}{"dpi": -1.0}},
+		{"fractional dpi", map[string]interface{}{"dpi": 420.5}},
+	})
+}
+
+func TestValidateParamsRotate(t *testing.T) {
+	assertInvalid(t, "rotate", []paramCase{
+		{"missing rotation and mode", map[string]interface{}{}},
+		{"string rotation", map[string]interface{}{"rotation": "1"}},
+		{"rotation out of range", map[string]interface{}{"rotation": 4.0}},
+		{"fractional rotation", map[string]interface{}{"rotation": 1.5}},
+		{"unknown mode", map[string]interface{}{"mode": "spin"}},
+	})
+}
+
+func TestValidateParamsReportsEveryProblem(t *testing.T) {
+	err := ValidateParams(&models.Action{Type: "swipe", Params: map[string]interface{}{"x1": "a", "y2": true}})
+	for _, want := range []string{"x1", "x2", "y1", "y2"} {
+		if err == nil || !strings.Contains(err.Error(), "param "+want) {
+			t.Errorf("error %v doesn't mention %s", err, want)
+		}
+	}
+}
+
+// waitForStatus polls a tracked action until it completes
+func waitForStatus(t *testing.T, d *ActionDispatcher, id string) *models.Action {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if action := d.GetAction(id); action != nil && action.Status != "pending" && action.Status != "executing" {
+			return action
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("action %s never completed", id)
+	return nil
+}
+
+func TestProcessActionQueueRecoversPanic(t *testing.T) {
+	dm := NewDeviceManager(nil)
+	dm.devices["dev1"] = &models.Device{ID: "dev1", ADBDeviceID: "dev1-serial", Status: models.DeviceStatusOnline}
+	dm.GetADBClient().ADBPath = "/nonexistent/adb" // The follow-up action fails fast instead of running adb
+	d := NewActionDispatcher(dm, nil, 1)
+
+	// Queued directly, bypassing ValidateParams: the string coordinate panics in executeAction
+	bad := &models.Action{ID: "bad", DeviceID: "dev1", Type: "swipe", Status: "pending",
+		Params: map[string]interface{}{"x1": "oops", "y1": 0.0, "x2": 1.0, "y2": 1.0}}
+	next := &models.Action{ID: "next", DeviceID: "dev1", Type: "key", Status: "pending",
+		Params: map[string]interface{}{"keycode": 3.0}}
+	for _, action := range []*models.Action{bad, next} {
+		d.actionsMu.Lock()
+		d.actions[action.ID] = &trackedAction{action: action}
+		d.actionsMu.Unlock()
+		if !d.lanes.push(action) {
+			t.Fatalf("queue rejected %s", action.ID)
+		}
+	}
+
+	got := waitForStatus(t, d, "bad")
+	if got.Status != "failed" || !strings.Contains(got.Result, "panicked") {
+		t.Errorf("panicking action = %s (%q), want failed with the panic", got.Status, got.Result)
+	}
+
+	// The lone worker survived and the device lane was released
+	got = waitForStatus(t, d, "next")
+	if got.Status != "failed" || strings.Contains(got.Result, "panicked") {
+		t.Errorf("follow-up action = %s (%q), want an adb failure", got.Status, got.Result)
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Adaptive bitrate tuning. Each change restarts the scrcpy session, so steps are coarse
+// and rate-limited by a cooldown.
+const (
+	dropThreshold           = 20 // Dropped frames per report window that count as congestion
+	bitrateStepDown         = 0.6
+	bitrateStepUp           = 1.25
+	minAdaptiveBitRate      = 200000
+	bitrateChangeCooldown   = 10 * time.Second
+	calmWindowsBeforeRampUp = 5 // Consecutive drop-free windows before ramping back up
+)
+
+// adaptiveBitrate is the per-stream feedback state (guarded by deviceStream.mu)
+type adaptiveBitrate struct {
+	bitRate     int // Current override (0 = use config/default)
+	calmWindows int
+	lastChange  time.Time
+}
+
+// targetBitRate is what the stream runs at without adaptation (must hold stream.mu):
+// explicit config, else its profile's bitrate, else the device default
+func (s *StreamingService) targetBitRate(stream *deviceStream) int {
+	if stream.config.BitRate > 0 {
+		return stream.config.BitRate
+	}
+	if profile := s.profiles.resolve(stream.config.Profile); profile != nil && profile.BitRate > 0 {
+		return profile.BitRate
+	}
+	return defaultBitRate(stream.deviceADBID)
+}
+
+// ReportFrameDrops feeds one window of WebSocket delivery stats for a device
+// Sustained drops lower the bitrate; drop-free windows ramp it back to the target
+func (s *StreamingService) ReportFrameDrops(deviceID string, sent, dropped int) {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	stream.mu.Lock()
+	if stream.state != StateRunning {
+		stream.mu.Unlock()
+		return
+	}
+
+	target := s.targetBitRate(stream)
+	current := stream.adaptive.bitRate
+	if current == 0 {
+		current = target
+	}
+	coolingDown := time.Since(stream.adaptive.lastChange) < bitrateChangeCooldown
+
+	next := 0
+	switch {
+	case dropped >= dropThreshold:
+		stream.adaptive.calmWindows = 0
+		if !coolingDown && current > minAdaptiveBitRate {
+			next = max(minAdaptiveBitRate, int(float64(current)*bitrateStepDown))
+			log.Printf("📉 [%s] %d/%d frames dropped, lowering bitrate %d -> %d", deviceID, dropped, sent+dropped, current, next)
+		}
+	case dropped == 0:
+		stream.adaptive.calmWindows++
+		if stream.adaptive.calmWindows >= calmWindowsBeforeRampUp && current < target && !coolingDown {
+			stream.adaptive.calmWindows = 0
+			next = min(target, int(float64(current)*bitrateStepUp))
+			log.Printf("📈 [%s] No drops, raising bitrate %d -> %d", deviceID, current, next)
+		}
+	default:
+		stream.adaptive.calmWindows = 0
+	}
+	stream.mu.Unlock()
+
+	if next > 0 {
+		if err := s.RequestBitrate(deviceID, next); err != nil {
+			log.Printf("⚠️ [%s] Bitrate change failed: %v", deviceID, err)
+		}
+	}
+}
+
+// RequestBitrate restarts the scrcpy session at a different video_bit_rate
+// The override lasts until the next SetStreamConfig; the target bitrate clears it
+func (s *StreamingService) RequestBitrate(deviceID string, bitrate int) error {
+	if bitrate <= 0 {
+		return fmt.Errorf("invalid bitrate: %d", bitrate)
+	}
+
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("stream not found for device: %s", deviceID)
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.degraded {
+		return nil // screenrecord runs at a fixed bitrate; restarting would retry scrcpy
+	}
+	if bitrate == s.targetBitRate(stream) {
+		bitrate = 0
+	}
+	if stream.adaptive.bitRate == bitrate {
+		return nil
+	}
+
+	stream.adaptive.bitRate = bitrate
+	stream.adaptive.lastChange = time.Now()
+
+	if stream.state == StateRunning {
+		s.restartSession(stream)
+	}
+	return nil
+}
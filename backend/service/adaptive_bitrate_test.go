@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// runningStream registers a RUNNING stream with no scrcpy client (restarts are no-ops)
+func runningStream(s *StreamingService, deviceID, adbID, profile string) *deviceStream {
+	stream := newDeviceStream(deviceID, adbID)
+	stream.state = StateRunning
+	stream.config.Profile = profile
+	s.streams[deviceID] = stream
+	return stream
+}
+
+func TestTargetBitRateOrder(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	tests := []struct {
+		name    string
+		adbID   string
+		profile string
+		bitRate int
+		want    int
+	}{
+		{"explicit config wins", "serial", "hq", 2_000_000, 2_000_000},
+		{"low profile", "serial", "low", 0, 500_000},
+		{"hq profile", "192.168.1.5:5555", "hq", 0, 6_000_000},
+		{"usb default", "serial", "", 0, 1_500_000},
+		{"wifi default", "192.168.1.5:5555", "", 0, 800_000},
+		{"unknown profile", "serial", "missing", 0, 1_500_000},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := newDeviceStream("dev", tc.adbID)
+			stream.config.Profile = tc.profile
+			stream.config.BitRate = tc.bitRate
+			if got := s.targetBitRate(stream); got != tc.want {
+				t.Errorf("targetBitRate = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAdaptiveStepDownStaysBelowLowProfile(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	stream := runningStream(s, "dev", "serial", "low")
+
+	s.ReportFrameDrops("dev", 10, dropThreshold)
+	if got, want := stream.adaptive.bitRate, int(500_000*bitrateStepDown); got != want {
+		t.Errorf("bitrate after congestion = %d, want %d (0.6 x the low profile)", got, want)
+	}
+}
+
+func TestAdaptiveRampUpReachesHQProfile(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	stream := runningStream(s, "dev", "serial", "hq")
+	stream.adaptive.bitRate = 1_500_000
+
+	var steps []int
+	for i := 0; i < 50 && stream.adaptive.bitRate != 0; i++ {
+		stream.adaptive.lastChange = time.Time{} // Skip the cooldown
+		for w := 0; w < calmWindowsBeforeRampUp; w++ {
+			s.ReportFrameDrops("dev", 30, 0)
+		}
+		steps = append(steps, stream.adaptive.bitRate)
+	}
+
+	if stream.adaptive.bitRate != 0 {
+		t.Fatalf("ramp-up stalled at %v, want to reach the hq target (override cleared)", steps)
+	}
+	if len(steps) < 2 || steps[0] <= 1_500_000 {
+		t.Errorf("ramp-up steps = %v, want to pass 1.5M on the way to 6M", steps)
+	}
+}
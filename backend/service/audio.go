@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+// Opus produced by scrcpy with audio_codec=opus
+const (
+	AudioSampleRate = 48000
+	AudioChannels   = 2
+)
+
+// pumpAudio forwards Opus packets from the audio socket, one frame each, until it closes
+// The first packet is the OpusHead config, flagged FrameFlagConfig for the decoder setup
+// The socket is closed by ScrcpyClient.Stop(); ctx only silences the shutdown error
+func (s *StreamingService) pumpAudio(ctx context.Context, deviceID string, conn net.Conn, ptsBase time.Time) {
+	log.Printf("🔊 [%s] Audio stream started (Opus %dHz, %d ch)", deviceID, AudioSampleRate, AudioChannels)
+	for {
+		packet, err := readFramePacket(conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && ctx.Err() == nil {
+				log.Printf("⚠️ [%s] Audio stream ended: %v", deviceID, err)
+			}
+			return
+		}
+		header := FrameHeader{Type: FrameTypeAudio, PTS: ptsSince(ptsBase), DeviceID: deviceID}
+		if packet.Config {
+			header.Flags = FrameFlagConfig
+		}
+		if pkt := EncodeFrame(header, packet.Data); pkt != nil {
+			s.wsHub.BroadcastToDevice(deviceID, pkt)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBaselineNameLength caps baseline names
+const maxBaselineNameLength = 128
+
+// Baseline is a reference screenshot for UI checks
+// The PNG lives on disk (<dir>/<id>.png); metadata is persisted in SQLite when available
+type Baseline struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	DeviceID  string `json:"device_id,omitempty"` // Device it was captured from, if any
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// BaselineStore keeps reference screenshots for ComparePNG
+type BaselineStore struct {
+	baselines map[string]*Baseline
+	mu        sync.RWMutex
+	db        *sql.DB
+	dir       string
+}
+
+func NewBaselineStore(db *sql.DB, dir string) *BaselineStore {
+	s := &BaselineStore{
+		baselines: make(map[string]*Baseline),
+		db:        db,
+		dir:       dir,
+	}
+
+	if db != nil {
+		if err := s.loadFromDB(); err != nil {
+			log.Printf("⚠️ Failed to load screenshot baselines from database: %v", err)
+		}
+	}
+
+	return s
+}
+
+// loadFromDB loads baseline metadata, skipping rows whose PNG is gone
+func (s *BaselineStore) loadFromDB() error {
+	rows, err := s.db.Query(`SELECT id, name, COALESCE(device_id, ''), width, height, COALESCE(created_at, 0) FROM screenshot_baselines`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for rows.Next() {
+		var b Baseline
+		if err := rows.Scan(&b.ID, &b.Name, &b.DeviceID, &b.Width, &b.Height, &b.CreatedAt); err != nil {
+			return err
+		}
+		if _, err := os.Stat(s.path(b.ID)); err != nil {
+			log.Printf("⚠️ Baseline %s (%s) has no image file, skipping", b.ID, b.Name)
+			continue
+		}
+		s.baselines[b.ID] = &b
+	}
+
+	log.Printf("💾 Loaded %d screenshot baselines from database", len(s.baselines))
+	return rows.Err()
+}
+
+// path is where a baseline's PNG is stored
+func (s *BaselineStore) path(id string) string {
+	return filepath.Join(s.dir, id+".png")
+}
+
+// Create stores a PNG as a new baseline
+func (s *BaselineStore) Create(name, deviceID string, data []byte) (*Baseline, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if len(name) > maxBaselineNameLength {
+		return nil, fmt.Errorf("name too long (max %d characters)", maxBaselineNameLength)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid png: %w", err)
+	}
+
+	b := &Baseline{
+		ID:        fmt.Sprintf("baseline_%d", time.Now().UnixNano()),
+		Name:      name,
+		DeviceID:  deviceID,
+		Width:     cfg.Width,
+		Height:    cfg.Height,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+	if err := os.WriteFile(s.path(b.ID), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save baseline: %w", err)
+	}
+	if s.db != nil {
+		if _, err := s.db.Exec(`INSERT INTO screenshot_baselines (id, name, device_id, width, height, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			b.ID, b.Name, b.DeviceID, b.Width, b.Height, b.CreatedAt); err != nil {
+			os.Remove(s.path(b.ID))
+			return nil, fmt.Errorf("failed to save baseline: %w", err)
+		}
+	}
+
+	s.baselines[b.ID] = b
+	log.Printf("🖼️ Baseline created: %s (%dx%d)", b.Name, b.Width, b.Height)
+	return b, nil
+}
+
+// GetAll returns all baselines ordered by creation time
+func (s *BaselineStore) GetAll() []*Baseline {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	baselines := make([]*Baseline, 0, len(s.baselines))
+	for _, b := range s.baselines {
+		baselines = append(baselines, b)
+	}
+	sort.Slice(baselines, func(i, j int) bool {
+		if baselines[i].CreatedAt != baselines[j].CreatedAt {
+			return baselines[i].CreatedAt < baselines[j].CreatedAt
+		}
+		return baselines[i].ID < baselines[j].ID
+	})
+	return baselines
+}
+
+// Get returns a baseline by ID
+func (s *BaselineStore) Get(id string) *Baseline {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.baselines[id]
+}
+
+// Image returns a baseline's PNG bytes
+func (s *BaselineStore) Image(id string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.baselines[id]; !ok {
+		return nil, fmt.Errorf("baseline not found: %s", id)
+	}
+	return os.ReadFile(s.path(id))
+}
+
+// Delete removes a baseline and its image
+func (s *BaselineStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.baselines[id]; !ok {
+		return fmt.Errorf("baseline not found: %s", id)
+	}
+
+	if s.db != nil {
+		if _, err := s.db.Exec(`DELETE FROM screenshot_baselines WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete baseline: %w", err)
+		}
+	}
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		log.Printf("⚠️ Failed to remove baseline image %s: %v", id, err)
+	}
+
+	delete(s.baselines, id)
+	return nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// StreamConfig.CodecOptions are MediaFormat keys passed to the device encoder through
+// scrcpy's video_codec_options ("key[:type]=value", type int by default; long, float, string).
+// Common keys:
+//
+//	i-frame-interval  seconds between IDRs (scrcpy uses 10); 1 lets new viewers join faster
+//	profile           H.264: 1 baseline, 2 main, 8 high; H.265: 1 main
+//	level             codec level constant, e.g. 4096 = H.264 level 4.1
+//	bitrate-mode      0 CQ, 1 VBR, 2 CBR
+//	latency           frames the encoder may buffer (Android 11+)
+//	priority          0 realtime, 1 best effort
+//
+// Unsupported keys are ignored by the encoder; values are logged by the server.
+var (
+	codecOptionKeyRe   = regexp.MustCompile(`^[a-z0-9][a-z0-9.\-]*(:(int|long|float|string))?$`)
+	codecOptionValueRe = regexp.MustCompile(`^[A-Za-z0-9._\-]+$`) // Ends up in an adb shell command line
+)
+
+// validateCodecOptions rejects keys and values the server can't parse or the shell would interpret
+func validateCodecOptions(opts map[string]string) error {
+	for key, value := range opts {
+		if !codecOptionKeyRe.MatchString(key) {
+			return fmt.Errorf("invalid codec option key: %q (expected key or key:int|long|float|string)", key)
+		}
+		if !codecOptionValueRe.MatchString(value) {
+			return fmt.Errorf("invalid codec option value for %s: %q", key, value)
+		}
+	}
+	return nil
+}
+
+// codecOptionsArg serializes codec options for video_codec_options= ("" when none), sorted by key
+func codecOptionsArg(opts map[string]string) string {
+	parts := make([]string, 0, len(opts))
+	for _, key := range slices.Sorted(maps.Keys(opts)) {
+		parts = append(parts, key+"="+opts[key])
+	}
+	return strings.Join(parts, ",")
+}
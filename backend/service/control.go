@@ -2,6 +2,8 @@ package service
 
 import (
 	"encoding/binary"
+	"fmt"
+	"io"
 )
 
 // Control message types (scrcpy 3.x protocol)
@@ -9,15 +11,50 @@ const (
 	CtrlInjectKeycode    = 0
 	CtrlInjectText       = 1
 	CtrlInjectTouchEvent = 2
+	CtrlInjectScroll     = 3
+	CtrlGetClipboard     = 8
 	CtrlSetClipboard     = 9
+	CtrlSetDisplayPower  = 10 // Screen off/on while mirroring (the server turns it back on when it exits)
+	CtrlResetVideo       = 17 // Restarts the encoder: fresh SPS/PPS + IDR
 )
 
+// Device message types (device -> client on the control socket)
+const (
+	DeviceMsgClipboard    = 0
+	DeviceMsgAckClipboard = 1
+	DeviceMsgUhidOutput   = 2
+)
+
+// Copy keys for GET_CLIPBOARD
+const (
+	CopyKeyNone = 0
+	CopyKeyCopy = 1
+	CopyKeyCut  = 2
+)
+
+// deviceMsgMaxSize caps the clipboard payload we accept (matches scrcpy's 256KB message limit)
+const deviceMsgMaxSize = 1 << 18
+
 // Android key event actions
 const (
 	ActionDown = 0
 	ActionUp   = 1
 )
 
+// Android motion event actions (touch)
+const (
+	MotionActionDown = 0
+	MotionActionUp   = 1
+	MotionActionMove = 2
+)
+
+// Special pointer IDs understood by scrcpy server
+const (
+	PointerIDMouse         uint64 = 0xFFFFFFFFFFFFFFFF // -1
+	PointerIDGenericFinger uint64 = 0xFFFFFFFFFFFFFFFE // -2
+	PointerIDVirtualFinger uint64 = 0xFFFFFFFFFFFFFFFD // -3
+)
+
 // Android meta state flags
 const (
 	MetaNone    = 0
@@ -29,25 +66,128 @@ const (
 
 // Common Android keycodes
 const (
-	AKEYCODE_0           = 7
-	AKEYCODE_A           = 29
-	AKEYCODE_Z           = 54
-	AKEYCODE_TAB         = 61
-	AKEYCODE_SPACE       = 62
-	AKEYCODE_ENTER       = 66
-	AKEYCODE_DEL         = 67 // Backspace
-	AKEYCODE_ESCAPE      = 111
-	AKEYCODE_FORWARD_DEL = 112 // Delete
-	AKEYCODE_DPAD_UP     = 19
-	AKEYCODE_DPAD_DOWN   = 20
-	AKEYCODE_DPAD_LEFT   = 21
-	AKEYCODE_DPAD_RIGHT  = 22
-	AKEYCODE_HOME        = 3
-	AKEYCODE_BACK        = 4
-	AKEYCODE_VOLUME_UP   = 24
-	AKEYCODE_VOLUME_DOWN = 25
+	AKEYCODE_0               = 7
+	AKEYCODE_A               = 29
+	AKEYCODE_Z               = 54
+	AKEYCODE_TAB             = 61
+	AKEYCODE_SPACE           = 62
+	AKEYCODE_ENTER           = 66
+	AKEYCODE_DEL             = 67 // Backspace
+	AKEYCODE_ESCAPE          = 111
+	AKEYCODE_FORWARD_DEL     = 112 // Delete
+	AKEYCODE_DPAD_UP         = 19
+	AKEYCODE_DPAD_DOWN       = 20
+	AKEYCODE_DPAD_LEFT       = 21
+	AKEYCODE_DPAD_RIGHT      = 22
+	AKEYCODE_HOME            = 3
+	AKEYCODE_BACK            = 4
+	AKEYCODE_VOLUME_UP       = 24
+	AKEYCODE_VOLUME_DOWN     = 25
+	AKEYCODE_APP_SWITCH      = 187
+	AKEYCODE_DPAD_CENTER     = 23
+	AKEYCODE_POWER           = 26
+	AKEYCODE_CAMERA          = 27
+	AKEYCODE_MENU            = 82
+	AKEYCODE_NOTIFICATION    = 83
+	AKEYCODE_SEARCH          = 84
+	AKEYCODE_PAGE_UP         = 92
+	AKEYCODE_PAGE_DOWN       = 93
+	AKEYCODE_MOVE_HOME       = 122
+	AKEYCODE_MOVE_END        = 123
+	AKEYCODE_VOLUME_MUTE     = 164
+	AKEYCODE_BRIGHTNESS_DOWN = 220
+	AKEYCODE_BRIGHTNESS_UP   = 221
+	AKEYCODE_SLEEP           = 223
+	AKEYCODE_WAKEUP          = 224
+
+	// Media
+	AKEYCODE_MEDIA_PLAY_PAUSE   = 85
+	AKEYCODE_MEDIA_STOP         = 86
+	AKEYCODE_MEDIA_NEXT         = 87
+	AKEYCODE_MEDIA_PREVIOUS     = 88
+	AKEYCODE_MEDIA_REWIND       = 89
+	AKEYCODE_MEDIA_FAST_FORWARD = 90
+	AKEYCODE_MUTE               = 91 // Microphone
+	AKEYCODE_MEDIA_PLAY         = 126
+	AKEYCODE_MEDIA_PAUSE        = 127
+
+	// Symbols (US layout)
+	AKEYCODE_STAR          = 17
+	AKEYCODE_POUND         = 18
+	AKEYCODE_COMMA         = 55
+	AKEYCODE_PERIOD        = 56
+	AKEYCODE_GRAVE         = 68
+	AKEYCODE_MINUS         = 69
+	AKEYCODE_EQUALS        = 70
+	AKEYCODE_LEFT_BRACKET  = 71
+	AKEYCODE_RIGHT_BRACKET = 72
+	AKEYCODE_BACKSLASH     = 73
+	AKEYCODE_SEMICOLON     = 74
+	AKEYCODE_APOSTROPHE    = 75
+	AKEYCODE_SLASH         = 76
+	AKEYCODE_AT            = 77
+	AKEYCODE_PLUS          = 81
 )
 
+// keyStroke is one key press that types a character
+type keyStroke struct {
+	keycode   int
+	metastate int
+}
+
+// symbolKeys maps printable ASCII symbols to key presses on a US layout
+var symbolKeys = map[byte]keyStroke{
+	' ':  {AKEYCODE_SPACE, MetaNone},
+	'\t': {AKEYCODE_TAB, MetaNone},
+	'\n': {AKEYCODE_ENTER, MetaNone},
+	'*':  {AKEYCODE_STAR, MetaNone},
+	'#':  {AKEYCODE_POUND, MetaNone},
+	',':  {AKEYCODE_COMMA, MetaNone},
+	'.':  {AKEYCODE_PERIOD, MetaNone},
+	'`':  {AKEYCODE_GRAVE, MetaNone},
+	'-':  {AKEYCODE_MINUS, MetaNone},
+	'=':  {AKEYCODE_EQUALS, MetaNone},
+	'[':  {AKEYCODE_LEFT_BRACKET, MetaNone},
+	']':  {AKEYCODE_RIGHT_BRACKET, MetaNone},
+	'\\': {AKEYCODE_BACKSLASH, MetaNone},
+	';':  {AKEYCODE_SEMICOLON, MetaNone},
+	'\'': {AKEYCODE_APOSTROPHE, MetaNone},
+	'/':  {AKEYCODE_SLASH, MetaNone},
+	'@':  {AKEYCODE_AT, MetaNone},
+	'+':  {AKEYCODE_PLUS, MetaNone},
+	'!':  {AKEYCODE_0 + 1, MetaShiftOn},
+	'$':  {AKEYCODE_0 + 4, MetaShiftOn},
+	'%':  {AKEYCODE_0 + 5, MetaShiftOn},
+	'^':  {AKEYCODE_0 + 6, MetaShiftOn},
+	'&':  {AKEYCODE_0 + 7, MetaShiftOn},
+	'(':  {AKEYCODE_0 + 9, MetaShiftOn},
+	')':  {AKEYCODE_0, MetaShiftOn},
+	'~':  {AKEYCODE_GRAVE, MetaShiftOn},
+	'_':  {AKEYCODE_MINUS, MetaShiftOn},
+	'{':  {AKEYCODE_LEFT_BRACKET, MetaShiftOn},
+	'}':  {AKEYCODE_RIGHT_BRACKET, MetaShiftOn},
+	'|':  {AKEYCODE_BACKSLASH, MetaShiftOn},
+	':':  {AKEYCODE_SEMICOLON, MetaShiftOn},
+	'"':  {AKEYCODE_APOSTROPHE, MetaShiftOn},
+	'<':  {AKEYCODE_COMMA, MetaShiftOn},
+	'>':  {AKEYCODE_PERIOD, MetaShiftOn},
+	'?':  {AKEYCODE_SLASH, MetaShiftOn},
+}
+
+// keyStrokeFor returns the key press that types c, if there is one
+func keyStrokeFor(c byte) (keyStroke, bool) {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return keyStroke{AKEYCODE_A + int(c-'a'), MetaNone}, true
+	case c >= 'A' && c <= 'Z':
+		return keyStroke{AKEYCODE_A + int(c-'A'), MetaShiftOn}, true
+	case c >= '0' && c <= '9':
+		return keyStroke{AKEYCODE_0 + int(c-'0'), MetaNone}, true
+	}
+	stroke, ok := symbolKeys[c]
+	return stroke, ok
+}
+
 // SerializeKeycode creates a binary message for key injection
 // Format: [type:1] [action:1] [keycode:4] [repeat:4] [metastate:4] = 14 bytes
 func SerializeKeycode(action, keycode, repeat, metastate int) []byte {
@@ -60,13 +200,16 @@ func SerializeKeycode(action, keycode, repeat, metastate int) []byte {
 	return buf
 }
 
+// injectTextMaxLength is the longest text scrcpy accepts in one inject-text message
+const injectTextMaxLength = 300
+
 // SerializeText creates a binary message for text injection
 // Format: [type:1] [length:4] [text:N] = 5+N bytes
 // Max text length: 300 bytes (SC_CONTROL_MSG_INJECT_TEXT_MAX_LENGTH)
 func SerializeText(text string) []byte {
 	textBytes := []byte(text)
-	if len(textBytes) > 300 {
-		textBytes = textBytes[:300]
+	if len(textBytes) > injectTextMaxLength {
+		textBytes = textBytes[:injectTextMaxLength]
 	}
 
 	buf := make([]byte, 5+len(textBytes))
@@ -94,6 +237,141 @@ func SerializeClipboard(text string, paste bool, sequence uint64) []byte {
 	return buf
 }
 
+// SerializeGetClipboard creates a message requesting the device clipboard
+// Format: [type:1] [copyKey:1] = 2 bytes
+func SerializeGetClipboard(copyKey int) []byte {
+	return []byte{CtrlGetClipboard, byte(copyKey)}
+}
+
+// deviceMessage is a parsed device -> client message
+type deviceMessage struct {
+	msgType  byte
+	text     string // DeviceMsgClipboard
+	sequence uint64 // DeviceMsgAckClipboard
+}
+
+// readDeviceMessage reads one device message from the control socket
+// Formats: clipboard [type:1][len:4][text:N], ack [type:1][seq:8], uhid [type:1][id:2][size:2][data:N]
+func readDeviceMessage(r io.Reader) (deviceMessage, error) {
+	var header [1]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return deviceMessage{}, err
+	}
+	msg := deviceMessage{msgType: header[0]}
+
+	switch msg.msgType {
+	case DeviceMsgClipboard:
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+			return msg, err
+		}
+		length := binary.BigEndian.Uint32(lenBuf[:])
+		if length > deviceMsgMaxSize {
+			return msg, fmt.Errorf("clipboard message too large: %d bytes", length)
+		}
+		text := make([]byte, length)
+		if _, err := io.ReadFull(r, text); err != nil {
+			return msg, err
+		}
+		msg.text = string(text)
+
+	case DeviceMsgAckClipboard:
+		var seqBuf [8]byte
+		if _, err := io.ReadFull(r, seqBuf[:]); err != nil {
+			return msg, err
+		}
+		msg.sequence = binary.BigEndian.Uint64(seqBuf[:])
+
+	case DeviceMsgUhidOutput:
+		var uhidHeader [4]byte
+		if _, err := io.ReadFull(r, uhidHeader[:]); err != nil {
+			return msg, err
+		}
+		size := int64(binary.BigEndian.Uint16(uhidHeader[2:4]))
+		if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return msg, err
+		}
+
+	default:
+		// Unknown type - framing is lost, the caller must stop reading
+		return msg, fmt.Errorf("unknown device message type: %d", msg.msgType)
+	}
+
+	return msg, nil
+}
+
+// SerializeTouchEvent creates a binary message for touch injection
+// Format: [type:1] [action:1] [pointerId:8] [x:4] [y:4] [w:2] [h:2] [pressure:2] [actionButton:4] [buttons:4] = 32 bytes
+// width/height must match the current video size, otherwise the server ignores the event
+func SerializeTouchEvent(action int, pointerID uint64, x, y, width, height int, pressure float32, buttons int) []byte {
+	buf := make([]byte, 32)
+	buf[0] = CtrlInjectTouchEvent
+	buf[1] = byte(action)
+	binary.BigEndian.PutUint64(buf[2:10], pointerID)
+	binary.BigEndian.PutUint32(buf[10:14], uint32(x))
+	binary.BigEndian.PutUint32(buf[14:18], uint32(y))
+	binary.BigEndian.PutUint16(buf[18:20], uint16(width))
+	binary.BigEndian.PutUint16(buf[20:22], uint16(height))
+	binary.BigEndian.PutUint16(buf[22:24], floatToU16FixedPoint(pressure))
+	binary.BigEndian.PutUint32(buf[24:28], 0) // actionButton: 0 for finger touches
+	binary.BigEndian.PutUint32(buf[28:32], uint32(buttons))
+	return buf
+}
+
+// floatToU16FixedPoint converts [0.0, 1.0] to 16-bit fixed point (1.0 => 0xFFFF)
+func floatToU16FixedPoint(f float32) uint16 {
+	if f >= 1.0 {
+		return 0xFFFF
+	}
+	if !(f > 0) { // Also catches NaN
+		return 0
+	}
+	return uint16(f * 65536)
+}
+
+// SerializeScrollEvent creates a binary message for scroll injection
+// Format: [type:1] [x:4] [y:4] [w:2] [h:2] [hScroll:2] [vScroll:2] [buttons:4] = 21 bytes
+// hScroll/vScroll are wheel steps; scrcpy 3.x expects them normalized by 16 into [-1, 1]
+func SerializeScrollEvent(x, y, width, height int, hScroll, vScroll float32, buttons int) []byte {
+	buf := make([]byte, 21)
+	buf[0] = CtrlInjectScroll
+	binary.BigEndian.PutUint32(buf[1:5], uint32(x))
+	binary.BigEndian.PutUint32(buf[5:9], uint32(y))
+	binary.BigEndian.PutUint16(buf[9:11], uint16(width))
+	binary.BigEndian.PutUint16(buf[11:13], uint16(height))
+	binary.BigEndian.PutUint16(buf[13:15], uint16(floatToI16FixedPoint(hScroll/16)))
+	binary.BigEndian.PutUint16(buf[15:17], uint16(floatToI16FixedPoint(vScroll/16)))
+	binary.BigEndian.PutUint32(buf[17:21], uint32(buttons))
+	return buf
+}
+
+// floatToI16FixedPoint converts [-1.0, 1.0] to signed 16-bit fixed point (1.0 => 0x7FFF)
+func floatToI16FixedPoint(f float32) int16 {
+	if f >= 1.0 {
+		return 0x7FFF
+	}
+	if f <= -1.0 {
+		return -0x8000
+	}
+	return int16(f * 32768)
+}
+
+// SerializeResetVideo creates a message asking the server to restart video capture/encoding
+// Format: [type:1] = 1 byte
+func SerializeResetVideo() []byte {
+	return []byte{CtrlResetVideo}
+}
+
+// SerializeDisplayPower creates a message turning the device display off or back on
+// Format: [type:1] [on:1] = 2 bytes
+func SerializeDisplayPower(on bool) []byte {
+	buf := []byte{CtrlSetDisplayPower, 0}
+	if on {
+		buf[1] = 1
+	}
+	return buf
+}
+
 // SerializeBackOrScreenOn creates a message for back button or screen on
 // Format: [type:1] [action:1] = 2 bytes
 func SerializeBackOrScreenOn(action int) []byte {
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"androidcontrol/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// maxAliasLength caps friendly device names
+const maxAliasLength = 64
+
+// loadAliases reads the saved friendly names (called before loadFromDB)
+func (m *DeviceManager) loadAliases() error {
+	rows, err := m.db.Query(`SELECT hardware_serial, alias FROM device_aliases`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for rows.Next() {
+		var serial, alias string
+		if err := rows.Scan(&serial, &alias); err != nil {
+			return err
+		}
+		m.aliases[serial] = alias
+	}
+	return rows.Err()
+}
+
+// applyAlias sets Name from the device's alias, or back to its model without one (must hold mu)
+// Keyed by hardware serial, so the name follows the phone across USB <-> WiFi and reconnects
+func (m *DeviceManager) applyAlias(device *models.Device) {
+	if alias, ok := m.aliases[device.HardwareSerial]; ok && device.HardwareSerial != "" {
+		device.Name = alias
+	} else if device.Model != "" {
+		device.Name = device.Model
+	}
+}
+
+// SetAlias names a device; an empty alias restores the model name
+// Returns the updated device
+func (m *DeviceManager) SetAlias(id, alias string) (*models.Device, error) {
+	alias = strings.TrimSpace(alias)
+	if len(alias) > maxAliasLength {
+		return nil, fmt.Errorf("alias too long (max %d characters)", maxAliasLength)
+	}
+
+	updated, err := m.setAlias(id, alias)
+	if err != nil {
+		return nil, err
+	}
+	m.persistDevices()
+
+	log.Printf("🏷️ [%s] Alias set to %q", id, alias)
+	return updated, nil
+}
+
+// setAlias saves an alias and applies it to the device entries sharing its hardware serial
+func (m *DeviceManager) setAlias(id, alias string) (*models.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	device, ok := m.devices[id]
+	if !ok {
+		return nil, fmt.Errorf("device not found: %s", id)
+	}
+	serial := device.HardwareSerial
+	if serial == "" {
+		return nil, fmt.Errorf("device %s has no hardware serial yet (not scanned while online)", id)
+	}
+
+	if m.db != nil {
+		var err error
+		if alias == "" {
+			_, err = m.db.Exec(`DELETE FROM device_aliases WHERE hardware_serial = ?`, serial)
+		} else {
+			_, err = m.db.Exec(`INSERT INTO device_aliases (hardware_serial, alias, updated_at) VALUES (?, ?, ?)
+				ON CONFLICT(hardware_serial) DO UPDATE SET alias = excluded.alias, updated_at = excluded.updated_at`,
+				serial, alias, time.Now().Unix())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to save alias: %w", err)
+		}
+	}
+
+	if alias == "" {
+		delete(m.aliases, serial)
+	} else {
+		m.aliases[serial] = alias
+	}
+
+	// Devices are replaced, not mutated - readers may hold the old pointers
+	for deviceID, d := range m.devices {
+		if d.HardwareSerial == serial {
+			updated := *d
+			if alias == "" && updated.Model == "" {
+				updated.Name = updated.ADBDeviceID // Model unknown until the next online scan
+			}
+			m.applyAlias(&updated)
+			m.devices[deviceID] = &updated
+		}
+	}
+	return m.devices[id], nil
+}
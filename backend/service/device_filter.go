@@ -0,0 +1,88 @@
+package service
+
+import (
+	"androidcontrol/models"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// DeviceFilter restricts which devices the manager tracks on a shared adb host
+// Entries match a device's hardware serial, ADB ID (serial or ip:port) or device ID.
+// Hardware serials are the safe choice: after USB/WiFi dedup only one ADB ID is kept.
+type DeviceFilter struct {
+	Allow []string // Non-empty: only matching devices are tracked
+	Deny  []string // Matching devices are never tracked (wins over Allow)
+}
+
+// Active reports whether the filter excludes anything
+func (f DeviceFilter) Active() bool {
+	return len(f.Allow) > 0 || len(f.Deny) > 0
+}
+
+// check returns why a device is filtered out ("" = allowed)
+func (f DeviceFilter) check(device *models.Device) string {
+	keys := []string{device.HardwareSerial, device.ADBDeviceID, device.ID}
+	match := func(list []string) string {
+		for _, key := range keys {
+			if key != "" && slices.Contains(list, key) {
+				return key
+			}
+		}
+		return ""
+	}
+
+	if key := match(f.Deny); key != "" {
+		return fmt.Sprintf("%s is in the denylist", key)
+	}
+	if len(f.Allow) > 0 && match(f.Allow) == "" {
+		return fmt.Sprintf("not in the allowlist (serial %s, adb %s)", device.HardwareSerial, device.ADBDeviceID)
+	}
+	return ""
+}
+
+// SetDeviceFilter sets the allow/deny lists applied on every scan
+// Tracked devices the new filter excludes are dropped from the device list (not from the database)
+func (m *DeviceManager) SetDeviceFilter(filter DeviceFilter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.filter = filter
+	m.filtered = make(map[string]bool)
+	if filter.Active() {
+		log.Printf("🚫 Device filter: allow=%v deny=%v", filter.Allow, filter.Deny)
+	}
+	for id, device := range m.devices {
+		if reason := filter.check(device); reason != "" {
+			delete(m.devices, id)
+			m.filtered[id] = true
+			log.Printf("🚫 [%s] Device filtered out: %s", id, reason)
+		}
+	}
+}
+
+// applyFilter removes devices the filter excludes from a scan result (must hold mu)
+// Runs after dedup, so an allowed phone stays usable over USB and WiFi.
+// Each device is logged once until it stops being filtered.
+func (m *DeviceManager) applyFilter(devices []models.Device) []models.Device {
+	if !m.filter.Active() {
+		return devices
+	}
+
+	kept := devices[:0]
+	for i := range devices {
+		id := devices[i].ID
+		reason := m.filter.check(&devices[i])
+		if reason == "" {
+			delete(m.filtered, id)
+			kept = append(kept, devices[i])
+			continue
+		}
+		if !m.filtered[id] {
+			m.filtered[id] = true
+			log.Printf("🚫 [%s] Device filtered out: %s", id, reason)
+		}
+		delete(m.devices, id) // Loaded from the database before the filter changed
+	}
+	return kept
+}
@@ -2,48 +2,547 @@ package service
 
 import (
 	"androidcontrol/adb"
+	"androidcontrol/events"
+	"androidcontrol/logging"
 	"androidcontrol/models"
 	"database/sql"
+	"fmt"
+	"log"
 	"sync"
 	"time"
 )
 
+// Device event names emitted when a scan changes a device's presence
+const (
+	DeviceEventOnline  = "online"
+	DeviceEventOffline = "offline"
+
+	// Battery level crossed a threshold since the previous scan
+	DeviceEventBatteryLow  = "battery_low"
+	DeviceEventBatteryHigh = "battery_high"
+
+	// A dropped WiFi device came back after adb connect, or retries ran out
+	DeviceEventReconnected     = "reconnected"
+	DeviceEventReconnectFailed = "reconnect_failed"
+)
+
+// DeviceEventHandler is notified when a device appears, disappears or crosses a battery threshold
+type DeviceEventHandler func(event string, device *models.Device)
+
 type DeviceManager struct {
 	devices   map[string]*models.Device
 	mu        sync.RWMutex
+	scanMu    sync.Mutex // Serializes scans; adb runs under it, not under mu
+	lastScan  time.Time  // When the last scan finished (guarded by scanMu)
 	db        *sql.DB
 	adbClient *adb.ADBClient
+
+	// Device rows are written outside mu; persistMu orders the writes
+	persistMu sync.Mutex
+	deleted   []string // Device IDs to delete at the next write (guarded by mu)
+
+	// Auto-scan + events
+	eventHandler DeviceEventHandler
+	bus          *events.Bus // Device events for WebSocket clients (nil = not published)
+	stopScan     chan struct{}
+
+	// adb reverse tunnels, torn down when a device goes offline
+	reverses *reverseTunnels
+
+	// Static device properties per hardware serial, so scans only query battery
+	props *adb.PropertyCache
+
+	// Battery alert thresholds in percent (guarded by mu)
+	batteryLow  int
+	batteryHigh int
+
+	// Friendly names per hardware serial, overlaid onto Device.Name (guarded by mu)
+	aliases map[string]string
+
+	// Last WiFi ip:port per hardware serial and pending reconnects (guarded by mu)
+	wifiEndpoints map[string]string
+	reconnects    map[string]*wifiReconnect
+
+	// Allow/deny lists and device IDs already logged as filtered (guarded by mu)
+	filter   DeviceFilter
+	filtered map[string]bool
+}
+
+// ScanDevicesOpts tunes a device scan
+type ScanDevicesOpts struct {
+	ForceRefresh bool // Re-query static properties instead of using the cache
 }
 
 func NewDeviceManager(db *sql.DB) *DeviceManager {
-	return &DeviceManager{
+	adbClient := adb.NewADBClient()
+	m := &DeviceManager{
 		devices:   make(map[string]*models.Device),
 		db:        db,
-		adbClient: adb.NewADBClient(),
+		adbClient: adbClient,
+		reverses:  newReverseTunnels(adbClient),
+		props:     adb.NewPropertyCache(),
+
+		batteryLow:  15,
+		batteryHigh: 95,
+		aliases:     make(map[string]string),
+
+		wifiEndpoints: make(map[string]string),
+		reconnects:    make(map[string]*wifiReconnect),
+		filtered:      make(map[string]bool),
+	}
+
+	// Load known devices so offline ones are visible with last-known info
+	if db != nil {
+		if err := m.loadAliases(); err != nil {
+			log.Printf("⚠️ Failed to load device aliases from database: %v", err)
+		}
+		if err := m.loadFromDB(); err != nil {
+			log.Printf("⚠️ Failed to load devices from database: %v", err)
+		}
 	}
+
+	// Seed serials for dedup in case getprop fails on the first scan
+	// (a serial equal to the ADB ID may be the lookup-failed fallback, so it isn't trusted)
+	for _, device := range m.devices {
+		if device.HardwareSerial != device.ADBDeviceID {
+			m.props.Remember(device.ADBDeviceID, device.HardwareSerial)
+		}
+	}
+
+	return m
 }
 
-// ScanDevices scans for connected Android devices via ADB
+// ScanDevices scans for connected Android devices via ADB, using cached static properties
 func (m *DeviceManager) ScanDevices() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	return m.ScanDevicesWithOpts(ScanDevicesOpts{})
+}
+
+// ScanDevicesWithOpts scans for connected Android devices via ADB
+// Devices missing from the scan are kept and marked offline
+// adb runs outside mu and the new map is swapped in whole, so readers never see a partial list.
+// Presence changes are reported to the event handler after the scan
+func (m *DeviceManager) ScanDevicesWithOpts(opts ScanDevicesOpts) error {
+	requested := time.Now()
+	m.scanMu.Lock()
+	defer m.scanMu.Unlock()
+
+	// Callers that queued behind a scan get its result instead of running another one
+	if !opts.ForceRefresh && m.lastScan.After(requested) {
+		return nil
+	}
+
+	if opts.ForceRefresh {
+		m.props.Clear()
+	}
 
 	// Get devices from ADB
-	devices, err := m.adbClient.ListDevices()
+	devices, err := m.adbClient.ListDevicesCached(m.props)
 	if err != nil {
 		return err
 	}
 
-	// Update device map
-	m.devices = make(map[string]*models.Device)
+	m.mu.Lock()
+	devices = m.applyFilter(devices)
+
+	type deviceEvent struct {
+		event  string
+		device *models.Device
+	}
+	var events []deviceEvent
+
+	// Build the new device map from the scan, diffed against the current one
+	now := time.Now().Unix()
+	next := make(map[string]*models.Device, len(m.devices))
+	onlineSerials := make(map[string]bool, len(devices))
 	for i := range devices {
-		devices[i].LastSeen = time.Now().Unix()
-		m.devices[devices[i].ID] = &devices[i]
+		devices[i].LastSeen = now
+		old, exists := m.devices[devices[i].ID]
+
+		if devices[i].Status != models.DeviceStatusOnline {
+			// Listed but not usable (unauthorized, offline, no permissions) - keep last-known info
+			if exists {
+				known := *old
+				known.Status = devices[i].Status
+				known.LastSeen = now
+				devices[i] = known
+			}
+			if exists && old.Status == models.DeviceStatusOnline {
+				events = append(events, deviceEvent{DeviceEventOffline, &devices[i]})
+				if isWiFiADBID(old.ADBDeviceID) {
+					m.scheduleWiFiReconnect(old)
+				}
+			}
+			if !exists || old.Status != devices[i].Status {
+				log.Printf("🔒 [%s] Device listed as %s", devices[i].ID, devices[i].Status)
+			}
+		} else if !exists || old.Status != models.DeviceStatusOnline {
+			if exists {
+				keepLastKnown(&devices[i], old)
+			}
+			events = append(events, deviceEvent{DeviceEventOnline, &devices[i]})
+		} else {
+			keepLastKnown(&devices[i], old)
+			if event := m.batteryCrossing(old.Battery, devices[i].Battery); event != "" {
+				events = append(events, deviceEvent{event, &devices[i]})
+			}
+		}
+
+		m.applyAlias(&devices[i])
+		m.trackWiFiEndpoint(&devices[i])
+		next[devices[i].ID] = &devices[i]
+		if devices[i].Status == models.DeviceStatusOnline && devices[i].HardwareSerial != "" {
+			onlineSerials[devices[i].HardwareSerial] = true
+		}
 	}
 
+	for id, device := range m.devices {
+		if _, seen := next[id]; seen {
+			continue
+		}
+		offline := *device
+		offline.Status = "offline"
+		if device.Status == "online" {
+			events = append(events, deviceEvent{DeviceEventOffline, &offline})
+			if isWiFiADBID(device.ADBDeviceID) {
+				m.scheduleWiFiReconnect(device)
+			}
+		}
+
+		// Same phone now reachable under another ADB ID (USB <-> WiFi) - drop the stale entry
+		if device.HardwareSerial != "" && onlineSerials[device.HardwareSerial] {
+			m.deleteFromDB(id)
+			continue
+		}
+		if device.Status != "offline" {
+			next[id] = &offline
+			log.Printf("📴 [%s] Device not seen in scan, marked offline", id)
+		} else {
+			next[id] = device
+		}
+	}
+
+	m.devices = next
+	handler := m.eventHandler
+	m.mu.Unlock()
+	m.persistDevices()
+	m.lastScan = time.Now()
+
+	// Notify outside the lock - handlers may call back into DeviceManager
+	for _, e := range events {
+		if e.event == DeviceEventOffline {
+			m.reverses.cleanup(e.device.ADBDeviceID)
+		}
+		m.notify(handler, e.event, e.device)
+	}
 	return nil
 }
 
+// keepLastKnown fills in what a scan of an online device failed to read
+// (getprop/dumpsys timing out under load) from its previous entry, so tiles don't lose info
+func keepLastKnown(device, old *models.Device) {
+	if device.Resolution == "" {
+		device.Resolution = old.Resolution
+	}
+	if device.AndroidVersion == "" {
+		device.AndroidVersion = old.AndroidVersion
+	}
+	if device.Manufacturer == "" {
+		device.Manufacturer = old.Manufacturer
+	}
+	if device.SDKInt == 0 {
+		device.SDKInt = old.SDKInt
+	}
+	if device.CPUABI == "" {
+		device.CPUABI = old.CPUABI
+	}
+	if device.Battery == 0 {
+		device.Battery = old.Battery // A phone at 0% is off, so 0 means the read failed
+	}
+	if device.Frame == "" {
+		device.Frame = old.Frame
+	}
+}
+
+// RefreshDisplayInfo re-reads a device's resolution after a wm size/density change
+// Drops its cached static properties so the next scan doesn't restore the old size
+func (m *DeviceManager) RefreshDisplayInfo(id string) {
+	m.mu.RLock()
+	device, exists := m.devices[id]
+	m.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	m.props.Invalidate(device.HardwareSerial)
+
+	resolution, err := m.adbClient.GetScreenResolution(device.ADBDeviceID)
+	if err != nil {
+		log.Printf("⚠️ [%s] Failed to read resolution: %v", id, err)
+		return
+	}
+
+	m.mu.Lock()
+	current, ok := m.devices[id]
+	if ok {
+		updated := *current
+		updated.Resolution = resolution
+		m.devices[id] = &updated
+	}
+	m.mu.Unlock()
+	if ok {
+		m.persistDevices()
+	}
+}
+
+// MarkOffline marks a device offline until the next scan sees it again
+// Emits an offline event (e.g. after reboot so its stream is stopped)
+func (m *DeviceManager) MarkOffline(id string) {
+	m.mu.Lock()
+	device, exists := m.devices[id]
+	if !exists || device.Status == "offline" {
+		m.mu.Unlock()
+		return
+	}
+
+	offline := *device
+	offline.Status = "offline"
+	m.devices[id] = &offline
+	handler := m.eventHandler
+	m.mu.Unlock()
+	m.persistDevices()
+
+	log.Printf("📴 [%s] Device marked offline", id)
+	m.reverses.cleanup(offline.ADBDeviceID)
+	m.notify(handler, DeviceEventOffline, &offline)
+}
+
+// AddReverse creates an adb reverse tunnel (device localabstract:remoteSocket -> local tcp:localPort)
+// Tracked tunnels are removed automatically when the device goes offline
+func (m *DeviceManager) AddReverse(deviceID, remoteSocket string, localPort int) error {
+	device := m.GetDevice(deviceID)
+	if device == nil {
+		return fmt.Errorf("device not found: %s", deviceID)
+	}
+	return m.reverses.add(device.ADBDeviceID, remoteSocket, localPort)
+}
+
+// RemoveReverse removes an adb reverse tunnel
+func (m *DeviceManager) RemoveReverse(deviceID, remoteSocket string) error {
+	device := m.GetDevice(deviceID)
+	if device == nil {
+		return fmt.Errorf("device not found: %s", deviceID)
+	}
+	return m.reverses.remove(device.ADBDeviceID, remoteSocket)
+}
+
+// CleanupReverses removes all tracked reverse tunnels (e.g. on shutdown)
+func (m *DeviceManager) CleanupReverses() {
+	m.reverses.cleanupAll()
+}
+
+// SetBatteryThresholds sets the levels that trigger battery alerts (0 disables that side)
+func (m *DeviceManager) SetBatteryThresholds(low, high int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batteryLow = low
+	m.batteryHigh = high
+}
+
+// batteryCrossing returns the battery event for a level change between scans, if any
+// Only crossings count, so a device sitting below the threshold alerts once (must hold mu)
+func (m *DeviceManager) batteryCrossing(previous, current int) string {
+	if previous <= 0 || current <= 0 {
+		return "" // Unknown level (first scan or dumpsys failed)
+	}
+	if m.batteryLow > 0 && previous > m.batteryLow && current <= m.batteryLow {
+		return DeviceEventBatteryLow
+	}
+	if m.batteryHigh > 0 && previous < m.batteryHigh && current >= m.batteryHigh {
+		return DeviceEventBatteryHigh
+	}
+	return ""
+}
+
+// SetEventHandler sets the callback for device online/offline and battery events
+func (m *DeviceManager) SetEventHandler(handler DeviceEventHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eventHandler = handler
+}
+
+// SetEventBus sets where device events are published for WebSocket clients
+func (m *DeviceManager) SetEventBus(bus *events.Bus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bus = bus
+}
+
+// notify publishes a device event to clients, then passes it to the handler (call without mu)
+// Battery crossings go out as {type:"battery_alert"}, everything else as {type:"device_event"}
+func (m *DeviceManager) notify(handler DeviceEventHandler, event string, device *models.Device) {
+	m.mu.RLock()
+	bus := m.bus
+	m.mu.RUnlock()
+
+	if event == DeviceEventBatteryLow || event == DeviceEventBatteryHigh {
+		threshold := "low"
+		if event == DeviceEventBatteryHigh {
+			threshold = "high"
+		}
+		logging.Device(device.ID).Info("battery_alert", "🔋 Battery %s: %d%%", threshold, device.Battery)
+		bus.Publish(events.Event{Type: "battery_alert", Payload: map[string]interface{}{
+			"type":      "battery_alert",
+			"device_id": device.ID,
+			"level":     device.Battery,
+			"threshold": threshold,
+		}})
+	} else {
+		logging.Device(device.ID).Info("device_event", "📱 Device %s", event)
+		bus.Publish(events.Event{Type: "device_event", Payload: map[string]interface{}{
+			"type":   "device_event",
+			"event":  event,
+			"device": device,
+		}})
+	}
+
+	if handler != nil {
+		handler(event, device)
+	}
+}
+
+// StartAutoScan rescans devices on a ticker until StopAutoScan is called
+func (m *DeviceManager) StartAutoScan(interval time.Duration) {
+	m.mu.Lock()
+	if m.stopScan != nil {
+		m.mu.Unlock()
+		return // Already running
+	}
+	stop := make(chan struct{})
+	m.stopScan = stop
+	m.mu.Unlock()
+
+	log.Printf("🔁 Auto-scan every %v", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := m.ScanDevices(); err != nil {
+					log.Printf("⚠️ Auto-scan failed: %v", err)
+				}
+				m.reconnectWiFiDevices()
+			}
+		}
+	}()
+}
+
+// StopAutoScan stops the periodic scan
+func (m *DeviceManager) StopAutoScan() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopScan != nil {
+		close(m.stopScan)
+		m.stopScan = nil
+	}
+}
+
+// loadFromDB loads previously seen devices as offline
+func (m *DeviceManager) loadFromDB() error {
+	rows, err := m.db.Query(`SELECT id, name, COALESCE(adb_device_id, ''), COALESCE(hardware_serial, ''),
+		COALESCE(resolution, ''), COALESCE(battery, 0), COALESCE(android_version, ''), COALESCE(last_seen, 0)
+		FROM devices`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for rows.Next() {
+		var d models.Device
+		if err := rows.Scan(&d.ID, &d.Name, &d.ADBDeviceID, &d.HardwareSerial,
+			&d.Resolution, &d.Battery, &d.AndroidVersion, &d.LastSeen); err != nil {
+			return err
+		}
+		d.Status = "offline" // Until the next scan sees it
+		adb.SetConnectionInfo(&d)
+		m.applyAlias(&d)
+		m.devices[d.ID] = &d
+		count++
+	}
+
+	log.Printf("💾 Loaded %d known devices from database", count)
+	return rows.Err()
+}
+
+// persistDevices writes all known devices and queued deletions in one transaction
+// Must not hold mu: rows are copied under it, then written without blocking device reads.
+// persistMu keeps writes in order, each one saving the state current when it started.
+func (m *DeviceManager) persistDevices() {
+	if m.db == nil {
+		return
+	}
+
+	m.persistMu.Lock()
+	defer m.persistMu.Unlock()
+
+	m.mu.Lock()
+	devices := make([]models.Device, 0, len(m.devices))
+	for _, d := range m.devices {
+		devices = append(devices, *d)
+	}
+	deleted := m.deleted
+	m.deleted = nil
+	m.mu.Unlock()
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		log.Printf("⚠️ Failed to persist devices: %v", err)
+		return
+	}
+	for _, id := range deleted {
+		if _, err := tx.Exec(`DELETE FROM devices WHERE id = ?`, id); err != nil {
+			log.Printf("⚠️ [%s] Failed to delete device: %v", id, err)
+		}
+	}
+	for _, d := range devices {
+		_, err := tx.Exec(`INSERT INTO devices
+			(id, name, adb_device_id, hardware_serial, status, resolution, battery, android_version, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				name = excluded.name,
+				adb_device_id = excluded.adb_device_id,
+				hardware_serial = excluded.hardware_serial,
+				status = excluded.status,
+				resolution = excluded.resolution,
+				battery = excluded.battery,
+				android_version = excluded.android_version,
+				last_seen = excluded.last_seen`,
+			d.ID, d.Name, d.ADBDeviceID, d.HardwareSerial, d.Status, d.Resolution, d.Battery, d.AndroidVersion, d.LastSeen)
+		if err != nil {
+			log.Printf("⚠️ [%s] Failed to persist device: %v", d.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("⚠️ Failed to persist devices: %v", err)
+	}
+}
+
+// deleteFromDB queues a device row for deletion by the next persistDevices (must hold mu)
+func (m *DeviceManager) deleteFromDB(id string) {
+	if m.db != nil {
+		m.deleted = append(m.deleted, id)
+	}
+}
+
 // GetAllDevices returns all devices
 func (m *DeviceManager) GetAllDevices() []*models.Device {
 	m.mu.RLock()
@@ -56,6 +555,19 @@ func (m *DeviceManager) GetAllDevices() []*models.Device {
 	return devices
 }
 
+// DeviceCounts returns the number of known and online devices
+func (m *DeviceManager) DeviceCounts() (total, online int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, device := range m.devices {
+		if device.Status == "online" {
+			online++
+		}
+	}
+	return len(m.devices), online
+}
+
 // GetDevice returns a single device by ID
 func (m *DeviceManager) GetDevice(id string) *models.Device {
 	m.mu.RLock()
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// displaySeparator joins a device ID and a display ID into a stream key ("device_X@2")
+// Display 0 keeps the plain device ID, so existing clients and endpoints are unchanged.
+const displaySeparator = "@"
+
+// StreamKey returns the key a display's stream is tracked, subscribed and broadcast under
+func StreamKey(deviceID string, displayID int) string {
+	if displayID == 0 {
+		return deviceID
+	}
+	return deviceID + displaySeparator + strconv.Itoa(displayID)
+}
+
+// ParseStreamKey splits a stream key into device ID and display ID (0 for a plain device ID)
+func ParseStreamKey(key string) (deviceID string, displayID int) {
+	i := strings.LastIndex(key, displaySeparator)
+	if i <= 0 {
+		return key, 0
+	}
+	id, err := strconv.Atoi(key[i+1:])
+	if err != nil || id <= 0 {
+		return key, 0
+	}
+	return key[:i], id
+}
+
+// streamKeysOf returns the keys of every stream (all displays) of a device
+func (s *StreamingService) streamKeysOf(deviceID string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []string
+	for key := range s.streams {
+		if id, _ := ParseStreamKey(key); id == deviceID {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// activeStreamKeys returns the keys of a device's streams that aren't stopped
+// Takes each stream's lock in turn, so callers must not hold any stream lock
+func (s *StreamingService) activeStreamKeys(deviceID string) []string {
+	var active []string
+	for _, key := range s.streamKeysOf(deviceID) {
+		s.mu.RLock()
+		stream := s.streams[key]
+		s.mu.RUnlock()
+		if stream == nil {
+			continue
+		}
+		stream.mu.Lock()
+		state := stream.state
+		stream.mu.Unlock()
+		if state != StateStopped {
+			active = append(active, key)
+		}
+	}
+	return active
+}
+
+// ErrStreamActive is returned by maintenance that would kill a running stream's server
+var ErrStreamActive = errors.New("device is streaming, stop its streams first")
+
+// CleanupScrcpy kills scrcpy servers and forwards left on a device by crashed sessions
+// Refused while any of the device's streams is running, since their servers would die too
+func (s *StreamingService) CleanupScrcpy(deviceID string) error {
+	device := s.deviceManager.GetDevice(deviceID)
+	if device == nil {
+		return fmt.Errorf("device not found: %s", deviceID)
+	}
+	if active := s.activeStreamKeys(deviceID); len(active) > 0 {
+		return fmt.Errorf("%w: %v", ErrStreamActive, active)
+	}
+	return s.deviceManager.GetADBClient().KillScrcpyServers(device.ADBDeviceID)
+}
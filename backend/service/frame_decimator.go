@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// decimateRefresh bounds how long dropped reference frames can smear the picture:
+// while frames are being skipped, a fresh IDR is requested at most this often
+const decimateRefresh = 2 * time.Second
+
+// frameDecimator thins delivered video to a target FPS without touching the encoder
+// Capture, recording and metrics keep the encoder's rate; only the broadcast is thinned.
+// Config NALs (VPS/SPS/PPS) and IDRs always pass so clients can (re)start decoding.
+type frameDecimator struct {
+	interval atomic.Int64 // Minimum ns between delivered frames (0 = deliver everything)
+
+	// Owned by the stream's NAL loop
+	next        time.Time // Earliest time the next frame may be delivered
+	dropFrame   bool      // Decision for the current frame, applied to its remaining slices
+	lastRefresh time.Time // Last keyframe requested to heal skipped references
+}
+
+// allow decides whether a NAL is broadcast; refresh asks the caller for a keyframe
+func (d *frameDecimator) allow(nalData []byte, kind nalKind, codec string, now time.Time) (send, refresh bool) {
+	interval := time.Duration(d.interval.Load())
+	if interval <= 0 {
+		return true, false
+	}
+
+	switch {
+	case kind == nalIDR:
+		d.dropFrame = false
+		d.next = now.Add(interval)
+		return true, false
+	case kind != nalOther || !isVCLNAL(nalData, codec):
+		return true, false // Config, SEI, AUD...
+	}
+
+	if firstSliceOfFrame(nalData, codec) {
+		d.dropFrame = now.Before(d.next)
+		if !d.dropFrame {
+			// Keep the average rate across arrival jitter, but don't bank time after a gap
+			d.next = d.next.Add(interval)
+			if d.next.Before(now) {
+				d.next = now.Add(interval)
+			}
+		}
+	}
+
+	if d.dropFrame && now.Sub(d.lastRefresh) >= decimateRefresh {
+		d.lastRefresh = now
+		return false, true
+	}
+	return !d.dropFrame, false
+}
+
+// firstSliceOfFrame reports whether a VCL NAL starts a new picture: first_mb_in_slice == 0
+// (H.264, ue(v) "1") or first_slice_segment_in_pic_flag (H.265), the first bit after the header
+func firstSliceOfFrame(nalData []byte, codec string) bool {
+	idx := nalHeaderIndex(nalData)
+	if idx < 0 {
+		return true
+	}
+	idx++
+	if codec == CodecH265 {
+		idx++ // 2-byte header
+	}
+	if idx >= len(nalData) {
+		return true
+	}
+	return nalData[idx]&0x80 != 0
+}
+
+// SetBroadcastFPS caps how many frames per second a device's viewers receive (0 = no cap)
+// Decouples delivery from capture FPS without restarting scrcpy
+func (s *StreamingService) SetBroadcastFPS(deviceID string, fps int) error {
+	if fps < 0 {
+		return fmt.Errorf("invalid fps: %d", fps)
+	}
+
+	stream, err := s.getOrCreateStream(deviceID)
+	if err != nil {
+		return err
+	}
+
+	var interval time.Duration
+	if fps > 0 {
+		interval = time.Second / time.Duration(fps)
+	}
+	stream.decimator.interval.Store(int64(interval))
+	log.Printf("🎚️ [%s] Broadcast FPS cap: %d (0 = off)", deviceID, fps)
+	return nil
+}
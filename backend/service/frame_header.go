@@ -0,0 +1,171 @@
+package service
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Binary WebSocket frame header (v2), all integers big-endian:
+//
+//	offset  size  field
+//	0       1     magic      0xAC
+//	1       1     version    0x02
+//	2       1     type       FrameTypeVideo / FrameTypeAudio
+//	3       1     flags      FrameFlag* bits
+//	4       2     idLen      device ID length in bytes
+//	6       8     pts        microseconds since the scrcpy session started
+//	14      idLen deviceID
+//	14+idLen ...  payload    one Annex-B NAL unit (video) or Opus packet (audio)
+//
+// Legacy frames (compatibility mode) are [idLen:1][deviceID][NAL] for video and
+// [0x00][type:1][idLen:1][deviceID][payload] for typed frames. Neither can start
+// with 0xAC 0x02 followed by a known type, so DecodeFrame tells them apart.
+const (
+	FrameMagic      = 0xAC
+	FrameVersion    = 0x02
+	frameHeaderSize = 14
+	maxFrameIDLen   = 0xFFFF
+	maxLegacyIDLen  = 0xFF
+)
+
+// Frame types. Legacy typed frames start with typedFrameMarker; legacy video
+// frames start with idLen (always >= 1), so old video parsers skip typed frames.
+const (
+	typedFrameMarker = 0x00
+	FrameTypeVideo   = 0x00
+	FrameTypeAudio   = 0x01
+)
+
+// Frame flags
+const (
+	FrameFlagKeyframe = 1 << 0 // IDR picture
+	FrameFlagConfig   = 1 << 1 // VPS/SPS/PPS parameter set, or the OpusHead audio config
+	FrameFlagH265     = 1 << 2 // Payload is H.265 (H.264 otherwise)
+)
+
+// FrameHeader is the decoded header of a binary WebSocket frame
+type FrameHeader struct {
+	Version  byte // 0 for legacy frames
+	Type     byte
+	Flags    byte
+	PTS      uint64
+	DeviceID string
+}
+
+// legacyFrames makes the server emit the pre-v2 packet layout for old frontends
+var legacyFrames atomic.Bool
+
+// SetLegacyFrames switches binary WebSocket frames between v2 and the legacy layout
+func SetLegacyFrames(enabled bool) {
+	legacyFrames.Store(enabled)
+}
+
+// LegacyFrames reports whether the legacy packet layout is in use
+func LegacyFrames() bool {
+	return legacyFrames.Load()
+}
+
+// ptsSince returns the presentation timestamp in microseconds relative to base
+func ptsSince(base time.Time) uint64 {
+	if base.IsZero() {
+		return 0
+	}
+	return uint64(time.Since(base).Microseconds())
+}
+
+// EncodeFrame builds a binary WebSocket frame in the active layout
+// Returns nil if the device ID doesn't fit the layout
+func EncodeFrame(h FrameHeader, payload []byte) []byte {
+	idLen := len(h.DeviceID)
+
+	if LegacyFrames() {
+		if idLen == 0 || idLen > maxLegacyIDLen {
+			return nil
+		}
+		if h.Type == FrameTypeVideo {
+			pkt := make([]byte, 1+idLen+len(payload))
+			pkt[0] = byte(idLen)
+			copy(pkt[1:], h.DeviceID)
+			copy(pkt[1+idLen:], payload)
+			return pkt
+		}
+		pkt := make([]byte, 3+idLen+len(payload))
+		pkt[0] = typedFrameMarker
+		pkt[1] = h.Type
+		pkt[2] = byte(idLen)
+		copy(pkt[3:], h.DeviceID)
+		copy(pkt[3+idLen:], payload)
+		return pkt
+	}
+
+	if idLen > maxFrameIDLen {
+		return nil
+	}
+	pkt := make([]byte, frameHeaderSize+idLen+len(payload))
+	pkt[0] = FrameMagic
+	pkt[1] = FrameVersion
+	pkt[2] = h.Type
+	pkt[3] = h.Flags
+	binary.BigEndian.PutUint16(pkt[4:6], uint16(idLen))
+	binary.BigEndian.PutUint64(pkt[6:14], h.PTS)
+	copy(pkt[frameHeaderSize:], h.DeviceID)
+	copy(pkt[frameHeaderSize+idLen:], payload)
+	return pkt
+}
+
+// isV2Frame reports whether pkt starts with a v2 header
+func isV2Frame(pkt []byte) bool {
+	return len(pkt) >= frameHeaderSize && pkt[0] == FrameMagic && pkt[1] == FrameVersion &&
+		(pkt[2] == FrameTypeVideo || pkt[2] == FrameTypeAudio)
+}
+
+// VideoFrameFlags returns a v2 video frame's FrameFlag* bits without decoding the device ID
+// ok is false for audio frames and legacy frames, which carry no flags (and may be H.265,
+// whose NAL types can't be told from H.264 without the codec)
+func VideoFrameFlags(pkt []byte) (flags byte, ok bool) {
+	if !isV2Frame(pkt) || pkt[2] != FrameTypeVideo {
+		return 0, false
+	}
+	return pkt[3], true
+}
+
+// DecodeFrame splits a binary WebSocket frame into header and payload
+// Accepts both v2 and legacy layouts; the payload aliases pkt
+func DecodeFrame(pkt []byte) (FrameHeader, []byte, error) {
+	if isV2Frame(pkt) {
+		idLen := int(binary.BigEndian.Uint16(pkt[4:6]))
+		if len(pkt) < frameHeaderSize+idLen {
+			return FrameHeader{}, nil, fmt.Errorf("frame truncated: need %d bytes, have %d", frameHeaderSize+idLen, len(pkt))
+		}
+		h := FrameHeader{
+			Version:  pkt[1],
+			Type:     pkt[2],
+			Flags:    pkt[3],
+			PTS:      binary.BigEndian.Uint64(pkt[6:14]),
+			DeviceID: string(pkt[frameHeaderSize : frameHeaderSize+idLen]),
+		}
+		return h, pkt[frameHeaderSize+idLen:], nil
+	}
+
+	if len(pkt) == 0 {
+		return FrameHeader{}, nil, fmt.Errorf("empty frame")
+	}
+
+	if pkt[0] == typedFrameMarker {
+		if len(pkt) < 3 || len(pkt) < 3+int(pkt[2]) {
+			return FrameHeader{}, nil, fmt.Errorf("typed frame truncated")
+		}
+		idLen := int(pkt[2])
+		h := FrameHeader{Type: pkt[1], DeviceID: string(pkt[3 : 3+idLen])}
+		return h, pkt[3+idLen:], nil
+	}
+
+	idLen := int(pkt[0])
+	if len(pkt) < 1+idLen {
+		return FrameHeader{}, nil, fmt.Errorf("video frame truncated")
+	}
+	h := FrameHeader{Type: FrameTypeVideo, DeviceID: string(pkt[1 : 1+idLen])}
+	return h, pkt[1+idLen:], nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// scrcpy frame meta, sent before every packet when send_frame_meta=true:
+// [pts_and_flags:8][size:4], big-endian; the top two pts bits flag config and key frames
+const (
+	frameMetaSize         = 12
+	frameMetaFlagConfig   = uint64(1) << 63
+	frameMetaFlagKeyFrame = uint64(1) << 62
+	frameMetaPTSMask      = frameMetaFlagKeyFrame - 1
+)
+
+// framePacket is one packet read from a socket with frame meta
+type framePacket struct {
+	PTS      uint64 // Microseconds, as stamped by the server
+	Config   bool   // Codec config (e.g. OpusHead), not media
+	KeyFrame bool
+	Data     []byte
+}
+
+// parseFrameMeta decodes a frame meta header, rejecting sizes no sane packet reaches
+func parseFrameMeta(meta []byte) (ptsAndFlags uint64, size int, err error) {
+	ptsAndFlags = binary.BigEndian.Uint64(meta[0:8])
+	n := binary.BigEndian.Uint32(meta[8:12])
+	if n > maxNALBufferSize {
+		return 0, 0, fmt.Errorf("frame meta packet size %d exceeds %d bytes", n, maxNALBufferSize)
+	}
+	return ptsAndFlags, int(n), nil
+}
+
+// readFramePacket reads the next frame meta header and its packet
+func readFramePacket(r io.Reader) (framePacket, error) {
+	var meta [frameMetaSize]byte
+	if _, err := io.ReadFull(r, meta[:]); err != nil {
+		return framePacket{}, err
+	}
+	ptsAndFlags, size, err := parseFrameMeta(meta[:])
+	if err != nil {
+		return framePacket{}, err
+	}
+	pkt := framePacket{
+		PTS:      ptsAndFlags & frameMetaPTSMask,
+		Config:   ptsAndFlags&frameMetaFlagConfig != 0,
+		KeyFrame: ptsAndFlags&frameMetaFlagKeyFrame != 0,
+		Data:     make([]byte, size),
+	}
+	if _, err := io.ReadFull(r, pkt.Data); err != nil {
+		return framePacket{}, err
+	}
+	return pkt, nil
+}
+
+// frameMetaConn strips frame meta from the video socket so consumeH264 still sees plain Annex-B
+// A read that times out mid-header keeps its progress, like a read on the bare socket
+type frameMetaConn struct {
+	net.Conn
+	meta      [frameMetaSize]byte
+	metaRead  int
+	remaining int // Payload bytes left in the current packet
+}
+
+func (f *frameMetaConn) Read(p []byte) (int, error) {
+	for f.remaining == 0 {
+		n, err := f.Conn.Read(f.meta[f.metaRead:])
+		f.metaRead += n
+		if f.metaRead == frameMetaSize {
+			f.metaRead = 0
+			_, size, metaErr := parseFrameMeta(f.meta[:])
+			if metaErr != nil {
+				return 0, metaErr
+			}
+			f.remaining = size
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if len(p) > f.remaining {
+		p = p[:f.remaining]
+	}
+	n, err := f.Conn.Read(p)
+	f.remaining -= n
+	return n, err
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// framed prepends scrcpy frame meta to a packet
+func framed(ptsAndFlags uint64, data []byte) []byte {
+	meta := make([]byte, frameMetaSize, frameMetaSize+len(data))
+	binary.BigEndian.PutUint64(meta[0:8], ptsAndFlags)
+	binary.BigEndian.PutUint32(meta[8:12], uint32(len(data)))
+	return append(meta, data...)
+}
+
+func TestReadFramePacket(t *testing.T) {
+	opusHead := []byte("OpusHead\x01\x02\x38\x01\x80\xbb\x00\x00\x00\x00\x00")
+	stream := concat(
+		framed(frameMetaFlagConfig, opusHead),
+		framed(20000, []byte{0xfc, 0xff, 0xfe}),
+		framed(frameMetaFlagKeyFrame|40000, []byte{0xfc}),
+	)
+	r := bytes.NewReader(stream)
+
+	want := []framePacket{
+		{PTS: 0, Config: true, Data: opusHead},
+		{PTS: 20000, Data: []byte{0xfc, 0xff, 0xfe}},
+		{PTS: 40000, KeyFrame: true, Data: []byte{0xfc}},
+	}
+	for i, w := range want {
+		got, err := readFramePacket(r)
+		if err != nil {
+			t.Fatalf("packet %d: %v", i, err)
+		}
+		if got.PTS != w.PTS || got.Config != w.Config || got.KeyFrame != w.KeyFrame || !bytes.Equal(got.Data, w.Data) {
+			t.Errorf("packet %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if _, err := readFramePacket(r); !errors.Is(err, io.EOF) {
+		t.Errorf("read past the end = %v, want EOF", err)
+	}
+}
+
+func TestReadFramePacketRejectsOversizedPacket(t *testing.T) {
+	meta := make([]byte, frameMetaSize)
+	binary.BigEndian.PutUint32(meta[8:12], maxNALBufferSize+1)
+	if _, err := readFramePacket(bytes.NewReader(meta)); err == nil {
+		t.Error("oversized packet accepted")
+	}
+}
+
+func TestFrameMetaConnYieldsPlainAnnexB(t *testing.T) {
+	sps := concat(sc4, []byte{0x67, 0x42, 0xc0, 0x1f})
+	pps := concat(sc4, []byte{0x68, 0xce, 0x3c, 0x80})
+	idr := concat(sc4, fakeNAL(0x65, 3000, 1)[4:])
+	stream := concat(
+		framed(frameMetaFlagConfig, concat(sps, pps)),
+		framed(frameMetaFlagKeyFrame, idr),
+		framed(33333, nil), // Empty packet: nothing to yield
+		framed(66666, concat(sc4, []byte{0x41, 0x9a})),
+	)
+	want := concat(sps, pps, idr, sc4, []byte{0x41, 0x9a})
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go func() {
+		// Odd chunk sizes split headers and payloads at arbitrary offsets
+		for off := 0; off < len(stream); off += 7 {
+			server.Write(stream[off:min(off+7, len(stream))])
+		}
+		server.Close()
+	}()
+
+	got, err := io.ReadAll(&frameMetaConn{Conn: client})
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("stripped stream = %d bytes, want %d", len(got), len(want))
+	}
+}
+
+func TestFrameMetaConnResumesAfterTimeoutMidHeader(t *testing.T) {
+	packet := framed(0, concat(sc4, []byte{0x65, 0xaa}))
+
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	fm := &frameMetaConn{Conn: client}
+
+	go server.Write(packet[:5])
+	buf := make([]byte, 64)
+	fm.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	n, err := fm.Read(buf)
+	var netErr net.Error
+	if n != 0 || !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Read mid-header = %d, %v, want a timeout", n, err)
+	}
+
+	go server.Write(packet[5:])
+	fm.SetReadDeadline(time.Now().Add(time.Second))
+	n, err = fm.Read(buf)
+	if err != nil || !bytes.Equal(buf[:n], packet[frameMetaSize:]) {
+		t.Fatalf("Read after timeout = % x, %v, want % x", buf[:n], err, packet[frameMetaSize:])
+	}
+}
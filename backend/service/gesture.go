@@ -0,0 +1,189 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// gestureStepInterval is the delay between interpolated MOVE events (~60Hz)
+const gestureStepInterval = 16 * time.Millisecond
+
+// gesturePointer is one finger of a gesture, moving in a straight line
+type gesturePointer struct {
+	id             uint64
+	x1, y1, x2, y2 int
+}
+
+// position returns the interpolated pointer position at progress t (0..1)
+func (p gesturePointer) position(t float64) (int, int) {
+	x := float64(p.x1) + float64(p.x2-p.x1)*t
+	y := float64(p.y1) + float64(p.y2-p.y1)*t
+	return int(x + 0.5), int(y + 0.5)
+}
+
+// controlTarget returns the scrcpy client and current video size for touch injection
+func (s *StreamingService) controlTarget(deviceID string) (*ScrcpyClient, int, int, error) {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists {
+		return nil, 0, 0, fmt.Errorf("control socket not connected for device: %s", deviceID)
+	}
+
+	stream.mu.Lock()
+	client := stream.scrcpyClient
+	width, height := stream.videoWidth, stream.videoHeight
+	stream.mu.Unlock()
+
+	if client == nil || !client.HasControl() {
+		return nil, 0, 0, fmt.Errorf("control socket not connected for device: %s", deviceID)
+	}
+	if width == 0 || height == 0 {
+		return nil, 0, 0, fmt.Errorf("video size unknown for device: %s", deviceID)
+	}
+	return client, width, height, nil
+}
+
+// videoFrameSize returns the size of the device's video frames, 0x0 when nothing is streaming
+func (s *StreamingService) videoFrameSize(deviceID string) (int, int) {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists {
+		return 0, 0
+	}
+
+	stream.mu.Lock()
+	client := stream.scrcpyClient
+	width, height := stream.videoWidth, stream.videoHeight
+	stream.mu.Unlock()
+
+	if (width == 0 || height == 0) && client != nil {
+		width, height = client.GetResolution()
+	}
+	return width, height
+}
+
+// frameToDevice scales a point from a frameW x frameH video frame to device pixels
+// wm size reports the natural orientation, so the device size is swapped to match a rotated frame
+func frameToDevice(x, y, frameW, frameH, deviceW, deviceH int) (int, int) {
+	if frameW <= 0 || frameH <= 0 || deviceW <= 0 || deviceH <= 0 {
+		return x, y
+	}
+	if (frameW > frameH) != (deviceW > deviceH) {
+		deviceW, deviceH = deviceH, deviceW
+	}
+	return x * deviceW / frameW, y * deviceH / frameH
+}
+
+// runGesture sends DOWN for every pointer, holds still for holdMs, then interpolated MOVEs and UPs on a timer
+// DOWN is sent synchronously so errors surface to the caller; the rest runs in background.
+// Events go straight to the client, so the touch rate limiter never coalesces a gesture.
+func (s *StreamingService) runGesture(deviceID string, pointers []gesturePointer, holdMs, durationMs int) error {
+	client, width, height, err := s.controlTarget(deviceID)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pointers {
+		if err := client.SendTouchEvent(MotionActionDown, p.id, p.x1, p.y1, width, height, 1.0, 0); err != nil {
+			return err
+		}
+	}
+
+	steps := int(time.Duration(durationMs) * time.Millisecond / gestureStepInterval)
+	if steps < 1 {
+		steps = 1
+	}
+
+	go func() {
+		if holdMs > 0 {
+			time.Sleep(time.Duration(holdMs) * time.Millisecond)
+		}
+
+		ticker := time.NewTicker(gestureStepInterval)
+		defer ticker.Stop()
+
+		for i := 1; i <= steps; i++ {
+			<-ticker.C
+			t := float64(i) / float64(steps)
+			for _, p := range pointers {
+				x, y := p.position(t)
+				if err := client.SendTouchEvent(MotionActionMove, p.id, x, y, width, height, 1.0, 0); err != nil {
+					log.Printf("⚠️ [%s] Gesture aborted: %v", deviceID, err)
+					return
+				}
+			}
+		}
+
+		for _, p := range pointers {
+			if err := client.SendTouchEvent(MotionActionUp, p.id, p.x2, p.y2, width, height, 0, 0); err != nil {
+				log.Printf("⚠️ [%s] Gesture release failed: %v", deviceID, err)
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// SendSwipeGesture swipes over the control socket (coordinates in video frame space)
+// Falls back to `adb shell input swipe` when the control socket isn't connected
+func (s *StreamingService) SendSwipeGesture(deviceID string, x1, y1, x2, y2 int, durationMs int) error {
+	pointer := gesturePointer{id: PointerIDGenericFinger, x1: x1, y1: y1, x2: x2, y2: y2}
+	err := s.runGesture(deviceID, []gesturePointer{pointer}, 0, durationMs)
+	if err == nil {
+		return nil
+	}
+
+	device := s.GetDevice(deviceID)
+	if device == nil {
+		return fmt.Errorf("device not found: %s", deviceID)
+	}
+	if _, displayID := ParseStreamKey(deviceID); displayID != 0 {
+		return err // adb input would land on the main display
+	}
+
+	// scrcpy scales by the frame size sent with each event; adb input takes device pixels
+	if frameW, frameH := s.videoFrameSize(deviceID); frameW > 0 && frameH > 0 {
+		if deviceW, deviceH, ok := parseResolution(device.Resolution); ok {
+			x1, y1 = frameToDevice(x1, y1, frameW, frameH, deviceW, deviceH)
+			x2, y2 = frameToDevice(x2, y2, frameW, frameH, deviceW, deviceH)
+		}
+	}
+
+	log.Printf("⚠️ [%s] Swipe via control socket unavailable (%v), using adb", deviceID, err)
+	return s.deviceManager.GetADBClient().SendSwipe(device.ADBDeviceID, x1, y1, x2, y2, durationMs)
+}
+
+// Pointer IDs for two-finger gestures (stable for the whole gesture)
+const (
+	pinchPointerA uint64 = 0
+	pinchPointerB uint64 = 1
+)
+
+// SendPinch drives two fingers symmetrically around a center point (horizontal axis)
+// startSpread/endSpread are finger distances in video pixels: end > start zooms in, end < start zooms out
+func (s *StreamingService) SendPinch(deviceID string, centerX, centerY, startSpread, endSpread int, durationMs int) error {
+	if startSpread < 0 || endSpread < 0 {
+		return fmt.Errorf("spread must be non-negative")
+	}
+
+	pointers := []gesturePointer{
+		{id: pinchPointerA, x1: centerX - startSpread/2, y1: centerY, x2: centerX - endSpread/2, y2: centerY},
+		{id: pinchPointerB, x1: centerX + startSpread/2, y1: centerY, x2: centerX + endSpread/2, y2: centerY},
+	}
+	return s.runGesture(deviceID, pointers, 0, durationMs)
+}
+
+// SendDrag long-presses at (x1,y1) for holdMs, then drags to (x2,y2) over moveMs
+// Used for reordering icons and other press-and-hold drags (control socket only)
+func (s *StreamingService) SendDrag(deviceID string, x1, y1, x2, y2 int, holdMs, moveMs int) error {
+	if holdMs < 0 || moveMs < 0 {
+		return fmt.Errorf("hold and move durations must be non-negative")
+	}
+
+	pointer := gesturePointer{id: PointerIDGenericFinger, x1: x1, y1: y1, x2: x2, y2: y2}
+	return s.runGesture(deviceID, []gesturePointer{pointer}, holdMs, moveMs)
+}
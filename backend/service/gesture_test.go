@@ -0,0 +1,41 @@
+package service
+
+import "testing"
+
+func TestFrameToDevice(t *testing.T) {
+	tests := []struct {
+		name             string
+		x, y             int
+		frameW, frameH   int
+		deviceW, deviceH int
+		wantX, wantY     int
+	}{
+		{"downscaled portrait", 360, 640, 720, 1280, 1080, 1920, 540, 960},
+		{"same size", 100, 200, 1080, 1920, 1080, 1920, 100, 200},
+		{"rotated frame", 640, 360, 1280, 720, 1080, 1920, 960, 540},
+		{"bottom right corner", 719, 1279, 720, 1280, 1080, 1920, 1078, 1918},
+		{"unknown frame size", 50, 60, 0, 0, 1080, 1920, 50, 60},
+		{"unknown device size", 50, 60, 720, 1280, 0, 0, 50, 60},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			x, y := frameToDevice(tc.x, tc.y, tc.frameW, tc.frameH, tc.deviceW, tc.deviceH)
+			if x != tc.wantX || y != tc.wantY {
+				t.Errorf("frameToDevice = (%d, %d), want (%d, %d)", x, y, tc.wantX, tc.wantY)
+			}
+		})
+	}
+}
+
+func TestVideoFrameSize(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	if w, h := s.videoFrameSize("dev"); w != 0 || h != 0 {
+		t.Errorf("size without a stream = %dx%d, want 0x0", w, h)
+	}
+
+	stream := runningStream(s, "dev", "serial", "")
+	stream.videoWidth, stream.videoHeight = 720, 1280
+	if w, h := s.videoFrameSize("dev"); w != 720 || h != 1280 {
+		t.Errorf("size = %dx%d, want the SPS size 720x1280", w, h)
+	}
+}
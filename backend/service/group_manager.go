@@ -0,0 +1,212 @@
+package service
+
+import (
+	"androidcontrol/models"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// GroupManager keeps named device groups, persisted in SQLite when available
+type GroupManager struct {
+	groups map[string]*models.DeviceGroup
+	mu     sync.RWMutex
+	db     *sql.DB
+}
+
+func NewGroupManager(db *sql.DB) *GroupManager {
+	m := &GroupManager{
+		groups: make(map[string]*models.DeviceGroup),
+		db:     db,
+	}
+
+	if db != nil {
+		if err := m.loadFromDB(); err != nil {
+			log.Printf("⚠️ Failed to load device groups from database: %v", err)
+		}
+	}
+
+	return m
+}
+
+// loadFromDB loads groups and their members
+func (m *GroupManager) loadFromDB() error {
+	rows, err := m.db.Query(`SELECT id, name, COALESCE(description, ''), COALESCE(created_at, 0) FROM device_groups`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for rows.Next() {
+		var g models.DeviceGroup
+		if err := rows.Scan(&g.ID, &g.Name, &g.Description, &g.CreatedAt); err != nil {
+			return err
+		}
+		g.DeviceIDs = []string{}
+		m.groups[g.ID] = &g
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	members, err := m.db.Query(`SELECT group_id, device_id FROM group_devices ORDER BY rowid`)
+	if err != nil {
+		return err
+	}
+	defer members.Close()
+
+	for members.Next() {
+		var groupID, deviceID string
+		if err := members.Scan(&groupID, &deviceID); err != nil {
+			return err
+		}
+		if g, ok := m.groups[groupID]; ok {
+			g.DeviceIDs = append(g.DeviceIDs, deviceID)
+		}
+	}
+
+	log.Printf("💾 Loaded %d device groups from database", len(m.groups))
+	return members.Err()
+}
+
+// persistGroup writes a group and replaces its members (must be called while holding mu)
+func (m *GroupManager) persistGroup(g *models.DeviceGroup) error {
+	if m.db == nil {
+		return nil
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO device_groups (id, name, description, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, description = excluded.description`,
+		g.ID, g.Name, g.Description, g.CreatedAt); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM group_devices WHERE group_id = ?`, g.ID); err != nil {
+		return err
+	}
+	for _, deviceID := range g.DeviceIDs {
+		if _, err := tx.Exec(`INSERT INTO group_devices (group_id, device_id) VALUES (?, ?)`, g.ID, deviceID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// uniqueIDs drops empty and duplicate device IDs, keeping order
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+// GetAllGroups returns all groups ordered by creation time
+func (m *GroupManager) GetAllGroups() []*models.DeviceGroup {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	groups := make([]*models.DeviceGroup, 0, len(m.groups))
+	for _, g := range m.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].CreatedAt != groups[j].CreatedAt {
+			return groups[i].CreatedAt < groups[j].CreatedAt
+		}
+		return groups[i].ID < groups[j].ID
+	})
+	return groups
+}
+
+// GetGroup returns a group by ID
+func (m *GroupManager) GetGroup(id string) *models.DeviceGroup {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.groups[id]
+}
+
+// CreateGroup creates and persists a new group
+func (m *GroupManager) CreateGroup(req models.GroupRequest) (*models.DeviceGroup, error) {
+	g := &models.DeviceGroup{
+		ID:          fmt.Sprintf("group_%d", time.Now().UnixNano()),
+		Name:        req.Name,
+		Description: req.Description,
+		DeviceIDs:   uniqueIDs(req.DeviceIDs),
+		CreatedAt:   time.Now().Unix(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.persistGroup(g); err != nil {
+		return nil, fmt.Errorf("failed to save group: %w", err)
+	}
+	m.groups[g.ID] = g
+	log.Printf("👥 Group created: %s (%d devices)", g.Name, len(g.DeviceIDs))
+	return g, nil
+}
+
+// UpdateGroup replaces a group's name, description and members
+func (m *GroupManager) UpdateGroup(id string, req models.GroupRequest) (*models.DeviceGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.groups[id]
+	if !ok {
+		return nil, fmt.Errorf("group not found: %s", id)
+	}
+
+	updated := &models.DeviceGroup{
+		ID:          existing.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		DeviceIDs:   uniqueIDs(req.DeviceIDs),
+		CreatedAt:   existing.CreatedAt,
+	}
+	if err := m.persistGroup(updated); err != nil {
+		return nil, fmt.Errorf("failed to save group: %w", err)
+	}
+	m.groups[id] = updated
+	return updated, nil
+}
+
+// DeleteGroup removes a group and its memberships
+func (m *GroupManager) DeleteGroup(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.groups[id]; !ok {
+		return fmt.Errorf("group not found: %s", id)
+	}
+
+	if m.db != nil {
+		if _, err := m.db.Exec(`DELETE FROM group_devices WHERE group_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete group: %w", err)
+		}
+		if _, err := m.db.Exec(`DELETE FROM device_groups WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete group: %w", err)
+		}
+	}
+
+	delete(m.groups, id)
+	return nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math/bits"
+)
+
+// DefaultCompareTolerance is the per-channel difference (0-255) still counted as a matching pixel
+// Absorbs anti-aliasing and gradient dithering that differ slightly between renders
+const DefaultCompareTolerance = 8
+
+// DefaultCompareThreshold is the share of matching pixels a screenshot needs to pass
+const DefaultCompareThreshold = 0.99
+
+// ErrImageSize is returned when the baseline and screenshot dimensions differ
+var ErrImageSize = errors.New("image sizes differ")
+
+// CompareResult scores a screenshot against a baseline
+type CompareResult struct {
+	Width        int     `json:"width"`
+	Height       int     `json:"height"`
+	Similarity   float64 `json:"similarity"`    // Share of pixels within tolerance (0-1)
+	DiffPixels   int     `json:"diff_pixels"`   // Pixels beyond tolerance
+	MSE          float64 `json:"mse"`           // Mean squared error over RGB (0-65025)
+	HashDistance int     `json:"hash_distance"` // Differing bits of the 64-bit difference hashes
+}
+
+// CompareImages compares two same-sized images pixel by pixel
+// Also returns a diff image: the baseline faded to gray with differing pixels in red.
+func CompareImages(baseline, current image.Image, tolerance int) (CompareResult, *image.NRGBA, error) {
+	a, b := toNRGBA(baseline), toNRGBA(current)
+	w, h := a.Rect.Dx(), a.Rect.Dy()
+	if w != b.Rect.Dx() || h != b.Rect.Dy() {
+		return CompareResult{}, nil, fmt.Errorf("%w: baseline %dx%d, screenshot %dx%d", ErrImageSize, w, h, b.Rect.Dx(), b.Rect.Dy())
+	}
+
+	diff := image.NewNRGBA(image.Rect(0, 0, w, h))
+	result := CompareResult{Width: w, Height: h}
+	var sumSq float64
+	for y := 0; y < h; y++ {
+		rowA, rowB, rowD := a.Pix[y*a.Stride:], b.Pix[y*b.Stride:], diff.Pix[y*diff.Stride:]
+		for x := 0; x < w*4; x += 4 {
+			maxDelta := 0
+			for c := 0; c < 3; c++ {
+				d := int(rowA[x+c]) - int(rowB[x+c])
+				sumSq += float64(d * d)
+				maxDelta = max(maxDelta, d, -d)
+			}
+			if maxDelta > tolerance {
+				result.DiffPixels++
+				rowD[x], rowD[x+1], rowD[x+2], rowD[x+3] = 255, 0, 0, 255
+				continue
+			}
+			gray := 170 + luma(rowA[x], rowA[x+1], rowA[x+2])/3
+			rowD[x], rowD[x+1], rowD[x+2], rowD[x+3] = gray, gray, gray, 255
+		}
+	}
+
+	pixels := w * h
+	if pixels > 0 {
+		result.Similarity = 1 - float64(result.DiffPixels)/float64(pixels)
+		result.MSE = sumSq / float64(pixels*3)
+	}
+	result.HashDistance = bits.OnesCount64(differenceHash(a) ^ differenceHash(b))
+	return result, diff, nil
+}
+
+// ComparePNG decodes a baseline and a screenshot and compares them
+func ComparePNG(baselinePNG, currentPNG []byte, tolerance int) (CompareResult, *image.NRGBA, error) {
+	baseline, err := png.Decode(bytes.NewReader(baselinePNG))
+	if err != nil {
+		return CompareResult{}, nil, fmt.Errorf("invalid baseline png: %w", err)
+	}
+	current, err := png.Decode(bytes.NewReader(currentPNG))
+	if err != nil {
+		return CompareResult{}, nil, fmt.Errorf("invalid screenshot png: %w", err)
+	}
+	return CompareImages(baseline, current, tolerance)
+}
+
+// differenceHash is a 64-bit dHash: the image shrunk to 9x8 gray cells, one bit per
+// horizontal neighbour pair (left brighter than right). Close hashes mean similar layouts
+// even when every pixel moved slightly, which per-pixel counts don't capture.
+func differenceHash(img *image.NRGBA) uint64 {
+	const cols, rows = 9, 8
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if w < cols || h < rows {
+		return 0
+	}
+
+	var cells [rows][cols]int
+	for r := 0; r < rows; r++ {
+		y0, y1 := r*h/rows, (r+1)*h/rows
+		for c := 0; c < cols; c++ {
+			x0, x1 := c*w/cols, (c+1)*w/cols
+			sum := 0
+			for y := y0; y < y1; y++ {
+				row := img.Pix[y*img.Stride:]
+				for x := x0; x < x1; x++ {
+					sum += int(luma(row[x*4], row[x*4+1], row[x*4+2]))
+				}
+			}
+			cells[r][c] = sum / ((y1 - y0) * (x1 - x0))
+		}
+	}
+
+	var hash uint64
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols-1; c++ {
+			hash <<= 1
+			if cells[r][c] > cells[r][c+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// luma is the Rec. 601 brightness of an RGB pixel
+func luma(r, g, b uint8) uint8 {
+	return uint8((299*int(r) + 587*int(g) + 114*int(b)) / 1000)
+}
+
+// toNRGBA returns img as non-premultiplied RGBA starting at (0,0), converting when needed
+// screencap PNGs decode to *image.NRGBA already, so this is usually free
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok && n.Rect.Min == (image.Point{}) {
+		return n
+	}
+	bounds := img.Bounds()
+	n := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(n, n.Rect, img, bounds.Min, draw.Src)
+	return n
+}
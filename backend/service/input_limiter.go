@@ -0,0 +1,84 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// touchLimiter coalesces touch MOVE events for one device before they hit the control socket
+// DOWN/UP always go through immediately; MOVEs are capped to the rate with the latest position winning,
+// so a fast pointer can't build a backlog of stale positions on a slow link.
+type touchLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	lastMove time.Time
+	pending  map[uint64]func() error // Latest deferred MOVE per pointer
+	timer    *time.Timer             // Fires when the next MOVE slot opens
+}
+
+func newTouchLimiter(maxRate int) *touchLimiter {
+	l := &touchLimiter{pending: make(map[uint64]func() error)}
+	if maxRate > 0 {
+		l.interval = time.Second / time.Duration(maxRate)
+	}
+	return l
+}
+
+// submit sends or defers one touch event; send performs the actual write
+func (l *touchLimiter) submit(action int, pointerID uint64, send func() error) error {
+	if l.interval == 0 {
+		return send()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if action != MotionActionMove {
+		// A deferred MOVE for this pointer is stale once it goes down/up; flush the
+		// other pointers' moves first so the device sees events in order
+		delete(l.pending, pointerID)
+		l.flushLocked()
+		return send()
+	}
+
+	if wait := l.interval - time.Since(l.lastMove); wait > 0 {
+		l.pending[pointerID] = send
+		if l.timer == nil {
+			l.timer = time.AfterFunc(wait, l.flush)
+		}
+		return nil
+	}
+
+	// A MOVE deferred earlier for this pointer is older than this one; sending it
+	// on the next flush would jump the pointer back
+	delete(l.pending, pointerID)
+	l.lastMove = time.Now()
+	return send()
+}
+
+// flush sends deferred MOVEs when the timer fires
+func (l *touchLimiter) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flushLocked()
+}
+
+// flushLocked sends all deferred MOVEs (must hold mu)
+func (l *touchLimiter) flushLocked() {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	if len(l.pending) == 0 {
+		return
+	}
+
+	for pointerID, send := range l.pending {
+		if err := send(); err != nil {
+			log.Printf("⚠️ Deferred touch move failed: %v", err)
+		}
+		delete(l.pending, pointerID)
+	}
+	l.lastMove = time.Now()
+}
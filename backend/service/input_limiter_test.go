@@ -0,0 +1,79 @@
+package service
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sentLog records the order touch events reach the control socket
+type sentLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *sentLog) send(event string) func() error {
+	return func() error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.events = append(l.events, event)
+		return nil
+	}
+}
+
+func (l *sentLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.events)
+}
+
+func TestTouchLimiterDefersMovesWithinInterval(t *testing.T) {
+	var sent sentLog
+	l := newTouchLimiter(10) // One MOVE per 100ms
+
+	l.submit(MotionActionMove, 0, sent.send("move1"))
+	l.submit(MotionActionMove, 0, sent.send("move2"))
+	l.submit(MotionActionMove, 0, sent.send("move3"))
+	if got := sent.get(); !slices.Equal(got, []string{"move1"}) {
+		t.Fatalf("sent before the slot opened = %v, want [move1]", got)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if got := sent.get(); !slices.Equal(got, []string{"move1", "move3"}) {
+		t.Errorf("sent = %v, want only the latest deferred move", got)
+	}
+}
+
+func TestTouchLimiterImmediateMoveDropsStaleDeferredMove(t *testing.T) {
+	var sent sentLog
+	l := newTouchLimiter(10)
+
+	// A MOVE deferred for pointer 0 whose flush is late (e.g. the timer fired behind schedule)
+	l.mu.Lock()
+	l.pending[0] = sent.send("stale")
+	l.lastMove = time.Now().Add(-time.Second)
+	l.mu.Unlock()
+
+	// The slot is open, so the newer MOVE goes out right away
+	l.submit(MotionActionMove, 0, sent.send("fresh"))
+	l.flush()
+
+	if got := sent.get(); !slices.Equal(got, []string{"fresh"}) {
+		t.Errorf("sent = %v, want [fresh]: the stale move must not follow the newer one", got)
+	}
+}
+
+func TestTouchLimiterUpFlushesOtherPointers(t *testing.T) {
+	var sent sentLog
+	l := newTouchLimiter(10)
+
+	l.submit(MotionActionMove, 0, sent.send("move0"))
+	l.submit(MotionActionMove, 1, sent.send("move1"))  // Deferred
+	l.submit(MotionActionMove, 0, sent.send("move0b")) // Deferred, then dropped by UP
+	l.submit(MotionActionUp, 0, sent.send("up0"))
+
+	if got := sent.get(); !slices.Equal(got, []string{"move0", "move1", "up0"}) {
+		t.Errorf("sent = %v, want [move0 move1 up0]", got)
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"log"
+)
+
+// Input ownership: while a client holds a device's control lock, only its
+// input reaches the control socket. Without an owner everyone may send input.
+
+// AcquireControl gives clientID the input lock of a device
+// Returns false if another client already holds it
+func (s *StreamingService) AcquireControl(deviceID, clientID string) bool {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists || clientID == "" {
+		return false
+	}
+
+	stream.mu.Lock()
+	if stream.controlOwner != "" && stream.controlOwner != clientID {
+		stream.mu.Unlock()
+		return false
+	}
+	changed := stream.controlOwner != clientID
+	stream.controlOwner = clientID
+	stream.mu.Unlock()
+
+	if changed {
+		log.Printf("🎮 [%s] Control taken by %s", deviceID, clientID)
+		s.broadcastControlOwner(deviceID, clientID)
+	}
+	return true
+}
+
+// ReleaseControl drops clientID's input lock on a device (no-op if it isn't the owner)
+func (s *StreamingService) ReleaseControl(deviceID, clientID string) {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	stream.mu.Lock()
+	if stream.controlOwner == "" || stream.controlOwner != clientID {
+		stream.mu.Unlock()
+		return
+	}
+	stream.controlOwner = ""
+	stream.mu.Unlock()
+
+	log.Printf("🎮 [%s] Control released by %s", deviceID, clientID)
+	s.broadcastControlOwner(deviceID, "")
+}
+
+// ControlOwner returns the client holding a device's input lock ("" = none)
+func (s *StreamingService) ControlOwner(deviceID string) string {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists {
+		return ""
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	return stream.controlOwner
+}
+
+// CheckControl returns an error if clientID may not send input to a device
+func (s *StreamingService) CheckControl(deviceID, clientID string) error {
+	if owner := s.ControlOwner(deviceID); owner != "" && owner != clientID {
+		return fmt.Errorf("device %s is controlled by %s", deviceID, owner)
+	}
+	return nil
+}
+
+// broadcastControlOwner tells a device's subscribers who holds its input lock
+func (s *StreamingService) broadcastControlOwner(deviceID, owner string) {
+	s.wsHub.BroadcastToDevice(deviceID, map[string]interface{}{
+		"type":      "control_owner",
+		"device_id": deviceID,
+		"owner":     owner,
+	})
+}
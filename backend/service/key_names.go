@@ -0,0 +1,86 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// keyNames maps API key names (AKEYCODE_* without the prefix) to keycodes
+var keyNames = map[string]int{
+	"HOME":         AKEYCODE_HOME,
+	"BACK":         AKEYCODE_BACK,
+	"APP_SWITCH":   AKEYCODE_APP_SWITCH,
+	"MENU":         AKEYCODE_MENU,
+	"SEARCH":       AKEYCODE_SEARCH,
+	"NOTIFICATION": AKEYCODE_NOTIFICATION,
+	"CAMERA":       AKEYCODE_CAMERA,
+	"POWER":        AKEYCODE_POWER,
+	"SLEEP":        AKEYCODE_SLEEP,
+	"WAKEUP":       AKEYCODE_WAKEUP,
+
+	"ENTER":       AKEYCODE_ENTER,
+	"TAB":         AKEYCODE_TAB,
+	"SPACE":       AKEYCODE_SPACE,
+	"DEL":         AKEYCODE_DEL,
+	"FORWARD_DEL": AKEYCODE_FORWARD_DEL,
+	"ESCAPE":      AKEYCODE_ESCAPE,
+	"PAGE_UP":     AKEYCODE_PAGE_UP,
+	"PAGE_DOWN":   AKEYCODE_PAGE_DOWN,
+	"MOVE_HOME":   AKEYCODE_MOVE_HOME,
+	"MOVE_END":    AKEYCODE_MOVE_END,
+
+	"DPAD_UP":     AKEYCODE_DPAD_UP,
+	"DPAD_DOWN":   AKEYCODE_DPAD_DOWN,
+	"DPAD_LEFT":   AKEYCODE_DPAD_LEFT,
+	"DPAD_RIGHT":  AKEYCODE_DPAD_RIGHT,
+	"DPAD_CENTER": AKEYCODE_DPAD_CENTER,
+
+	"VOLUME_UP":       AKEYCODE_VOLUME_UP,
+	"VOLUME_DOWN":     AKEYCODE_VOLUME_DOWN,
+	"VOLUME_MUTE":     AKEYCODE_VOLUME_MUTE,
+	"MUTE":            AKEYCODE_MUTE,
+	"BRIGHTNESS_UP":   AKEYCODE_BRIGHTNESS_UP,
+	"BRIGHTNESS_DOWN": AKEYCODE_BRIGHTNESS_DOWN,
+
+	"MEDIA_PLAY_PAUSE":   AKEYCODE_MEDIA_PLAY_PAUSE,
+	"MEDIA_PLAY":         AKEYCODE_MEDIA_PLAY,
+	"MEDIA_PAUSE":        AKEYCODE_MEDIA_PAUSE,
+	"MEDIA_STOP":         AKEYCODE_MEDIA_STOP,
+	"MEDIA_NEXT":         AKEYCODE_MEDIA_NEXT,
+	"MEDIA_PREVIOUS":     AKEYCODE_MEDIA_PREVIOUS,
+	"MEDIA_REWIND":       AKEYCODE_MEDIA_REWIND,
+	"MEDIA_FAST_FORWARD": AKEYCODE_MEDIA_FAST_FORWARD,
+}
+
+// KeycodeByName resolves a key name ("HOME", "volume_up", "KEYCODE_BACK") to its keycode
+func KeycodeByName(name string) (int, bool) {
+	name = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "KEYCODE_")
+	keycode, ok := keyNames[name]
+	return keycode, ok
+}
+
+// KeyNames returns the supported key names, sorted
+func KeyNames() []string {
+	return slices.Sorted(maps.Keys(keyNames))
+}
+
+// PressKey sends a key press over the control socket when connected, else via
+// `adb shell input keyevent` (slower, ~100ms+ per call). Returns the path used: "control" or "adb"
+func (s *StreamingService) PressKey(deviceID string, keycode int) (string, error) {
+	if s.HasControl(deviceID) {
+		if err := s.sendKeyPress(deviceID, keycode); err == nil {
+			return "control", nil
+		}
+	}
+
+	device := s.GetDevice(deviceID)
+	if device == nil {
+		return "", fmt.Errorf("device not found: %s", deviceID)
+	}
+	if err := s.deviceManager.GetADBClient().SendKey(device.ADBDeviceID, keycode); err != nil {
+		return "", err
+	}
+	return "adb", nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// typeKeyInterval is the pause between typed characters so IMEs and games keep up
+const typeKeyInterval = 15 * time.Millisecond
+
+// Held keys repeat like Android's own (ViewConfiguration key repeat timeout/delay)
+const (
+	keyRepeatTimeout     = 500 * time.Millisecond // Hold before the first repeat
+	keyRepeatInterval    = 50 * time.Millisecond  // Between repeats
+	maxLongPressDuration = 60 * time.Second
+)
+
+// TypeAsKeys types text as real DOWN/UP key events over the control socket
+// For fields that ignore injected text (some password inputs, games). Runs of
+// characters without a US-layout key (Unicode, emoji) fall back to SendText.
+// Blocks until the whole string has been typed.
+func (s *StreamingService) TypeAsKeys(deviceID string, text string) error {
+	client, err := s.controlClient(deviceID)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(text); {
+		stroke, ok := keyStrokeFor(text[i])
+		if !ok {
+			// Collect the whole unmapped run so multi-byte characters stay intact
+			end := i + 1
+			for end < len(text) {
+				if _, mapped := keyStrokeFor(text[end]); mapped {
+					break
+				}
+				end++
+			}
+			if err := s.SendText(deviceID, text[i:end]); err != nil {
+				return err
+			}
+			i = end
+			time.Sleep(typeKeyInterval)
+			continue
+		}
+
+		if err := client.SendKeyEvent(ActionDown, stroke.keycode, 0, stroke.metastate); err != nil {
+			return err
+		}
+		if err := client.SendKeyEvent(ActionUp, stroke.keycode, 0, stroke.metastate); err != nil {
+			return err
+		}
+		i++
+		time.Sleep(typeKeyInterval)
+	}
+	return nil
+}
+
+// SendLongPress holds a key for durationMs: DOWN, repeated DOWNs with an increasing
+// repeat count after keyRepeatTimeout, then UP - the sequence Android delivers for a held key.
+// DOWN is sent synchronously so errors surface to the caller; the rest runs in background.
+func (s *StreamingService) SendLongPress(deviceID string, keycode int, durationMs int) error {
+	duration := time.Duration(durationMs) * time.Millisecond
+	if duration <= 0 || duration > maxLongPressDuration {
+		return fmt.Errorf("duration must be between 1ms and %v", maxLongPressDuration)
+	}
+
+	client, err := s.controlClient(deviceID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.SendKeyEvent(ActionDown, keycode, 0, MetaNone); err != nil {
+		return err
+	}
+
+	go func() {
+		release := time.NewTimer(duration)
+		defer release.Stop()
+
+		next := time.NewTimer(keyRepeatTimeout)
+		defer next.Stop()
+
+		for repeat := 1; ; repeat++ {
+			select {
+			case <-release.C:
+				if err := client.SendKeyEvent(ActionUp, keycode, 0, MetaNone); err != nil {
+					log.Printf("⚠️ [%s] Long press release failed: %v", deviceID, err)
+				}
+				return
+			case <-next.C:
+				if err := client.SendKeyEvent(ActionDown, keycode, repeat, MetaNone); err != nil {
+					log.Printf("⚠️ [%s] Long press aborted: %v", deviceID, err)
+					return
+				}
+				next.Reset(keyRepeatInterval)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// LogcatTopicPrefix marks WebSocket subscriptions for logcat lines (logcat:<deviceID>)
+const LogcatTopicPrefix = "logcat:"
+
+// LogcatTopic returns the subscription key for a device's logcat
+func LogcatTopic(deviceID string) string {
+	return LogcatTopicPrefix + deviceID
+}
+
+// IsTopic reports whether a subscription key is a non-video topic
+// Topics are excluded from the "all" video subscription
+func IsTopic(key string) bool {
+	return strings.HasPrefix(key, LogcatTopicPrefix) || strings.HasPrefix(key, StatsTopicPrefix)
+}
+
+// logcatSession is a running logcat process shared by all its subscribers
+type logcatSession struct {
+	cmd         *exec.Cmd
+	stdout      io.ReadCloser
+	subscribers int
+	filters     []string
+}
+
+// logcatRegistry tracks logcat sessions per device
+type logcatRegistry struct {
+	sessions map[string]*logcatSession
+	mu       sync.Mutex
+}
+
+// StartLogcat adds a logcat subscriber for a device, starting logcat on the first one
+// Lines are broadcast as {type:"logcat", device_id, line} to LogcatTopic(deviceID)
+// nil filters join a running session as is; other filters restart it (see SetLogcatFilters)
+func (s *StreamingService) StartLogcat(deviceID string, filters []string) error {
+	s.logcats.mu.Lock()
+	defer s.logcats.mu.Unlock()
+
+	if session, exists := s.logcats.sessions[deviceID]; exists {
+		if filters != nil && !slices.Equal(filters, session.filters) {
+			restarted, err := s.restartLogcatLocked(deviceID, session, filters)
+			if err != nil {
+				return err
+			}
+			session = restarted
+		}
+		session.subscribers++
+		log.Printf("📜 [%s] Logcat subscriber added (total: %d)", deviceID, session.subscribers)
+		return nil
+	}
+
+	session, err := s.spawnLogcat(deviceID, filters)
+	if err != nil {
+		return err
+	}
+	session.subscribers = 1
+	s.logcats.sessions[deviceID] = session
+	log.Printf("📜 [%s] Logcat started (filters: %v)", deviceID, filters)
+
+	go s.pumpLogcat(deviceID, session)
+	return nil
+}
+
+// SetLogcatFilters restarts a device's running logcat with new filters
+// The session is shared, so every subscriber gets the new filters (announced as
+// {type:"logcat_filters", device_id, filters}); on error the old process keeps running
+func (s *StreamingService) SetLogcatFilters(deviceID string, filters []string) error {
+	s.logcats.mu.Lock()
+	defer s.logcats.mu.Unlock()
+
+	session, exists := s.logcats.sessions[deviceID]
+	if !exists {
+		return fmt.Errorf("logcat not running for device: %s", deviceID)
+	}
+	if slices.Equal(filters, session.filters) {
+		return nil
+	}
+	_, err := s.restartLogcatLocked(deviceID, session, filters)
+	return err
+}
+
+// spawnLogcat starts a logcat process for a device (no subscribers yet)
+func (s *StreamingService) spawnLogcat(deviceID string, filters []string) (*logcatSession, error) {
+	device := s.deviceManager.GetDevice(deviceID)
+	if device == nil {
+		return nil, fmt.Errorf("device not found: %s", deviceID)
+	}
+
+	stdout, cmd, err := s.deviceManager.GetADBClient().StartLogcat(device.ADBDeviceID, filters)
+	if err != nil {
+		return nil, err
+	}
+	return &logcatSession{cmd: cmd, stdout: stdout, filters: filters}, nil
+}
+
+// restartLogcatLocked replaces a session with a logcat running the new filters (must hold logcats.mu)
+// The new process starts before the old one is killed, so a bad filter leaves the session untouched
+func (s *StreamingService) restartLogcatLocked(deviceID string, old *logcatSession, filters []string) (*logcatSession, error) {
+	session, err := s.spawnLogcat(deviceID, filters)
+	if err != nil {
+		return nil, err
+	}
+	session.subscribers = old.subscribers
+	s.logcats.sessions[deviceID] = session // The old pump sees it was replaced and exits quietly
+	killLogcat(old)
+	log.Printf("📜 [%s] Logcat restarted (filters: %v -> %v)", deviceID, old.filters, filters)
+
+	go s.pumpLogcat(deviceID, session)
+	s.wsHub.BroadcastToDevice(LogcatTopic(deviceID), map[string]interface{}{
+		"type":      "logcat_filters",
+		"device_id": deviceID,
+		"filters":   filters,
+	})
+	return session, nil
+}
+
+// StopLogcat removes a logcat subscriber, killing logcat when the last one leaves
+func (s *StreamingService) StopLogcat(deviceID string) {
+	s.logcats.mu.Lock()
+	defer s.logcats.mu.Unlock()
+
+	session, exists := s.logcats.sessions[deviceID]
+	if !exists {
+		return
+	}
+
+	if session.subscribers > 0 {
+		session.subscribers--
+	}
+	log.Printf("📜 [%s] Logcat subscriber removed (remaining: %d)", deviceID, session.subscribers)
+
+	if session.subscribers == 0 {
+		delete(s.logcats.sessions, deviceID)
+		killLogcat(session)
+	}
+}
+
+// StopAllLogcats kills every logcat session regardless of subscribers (shutdown)
+func (s *StreamingService) StopAllLogcats() {
+	s.logcats.mu.Lock()
+	defer s.logcats.mu.Unlock()
+
+	for deviceID, session := range s.logcats.sessions {
+		delete(s.logcats.sessions, deviceID)
+		killLogcat(session)
+	}
+}
+
+// pumpLogcat reads logcat lines and broadcasts them until the process exits
+func (s *StreamingService) pumpLogcat(deviceID string, session *logcatSession) {
+	topic := LogcatTopic(deviceID)
+	scanner := bufio.NewScanner(session.stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		s.wsHub.BroadcastToDevice(topic, map[string]interface{}{
+			"type":      "logcat",
+			"device_id": deviceID,
+			"line":      scanner.Text(),
+		})
+	}
+
+	// Process ended on its own (device gone) - drop the session if it's still ours
+	s.logcats.mu.Lock()
+	if s.logcats.sessions[deviceID] == session {
+		delete(s.logcats.sessions, deviceID)
+		log.Printf("⚠️ [%s] Logcat ended unexpectedly", deviceID)
+		killLogcat(session)
+	}
+	s.logcats.mu.Unlock()
+}
+
+// killLogcat terminates the logcat process
+func killLogcat(session *logcatSession) {
+	if session.cmd != nil && session.cmd.Process != nil {
+		session.cmd.Process.Kill()
+		go session.cmd.Wait() // Reap without blocking the registry lock
+	}
+}
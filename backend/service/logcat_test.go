@@ -0,0 +1,115 @@
+package service
+
+import (
+	"androidcontrol/models"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHub captures broadcasts instead of sending them
+type recordingHub struct {
+	mu       sync.Mutex
+	messages []map[string]interface{}
+}
+
+func (h *recordingHub) BroadcastToDevice(_ string, message interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if m, ok := message.(map[string]interface{}); ok {
+		h.messages = append(h.messages, m)
+	}
+}
+
+// waitFor polls the captured broadcasts until one matches
+func (h *recordingHub) waitFor(t *testing.T, match func(map[string]interface{}) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		h.mu.Lock()
+		found := slices.ContainsFunc(h.messages, match)
+		h.mu.Unlock()
+		if found {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("expected broadcast never arrived")
+}
+
+// logcatLine matches a logcat line ending with the given args (the fake adb echoes its argv)
+func logcatLine(args string) func(map[string]interface{}) bool {
+	return func(m map[string]interface{}) bool {
+		line, _ := m["line"].(string)
+		return m["type"] == "logcat" && strings.HasSuffix(line, "logcat "+args)
+	}
+}
+
+func TestSetLogcatFiltersRestartsLogcat(t *testing.T) {
+	// A fake adb that prints its argv as the first logcat line, then idles like logcat
+	fakeADB := filepath.Join(t.TempDir(), "adb")
+	if err := os.WriteFile(fakeADB, []byte("#!/bin/sh\necho \"$@\"\nexec sleep 30\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	hub := &recordingHub{}
+	dm := NewDeviceManager(nil)
+	dm.devices["dev1"] = &models.Device{ID: "dev1", ADBDeviceID: "dev1-serial", Status: models.DeviceStatusOnline}
+	dm.GetADBClient().ADBPath = fakeADB
+	s := NewStreamingService(dm, hub)
+	defer s.StopAllLogcats()
+
+	if err := s.StartLogcat("dev1", []string{"*:E"}); err != nil {
+		t.Fatalf("StartLogcat: %v", err)
+	}
+	hub.waitFor(t, logcatLine("*:E"))
+
+	session := func() *logcatSession {
+		s.logcats.mu.Lock()
+		defer s.logcats.mu.Unlock()
+		return s.logcats.sessions["dev1"]
+	}
+	first := session()
+
+	// A filter adb would reject leaves the running process alone
+	if err := s.SetLogcatFilters("dev1", []string{"*:E; reboot"}); err == nil {
+		t.Error("invalid filter accepted")
+	}
+	if session() != first {
+		t.Error("failed update replaced the session")
+	}
+
+	newFilters := []string{"ActivityManager:I", "*:S"}
+	if err := s.SetLogcatFilters("dev1", newFilters); err != nil {
+		t.Fatalf("SetLogcatFilters: %v", err)
+	}
+	hub.waitFor(t, logcatLine("ActivityManager:I *:S"))
+	hub.waitFor(t, func(m map[string]interface{}) bool {
+		filters, _ := m["filters"].([]string)
+		return m["type"] == "logcat_filters" && slices.Equal(filters, newFilters)
+	})
+
+	// Joining without filters keeps the new ones; the killed process didn't take the session down
+	if err := s.StartLogcat("dev1", nil); err != nil {
+		t.Fatalf("StartLogcat join: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	got := session()
+	if got == nil || got == first {
+		t.Fatalf("session after restart = %p, want a new one (old %p)", got, first)
+	}
+	if got.subscribers != 2 || !slices.Equal(got.filters, newFilters) {
+		t.Errorf("session = %d subscribers, filters %v; want 2, %v", got.subscribers, got.filters, newFilters)
+	}
+}
+
+func TestSetLogcatFiltersWithoutSession(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	if err := s.SetLogcatFilters("dev1", []string{"*:E"}); err == nil {
+		t.Error("SetLogcatFilters without a running logcat succeeded")
+	}
+}
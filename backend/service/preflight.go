@@ -0,0 +1,82 @@
+package service
+
+import (
+	"androidcontrol/adb"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+)
+
+// ErrUnavailable marks features disabled because a dependency was missing at startup
+var ErrUnavailable = errors.New("feature unavailable")
+
+// Capabilities records which external dependencies the startup preflight found
+type Capabilities struct {
+	ADB              bool     `json:"adb"`
+	ADBVersion       string   `json:"adb_version,omitempty"`
+	ScrcpyServer     bool     `json:"scrcpy_server"`
+	ScrcpyServerPath string   `json:"scrcpy_server_path"`
+	FFmpeg           bool     `json:"ffmpeg"`
+	Streaming        bool     `json:"streaming"` // adb + scrcpy-server
+	Recording        bool     `json:"recording"` // streaming + ffmpeg
+	Problems         []string `json:"problems,omitempty"`
+}
+
+// Preflight checks for adb, the scrcpy-server jar and ffmpeg, logging how to fix what's missing
+func Preflight(adbClient *adb.ADBClient, server ServerConfig) Capabilities {
+	caps := Capabilities{ScrcpyServerPath: server.JarPath}
+	problem := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		caps.Problems = append(caps.Problems, msg)
+		log.Printf("⚠️ Preflight: %s", msg)
+	}
+
+	if version, err := adbClient.Version(); err != nil {
+		problem("adb not usable (%v) - install platform-tools and put adb in PATH; device control and streaming are disabled", err)
+	} else {
+		caps.ADB = true
+		caps.ADBVersion = version
+	}
+
+	if info, err := os.Stat(server.JarPath); err != nil || info.IsDir() {
+		problem("scrcpy-server %s not found at %s - download it or set SCRCPY_SERVER_PATH; streaming is disabled", server.Version, server.JarPath)
+	} else {
+		caps.ScrcpyServer = true
+	}
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		problem("ffmpeg not found in PATH - recording is disabled and snapshots fall back to screencap")
+	} else {
+		caps.FFmpeg = true
+	}
+
+	caps.Streaming = caps.ADB && caps.ScrcpyServer
+	caps.Recording = caps.Streaming && caps.FFmpeg
+	if len(caps.Problems) == 0 {
+		log.Printf("✅ Preflight: %s, scrcpy-server %s, ffmpeg found", caps.ADBVersion, server.Version)
+	}
+	return caps
+}
+
+// SetCapabilities stores the preflight result; features it marks missing return ErrUnavailable
+func (s *StreamingService) SetCapabilities(caps Capabilities) {
+	s.caps.Store(&caps)
+}
+
+// Capabilities returns the preflight result (everything available if no preflight ran)
+func (s *StreamingService) Capabilities() Capabilities {
+	if caps := s.caps.Load(); caps != nil {
+		return *caps
+	}
+	return Capabilities{ADB: true, ScrcpyServer: true, FFmpeg: true, Streaming: true, Recording: true}
+}
+
+// requireStreaming returns ErrUnavailable when adb or the scrcpy-server jar is missing
+func (s *StreamingService) requireStreaming() error {
+	if !s.Capabilities().Streaming {
+		return fmt.Errorf("%w: streaming needs adb and scrcpy-server (see GET /api/capabilities)", ErrUnavailable)
+	}
+	return nil
+}
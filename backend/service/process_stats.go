@@ -0,0 +1,143 @@
+package service
+
+import (
+	"androidcontrol/models"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsTopicPrefix marks WebSocket subscriptions for app CPU/memory samples (stats:<deviceID>:<pkg>)
+const StatsTopicPrefix = "stats:"
+
+// statsInterval is how often a watched app is sampled (top + dumpsys take ~0.5s each)
+const statsInterval = 2 * time.Second
+
+// StatsTopic returns the subscription key for an app's samples on a device
+func StatsTopic(deviceID, pkg string) string {
+	return StatsTopicPrefix + deviceID + ":" + pkg
+}
+
+// ParseStatsTopic splits stats:<deviceID>:<pkg> (device IDs may contain ':', packages don't)
+func ParseStatsTopic(key string) (deviceID, pkg string, ok bool) {
+	rest, found := strings.CutPrefix(key, StatsTopicPrefix)
+	if !found {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, ":")
+	if i <= 0 || i == len(rest)-1 {
+		return "", "", false
+	}
+	return rest[:i], rest[i+1:], true
+}
+
+// statsSampler polls one app on one device while it has subscribers
+type statsSampler struct {
+	subscribers int
+	stop        chan struct{}
+}
+
+// statsRegistry tracks samplers per stats topic
+type statsRegistry struct {
+	samplers map[string]*statsSampler
+	mu       sync.Mutex
+}
+
+// GetProcessStats takes one CPU/memory sample of an app
+func (s *StreamingService) GetProcessStats(deviceID, pkg string) (models.ProcessStats, error) {
+	stats := models.ProcessStats{DeviceID: deviceID, Package: pkg}
+	device := s.deviceManager.GetDevice(deviceID)
+	if device == nil {
+		return stats, fmt.Errorf("device not found: %s", deviceID)
+	}
+
+	cpu, mem, err := s.deviceManager.GetADBClient().GetProcessStats(device.ADBDeviceID, pkg)
+	stats.Timestamp = time.Now().UnixMilli()
+	if err != nil {
+		return stats, err
+	}
+	stats.CPUPercent = cpu
+	stats.MemKB = mem
+	return stats, nil
+}
+
+// StartStatsSampling adds a subscriber for an app's samples, starting the poller on the first one
+// Samples are broadcast as {type:"process_stats", device_id, stats} to StatsTopic(deviceID, pkg)
+func (s *StreamingService) StartStatsSampling(deviceID, pkg string) error {
+	if s.deviceManager.GetDevice(deviceID) == nil {
+		return fmt.Errorf("device not found: %s", deviceID)
+	}
+
+	topic := StatsTopic(deviceID, pkg)
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	if sampler, exists := s.stats.samplers[topic]; exists {
+		sampler.subscribers++
+		return nil
+	}
+
+	sampler := &statsSampler{subscribers: 1, stop: make(chan struct{})}
+	s.stats.samplers[topic] = sampler
+	log.Printf("📈 [%s] Sampling %s every %v", deviceID, pkg, statsInterval)
+
+	go s.pollStats(deviceID, pkg, topic, sampler.stop)
+	return nil
+}
+
+// StopStatsSampling removes a subscriber, stopping the poller when the last one leaves
+func (s *StreamingService) StopStatsSampling(deviceID, pkg string) {
+	topic := StatsTopic(deviceID, pkg)
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	sampler, exists := s.stats.samplers[topic]
+	if !exists {
+		return
+	}
+	if sampler.subscribers > 0 {
+		sampler.subscribers--
+	}
+	if sampler.subscribers == 0 {
+		delete(s.stats.samplers, topic)
+		close(sampler.stop)
+		log.Printf("📈 [%s] Stopped sampling %s", deviceID, pkg)
+	}
+}
+
+// StopAllStatsSampling stops every poller regardless of subscribers (shutdown)
+func (s *StreamingService) StopAllStatsSampling() {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	for topic, sampler := range s.stats.samplers {
+		delete(s.stats.samplers, topic)
+		close(sampler.stop)
+	}
+}
+
+// pollStats samples an app until stopped; failed samples carry an error instead of values
+func (s *StreamingService) pollStats(deviceID, pkg, topic string, stop <-chan struct{}) {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	for {
+		stats, err := s.GetProcessStats(deviceID, pkg)
+		if err != nil {
+			stats.Error = err.Error()
+		}
+		s.wsHub.BroadcastToDevice(topic, map[string]interface{}{
+			"type":      "process_stats",
+			"device_id": deviceID,
+			"stats":     stats,
+		})
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
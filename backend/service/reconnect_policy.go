@@ -0,0 +1,124 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// maxReconnectAttempts bounds MaxAttempts (a stream retrying forever should lean on the cap instead)
+const maxReconnectAttempts = 1000
+
+// ReconnectPolicy controls how runStream retries scrcpy after a failed start or a session
+// that died within seconds. Retry n waits BaseBackoff * Multiplier^(n-1), capped at MaxBackoff;
+// after MaxAttempts retries the stream falls back to screenrecord.
+type ReconnectPolicy struct {
+	MaxAttempts int           // Retries before giving up on scrcpy (0 = fail fast)
+	BaseBackoff time.Duration // Wait before the first retry
+	Multiplier  float64       // Growth of the wait per further retry (1 = constant)
+	MaxBackoff  time.Duration // Cap on any single wait (0 = uncapped)
+}
+
+// DefaultReconnectPolicy retries 3 times after 2s, 4s and 8s
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: 2 * time.Second,
+		Multiplier:  2,
+		MaxBackoff:  30 * time.Second,
+	}
+}
+
+// Validate rejects policies runStream can't follow
+func (p ReconnectPolicy) Validate() error {
+	switch {
+	case p.MaxAttempts < 0 || p.MaxAttempts > maxReconnectAttempts:
+		return fmt.Errorf("max attempts must be 0-%d", maxReconnectAttempts)
+	case p.BaseBackoff < 0 || p.MaxBackoff < 0:
+		return fmt.Errorf("backoff must be >= 0")
+	case p.Multiplier < 1 || math.IsInf(p.Multiplier, 0) || math.IsNaN(p.Multiplier):
+		return fmt.Errorf("multiplier must be >= 1")
+	}
+	return nil
+}
+
+// Backoff returns the wait before retry attempt n (1-based)
+func (p ReconnectPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	wait := float64(p.BaseBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	if wait > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(wait)
+}
+
+// Schedule lists the waits of every retry, for logs and the API
+func (p ReconnectPolicy) Schedule() []time.Duration {
+	waits := make([]time.Duration, p.MaxAttempts)
+	for i := range waits {
+		waits[i] = p.Backoff(i + 1)
+	}
+	return waits
+}
+
+// SetReconnectPolicy sets the reconnect policy for streams without their own
+func (s *StreamingService) SetReconnectPolicy(policy ReconnectPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reconnect = policy
+	return nil
+}
+
+// SetDeviceReconnectPolicy overrides the reconnect policy for one stream; nil restores the default
+// Takes effect at the stream's next retry
+func (s *StreamingService) SetDeviceReconnectPolicy(deviceID string, policy *ReconnectPolicy) error {
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+	stream, err := s.getOrCreateStream(deviceID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	stream.reconnect = policy
+	return nil
+}
+
+// ReconnectPolicyFor returns the policy a stream retries with (its override or the default)
+func (s *StreamingService) ReconnectPolicyFor(deviceID string) ReconnectPolicy {
+	s.mu.RLock()
+	stream := s.streams[deviceID]
+	policy := s.reconnect
+	s.mu.RUnlock()
+	if stream == nil {
+		return policy
+	}
+	return s.reconnectPolicy(stream)
+}
+
+// reconnectPolicy resolves a stream's policy (must not hold stream.mu)
+func (s *StreamingService) reconnectPolicy(stream *deviceStream) ReconnectPolicy {
+	s.mu.RLock()
+	policy := s.reconnect
+	s.mu.RUnlock()
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if stream.reconnect != nil {
+		policy = *stream.reconnect
+	}
+	return policy
+}
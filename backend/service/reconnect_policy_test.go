@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDefaultReconnectSchedule(t *testing.T) {
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second}
+	if got := DefaultReconnectPolicy().Schedule(); !slices.Equal(got, want) {
+		t.Errorf("Schedule() = %v, want %v", got, want)
+	}
+	if err := DefaultReconnectPolicy().Validate(); err != nil {
+		t.Errorf("default policy invalid: %v", err)
+	}
+}
+
+func TestReconnectScheduleHitsCap(t *testing.T) {
+	policy := ReconnectPolicy{MaxAttempts: 6, BaseBackoff: 500 * time.Millisecond, Multiplier: 3, MaxBackoff: 10 * time.Second}
+	want := []time.Duration{
+		500 * time.Millisecond,
+		1500 * time.Millisecond,
+		4500 * time.Millisecond,
+		10 * time.Second, // 13.5s capped
+		10 * time.Second,
+		10 * time.Second,
+	}
+	if got := policy.Schedule(); !slices.Equal(got, want) {
+		t.Errorf("Schedule() = %v, want %v", got, want)
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ReconnectPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"constant", ReconnectPolicy{BaseBackoff: time.Second, Multiplier: 1}, 5, time.Second},
+		{"attempt below 1", ReconnectPolicy{BaseBackoff: time.Second, Multiplier: 2}, 0, time.Second},
+		{"uncapped", ReconnectPolicy{BaseBackoff: time.Second, Multiplier: 2}, 7, 64 * time.Second},
+		{"overflow saturates", ReconnectPolicy{BaseBackoff: time.Hour, Multiplier: 10}, 1000, time.Duration(math.MaxInt64)},
+		{"overflow capped", ReconnectPolicy{BaseBackoff: time.Hour, Multiplier: 10, MaxBackoff: time.Minute}, 1000, time.Minute},
+		{"zero base", ReconnectPolicy{Multiplier: 2}, 3, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.policy.Backoff(tc.attempt); got != tc.want {
+				t.Errorf("Backoff(%d) = %v, want %v", tc.attempt, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReconnectPolicyValidate(t *testing.T) {
+	valid := DefaultReconnectPolicy()
+	tests := []struct {
+		name   string
+		modify func(p *ReconnectPolicy)
+		ok     bool
+	}{
+		{"default", func(p *ReconnectPolicy) {}, true},
+		{"zero attempts fails fast", func(p *ReconnectPolicy) { p.MaxAttempts = 0 }, true},
+		{"constant backoff", func(p *ReconnectPolicy) { p.Multiplier = 1 }, true},
+		{"negative attempts", func(p *ReconnectPolicy) { p.MaxAttempts = -1 }, false},
+		{"too many attempts", func(p *ReconnectPolicy) { p.MaxAttempts = maxReconnectAttempts + 1 }, false},
+		{"multiplier below 1", func(p *ReconnectPolicy) { p.Multiplier = 0.5 }, false},
+		{"zero multiplier", func(p *ReconnectPolicy) { p.Multiplier = 0 }, false},
+		{"NaN multiplier", func(p *ReconnectPolicy) { p.Multiplier = math.NaN() }, false},
+		{"infinite multiplier", func(p *ReconnectPolicy) { p.Multiplier = math.Inf(1) }, false},
+		{"negative base", func(p *ReconnectPolicy) { p.BaseBackoff = -time.Second }, false},
+		{"negative cap", func(p *ReconnectPolicy) { p.MaxBackoff = -time.Second }, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := valid
+			tc.modify(&policy)
+			if err := policy.Validate(); (err == nil) != tc.ok {
+				t.Errorf("Validate(%+v) = %v, want ok=%t", policy, err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestRunStreamRetriesWithBackoff(t *testing.T) {
+	// Every scrcpy start fails at once: the server jar is missing and adb doesn't exist
+	t.Setenv("SCRCPY_SERVER_PATH", "/nonexistent/scrcpy-server")
+	dm := NewDeviceManager(nil)
+	dm.GetADBClient().ADBPath = "/nonexistent/adb"
+
+	var sleeps []time.Duration
+	orig := retryTimer
+	retryTimer = func(d time.Duration) *time.Timer {
+		sleeps = append(sleeps, d)
+		return time.NewTimer(0)
+	}
+	t.Cleanup(func() { retryTimer = orig })
+
+	s := NewStreamingService(dm, nil)
+	policy := ReconnectPolicy{MaxAttempts: 4, BaseBackoff: time.Second, Multiplier: 2, MaxBackoff: 5 * time.Second}
+
+	// A secondary display stream: screenrecord can't capture it, so runStream gives up after the retries
+	key := StreamKey("dev1", 1)
+	stream := newDeviceStream(key, "dev1-serial")
+	stream.state = StateStarting
+	stream.devCtx, stream.devCancel = context.WithCancel(context.Background())
+	stream.reconnect = &policy
+	stream.scrcpyClient = s.newScrcpyClient(stream)
+	s.streams[key] = stream
+
+	done := make(chan struct{})
+	go func() {
+		s.runStream(stream)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runStream didn't give up")
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	if !slices.Equal(sleeps, want) {
+		t.Errorf("retry sleeps = %v, want %v", sleeps, want)
+	}
+	if stream.reconnectAttempts != policy.MaxAttempts {
+		t.Errorf("reconnect attempts = %d, want %d", stream.reconnectAttempts, policy.MaxAttempts)
+	}
+	if stream.state != StateStopped {
+		t.Errorf("state = %s, want %s", stream.state, StateStopped)
+	}
+}
+
+func TestWaitRetryReturnsWhenStopped(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	stream := newDeviceStream("dev1", "dev1-serial")
+	stream.state = StateStarting
+	stream.devCtx, stream.devCancel = context.WithCancel(context.Background())
+	s.streams["dev1"] = stream
+
+	policy := ReconnectPolicy{MaxAttempts: 1, BaseBackoff: time.Minute, Multiplier: 1}
+	result := make(chan error, 1)
+	start := time.Now()
+	go func() { result <- s.waitRetry(stream, policy, 1) }()
+
+	time.Sleep(20 * time.Millisecond)
+	if err := s.StopStreaming("dev1"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("waitRetry = %v, want context.Canceled", err)
+		}
+		if waited := time.Since(start); waited > time.Second {
+			t.Errorf("waitRetry returned after %v", waited)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("waitRetry kept waiting after StopStreaming")
+	}
+}
+
+func TestWaitRetryWaitsBackoff(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	stream := newDeviceStream("dev1", "dev1-serial")
+	stream.devCtx, stream.devCancel = context.WithCancel(context.Background())
+	defer stream.devCancel()
+
+	policy := ReconnectPolicy{MaxAttempts: 1, BaseBackoff: 30 * time.Millisecond, Multiplier: 1}
+	start := time.Now()
+	if err := s.waitRetry(stream, policy, 1); err != nil {
+		t.Fatalf("waitRetry = %v", err)
+	}
+	if waited := time.Since(start); waited < 30*time.Millisecond {
+		t.Errorf("waitRetry returned after %v, before the backoff", waited)
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordQueueSize bounds NALs waiting for ffmpeg; the stream never blocks on a slow recorder
+const recordQueueSize = 512
+
+// recorder muxes the live Annex-B stream into an MP4 file via ffmpeg
+// NALs are queued from broadcastNAL and written by a single goroutine
+type recorder struct {
+	path      string
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	queue     chan []byte
+	done      chan struct{} // Closed when the writer goroutine exits
+	startedAt time.Time
+
+	mu     sync.Mutex // Guards closing queue against concurrent enqueue
+	closed bool
+}
+
+// mp4Path gives a recording path the .mp4 extension, so a name like "demo" or "demo.v2"
+// is still saved as (and recognisable as) an MP4
+func mp4Path(outputPath string) string {
+	if strings.EqualFold(filepath.Ext(outputPath), ".mp4") {
+		return outputPath
+	}
+	return outputPath + ".mp4"
+}
+
+// StartRecording tees the live stream of a RUNNING device into an MP4 file
+// Returns the path actually written (with the .mp4 extension added if missing)
+func (s *StreamingService) StartRecording(deviceID, outputPath string) (string, error) {
+	if !s.Capabilities().Recording {
+		return "", fmt.Errorf("%w: recording needs ffmpeg in PATH (see GET /api/capabilities)", ErrUnavailable)
+	}
+
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("stream not found for device: %s", deviceID)
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.state != StateRunning {
+		return "", fmt.Errorf("stream is not running (state=%s)", stream.state)
+	}
+	if stream.recorder.Load() != nil {
+		return "", fmt.Errorf("already recording device: %s", deviceID)
+	}
+
+	outputPath = mp4Path(outputPath)
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	// Raw Annex-B in, stream copy out - wallclock timestamps since scrcpy frames are variable-rate
+	// The muxer is pinned to mp4 rather than guessed from the file name
+	inputFormat := "h264"
+	if stream.codec == CodecH265 {
+		inputFormat = "hevc"
+	}
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-use_wallclock_as_timestamps", "1",
+		"-f", inputFormat, "-i", "-",
+		"-c", "copy", "-f", "mp4", "-y", outputPath)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to create ffmpeg stdin pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	rec := &recorder{
+		path:      outputPath,
+		cmd:       cmd,
+		stdin:     stdin,
+		queue:     make(chan []byte, recordQueueSize),
+		done:      make(chan struct{}),
+		startedAt: time.Now(),
+	}
+
+	// Seed with cached headers + IDR so the file starts decodable
+	for _, cached := range [][]byte{stream.vpsPkt, stream.spsPkt, stream.ppsPkt, stream.lastIDRPkt} {
+		if nal := stripPacketPrefix(cached); nal != nil {
+			rec.queue <- nal
+		}
+	}
+
+	go rec.writeLoop(deviceID)
+	stream.recorder.Store(rec)
+
+	log.Printf("⏺️ [%s] Recording started: %s", deviceID, outputPath)
+	return outputPath, nil
+}
+
+// StopRecording finalizes the MP4 file and returns its path
+func (s *StreamingService) StopRecording(deviceID string) (string, error) {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("stream not found for device: %s", deviceID)
+	}
+
+	rec := stream.recorder.Swap(nil)
+	if rec == nil {
+		return "", fmt.Errorf("not recording device: %s", deviceID)
+	}
+
+	if err := rec.finish(deviceID); err != nil {
+		return rec.path, err
+	}
+	return rec.path, nil
+}
+
+// recordNAL queues a raw NAL for the active recorder, if any
+func (stream *deviceStream) recordNAL(nalData []byte) {
+	rec := stream.recorder.Load()
+	if rec == nil {
+		return
+	}
+
+	nal := make([]byte, len(nalData))
+	copy(nal, nalData)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.closed {
+		return
+	}
+
+	select {
+	case rec.queue <- nal:
+	default:
+		log.Printf("⚠️ [%s] Recorder queue full, dropping NAL", stream.deviceID)
+	}
+}
+
+// stopRecordingOnExit finalizes a recording when the stream goroutine ends
+func (stream *deviceStream) stopRecordingOnExit() {
+	if rec := stream.recorder.Swap(nil); rec != nil {
+		log.Printf("⏹️ [%s] Stream ended, finalizing recording", stream.deviceID)
+		rec.finish(stream.deviceID)
+	}
+}
+
+// writeLoop drains the queue into ffmpeg until the queue is closed
+func (r *recorder) writeLoop(deviceID string) {
+	defer close(r.done)
+
+	failed := false
+	for nal := range r.queue {
+		if failed {
+			continue // Keep draining so senders never block
+		}
+		if _, err := r.stdin.Write(nal); err != nil {
+			log.Printf("❌ [%s] Recorder write failed: %v", deviceID, err)
+			failed = true
+		}
+	}
+}
+
+// finish closes ffmpeg's input and waits for it to write the MP4 trailer
+func (r *recorder) finish(deviceID string) error {
+	r.mu.Lock()
+	r.closed = true
+	close(r.queue)
+	r.mu.Unlock()
+
+	<-r.done
+	r.stdin.Close()
+
+	waitDone := make(chan error, 1)
+	go func() { waitDone <- r.cmd.Wait() }()
+
+	select {
+	case err := <-waitDone:
+		if err != nil {
+			log.Printf("⚠️ [%s] ffmpeg exited with error: %v", deviceID, err)
+			return fmt.Errorf("ffmpeg failed: %w", err)
+		}
+	case <-time.After(10 * time.Second):
+		r.cmd.Process.Kill()
+		<-waitDone
+		return fmt.Errorf("ffmpeg did not finish in time, recording may be incomplete")
+	}
+
+	log.Printf("💾 [%s] Recording saved: %s (%v)", deviceID, r.path, time.Since(r.startedAt).Round(time.Second))
+	return nil
+}
+
+// stripPacketPrefix removes the WebSocket frame header from a cached packet
+func stripPacketPrefix(pkt []byte) []byte {
+	_, payload, err := DecodeFrame(pkt)
+	if err != nil || len(payload) == 0 {
+		return nil
+	}
+	return payload
+}
@@ -0,0 +1,21 @@
+package service
+
+import "testing"
+
+func TestMP4Path(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"recordings/demo.mp4", "recordings/demo.mp4"},
+		{"recordings/demo.MP4", "recordings/demo.MP4"},
+		{"recordings/demo", "recordings/demo.mp4"},
+		{"recordings/demo.v2", "recordings/demo.v2.mp4"},
+		{"recordings/demo.mkv", "recordings/demo.mkv.mp4"},
+		{"recordings/device_192.168.1.5_5555", "recordings/device_192.168.1.5_5555.mp4"},
+	}
+	for _, tc := range tests {
+		if got := mp4Path(tc.in); got != tc.want {
+			t.Errorf("mp4Path(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"androidcontrol/adb"
+	"log"
+	"sync"
+)
+
+// reverseTunnels tracks adb reverse tunnels per device so they can be torn down together
+// (the reverse counterpart of ScrcpyClient's forward, which cleanup() removes)
+type reverseTunnels struct {
+	adbClient *adb.ADBClient
+	tunnels   map[string]map[string]int // ADB device ID -> remote socket -> local port
+	mu        sync.Mutex
+}
+
+func newReverseTunnels(adbClient *adb.ADBClient) *reverseTunnels {
+	return &reverseTunnels{
+		adbClient: adbClient,
+		tunnels:   make(map[string]map[string]int),
+	}
+}
+
+// add creates a reverse tunnel and records it
+func (r *reverseTunnels) add(deviceADBID, remoteSocket string, localPort int) error {
+	if err := r.adbClient.Reverse(deviceADBID, remoteSocket, localPort); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tunnels[deviceADBID] == nil {
+		r.tunnels[deviceADBID] = make(map[string]int)
+	}
+	r.tunnels[deviceADBID][remoteSocket] = localPort
+	log.Printf("🔁 [%s] Reverse established: localabstract:%s -> tcp:%d", deviceADBID, remoteSocket, localPort)
+	return nil
+}
+
+// remove tears down one reverse tunnel
+func (r *reverseTunnels) remove(deviceADBID, remoteSocket string) error {
+	r.mu.Lock()
+	delete(r.tunnels[deviceADBID], remoteSocket)
+	r.mu.Unlock()
+
+	return r.adbClient.RemoveReverse(deviceADBID, remoteSocket)
+}
+
+// cleanup removes all reverse tunnels for a device (best effort - the device may be gone)
+func (r *reverseTunnels) cleanup(deviceADBID string) {
+	r.mu.Lock()
+	sockets := r.tunnels[deviceADBID]
+	delete(r.tunnels, deviceADBID)
+	r.mu.Unlock()
+
+	for socket := range sockets {
+		log.Printf("🔌 [%s] Removing reverse localabstract:%s...", deviceADBID, socket)
+		if err := r.adbClient.RemoveReverse(deviceADBID, socket); err != nil {
+			log.Printf("⚠️ [%s] Failed to remove reverse: %v", deviceADBID, err)
+		}
+	}
+}
+
+// cleanupAll removes every tracked reverse tunnel
+func (r *reverseTunnels) cleanupAll() {
+	r.mu.Lock()
+	ids := make([]string, 0, len(r.tunnels))
+	for id := range r.tunnels {
+		ids = append(ids, id)
+	}
+	r.mu.Unlock()
+
+	for _, id := range ids {
+		r.cleanup(id)
+	}
+}
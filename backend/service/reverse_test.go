@@ -0,0 +1,73 @@
+package service
+
+import (
+	"androidcontrol/models"
+	"testing"
+)
+
+// reverseManager returns a manager with one online device whose adb calls always succeed
+func reverseManager(t *testing.T) *DeviceManager {
+	t.Helper()
+	dm := NewDeviceManager(nil)
+	dm.GetADBClient().ADBPath = "true"
+	dm.devices["dev1"] = &models.Device{ID: "dev1", ADBDeviceID: "dev1-serial", Status: models.DeviceStatusOnline}
+	return dm
+}
+
+func tunnelPorts(dm *DeviceManager, adbID string) map[string]int {
+	dm.reverses.mu.Lock()
+	defer dm.reverses.mu.Unlock()
+	ports := make(map[string]int)
+	for socket, port := range dm.reverses.tunnels[adbID] {
+		ports[socket] = port
+	}
+	return ports
+}
+
+func TestReverseAddRemove(t *testing.T) {
+	dm := reverseManager(t)
+
+	if err := dm.AddReverse("dev1", "agent", 8080); err != nil {
+		t.Fatalf("AddReverse: %v", err)
+	}
+	if err := dm.AddReverse("dev1", "metrics", 9090); err != nil {
+		t.Fatalf("AddReverse: %v", err)
+	}
+	if got := tunnelPorts(dm, "dev1-serial"); len(got) != 2 || got["agent"] != 8080 || got["metrics"] != 9090 {
+		t.Fatalf("tracked tunnels = %v", got)
+	}
+
+	if err := dm.RemoveReverse("dev1", "agent"); err != nil {
+		t.Fatalf("RemoveReverse: %v", err)
+	}
+	if got := tunnelPorts(dm, "dev1-serial"); len(got) != 1 || got["metrics"] != 9090 {
+		t.Errorf("tracked tunnels after remove = %v", got)
+	}
+
+	if err := dm.AddReverse("missing", "agent", 8080); err == nil {
+		t.Error("AddReverse accepted an unknown device")
+	}
+}
+
+func TestReverseTornDownWhenDeviceGoesOffline(t *testing.T) {
+	dm := reverseManager(t)
+	if err := dm.AddReverse("dev1", "agent", 8080); err != nil {
+		t.Fatalf("AddReverse: %v", err)
+	}
+
+	dm.MarkOffline("dev1")
+	if got := tunnelPorts(dm, "dev1-serial"); len(got) != 0 {
+		t.Errorf("tunnels left after the device went offline: %v", got)
+	}
+}
+
+func TestReverseFailedAddIsNotTracked(t *testing.T) {
+	dm := reverseManager(t)
+	dm.GetADBClient().ADBPath = "false"
+	if err := dm.AddReverse("dev1", "agent", 8080); err == nil {
+		t.Fatal("AddReverse succeeded although adb failed")
+	}
+	if got := tunnelPorts(dm, "dev1-serial"); len(got) != 0 {
+		t.Errorf("failed tunnel tracked: %v", got)
+	}
+}
@@ -2,17 +2,104 @@ package service
 
 import (
 	"androidcontrol/adb"
+	"androidcontrol/logging"
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"math/rand"
 	"net"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// StreamConfig holds per-device scrcpy encoder settings
+// Zero values keep the built-in defaults (see quality profiles in Start)
+type StreamConfig struct {
+	MaxSize     int    `json:"maxSize"`             // max_size (longest edge in px)
+	BitRate     int    `json:"bitRate"`             // video_bit_rate (bps)
+	MaxFPS      int    `json:"maxFps"`              // max_fps
+	Codec       string `json:"codec"`               // video_codec: "h264" (default) or "h265"
+	Audio       bool   `json:"audio"`               // Capture device audio (Android 11+) as Opus on a second socket; implies raw_stream=false
+	StayAwake   bool   `json:"stayAwake"`           // stay_awake: keep the screen on while plugged in
+	WakeOnStart bool   `json:"wakeOnStart"`         // Wake the screen and dismiss the keyguard before each session
+	ShowTouches bool   `json:"showTouches"`         // show_touches: draw touch indicators (restored when scrcpy exits)
+	RawStream   *bool  `json:"rawStream,omitempty"` // raw_stream (nil = true); false makes the server send device/codec metadata first
+	Profile     string `json:"profile,omitempty"`   // Named StreamProfile ("" = global profile); the fields above override it
+	DisplayID   int    `json:"displayId,omitempty"` // display_id: set from the stream key (see StreamKey), not by clients
+
+	CodecOptions map[string]string `json:"codecOptions,omitempty"` // video_codec_options, e.g. {"i-frame-interval": "1"} (see codec_options.go)
+}
+
+// Supported video codecs
+const (
+	CodecH264 = "h264"
+	CodecH265 = "h265"
+)
+
+// UsesRawStream reports whether the server runs with raw_stream=true (the default)
+// Audio needs frame meta to delimit Opus packets, which raw_stream turns off
+func (cfg StreamConfig) UsesRawStream() bool {
+	if cfg.Audio {
+		return false
+	}
+	return cfg.RawStream == nil || *cfg.RawStream
+}
+
+// ActiveCodec returns the codec this config streams with
+func (cfg StreamConfig) ActiveCodec() string {
+	if cfg.Codec == "" {
+		return CodecH264
+	}
+	return cfg.Codec
+}
+
+// Default scrcpy-server build (overridable via env)
+const (
+	defaultServerVersion    = "3.3.3"
+	defaultServerRemotePath = "/data/local/tmp/scrcpy-server.jar"
+)
+
+var defaultServerJarPath = filepath.Join(".", "assets", "scrcpy-server")
+
+// ServerConfig locates the scrcpy-server build pushed to devices
+type ServerConfig struct {
+	Version    string // Must match the jar exactly - the server rejects mismatched versions
+	JarPath    string // Local jar path
+	RemotePath string // Push destination on the device
+}
+
+// DefaultServerConfig reads SCRCPY_SERVER_VERSION and SCRCPY_SERVER_PATH, falling back to the bundled 3.3.3 jar
+func DefaultServerConfig() ServerConfig {
+	cfg := ServerConfig{
+		Version:    defaultServerVersion,
+		JarPath:    defaultServerJarPath,
+		RemotePath: defaultServerRemotePath,
+	}
+	if v := os.Getenv("SCRCPY_SERVER_VERSION"); v != "" {
+		cfg.Version = v
+	}
+	if p := os.Getenv("SCRCPY_SERVER_PATH"); p != "" {
+		cfg.JarPath = p
+	}
+	return cfg
+}
+
+// defaultBitRate is the profile 0 video bit rate (USB: high quality, WiFi: reduced)
+func defaultBitRate(deviceADBID string) int {
+	if strings.Contains(deviceADBID, ":") {
+		return 800000
+	}
+	return 1500000
+}
+
 // ScrcpyClient manages a scrcpy server connection for a single device
 // Updated for scrcpy 3.x protocol with control socket support
 type ScrcpyClient struct {
@@ -22,24 +109,59 @@ type ScrcpyClient struct {
 	scid        uint32 // Session Connection ID (32-bit HEX) for scrcpy 3.x
 	serverCmd   *exec.Cmd
 	conn        net.Conn // Video stream connection
+	audioConn   net.Conn // Audio stream connection (nil unless config.Audio)
 	ctrlConn    net.Conn // Control socket connection
 	deviceName  string
 	width       int
 	height      int
 	mu          sync.Mutex
 	running     bool
+	config      StreamConfig   // Per-device overrides for profile 0
+	profile     *StreamProfile // Named profile resolved by the stream (nil = built-in defaults)
+	server      ServerConfig
+	cleanStart  bool // Kill leftover servers/forwards before pushing (previous start failed)
+
+	// Clipboard replies from the control socket reader
+	clipboardCh chan string
+	clipboardMu sync.Mutex // Serializes GetClipboard requests
+
+	// SET_CLIPBOARD acks by sequence number, closed by the control socket reader
+	clipboardSeq atomic.Uint64
+	ackMu        sync.Mutex
+	ackWaiters   map[uint64]chan struct{}
+
+	// scrcpy server stdout/stderr (log_level=debug), shared across restarts by the stream
+	serverLogs *serverLogBuffer
 }
 
+// clipboardTimeout is how long GetClipboard waits for the device reply
+const clipboardTimeout = 3 * time.Second
+
 // NewScrcpyClient creates a new scrcpy client for the given device
-func NewScrcpyClient(adbClient *adb.ADBClient, deviceADBID string) *ScrcpyClient {
+// serverCfg is optional; DefaultServerConfig() is used when omitted
+func NewScrcpyClient(adbClient *adb.ADBClient, deviceADBID string, serverCfg ...ServerConfig) *ScrcpyClient {
+	server := DefaultServerConfig()
+	if len(serverCfg) > 0 {
+		server = serverCfg[0]
+	}
+
 	return &ScrcpyClient{
 		adbClient:   adbClient,
 		deviceADBID: deviceADBID,
 		localPort:   0,
 		scid:        0, // Will be generated on Start
+		server:      server,
+		clipboardCh: make(chan string, 1),
+		ackWaiters:  make(map[uint64]chan struct{}),
+		serverLogs:  newServerLogBuffer(),
 	}
 }
 
+// ServerLogs returns the captured scrcpy server output, oldest line first
+func (c *ScrcpyClient) ServerLogs() []string {
+	return c.serverLogs.Last(0)
+}
+
 // Start initializes the scrcpy server and establishes the video stream connection
 // Returns the net.Conn for reading raw H.264 Annex-B data
 func (c *ScrcpyClient) Start() (net.Conn, error) {
@@ -55,15 +177,32 @@ func (c *ScrcpyClient) Start() (net.Conn, error) {
 	// Values >= 0x80000000 will overflow, so mask to 31-bit (bit 31 = 0)
 	c.scid = rand.Uint32() & 0x7FFFFFFF
 
+	if c.cleanStart {
+		logging.Device(c.deviceADBID).Info("orphans_killing", "🧹 Killing leftover scrcpy servers before retrying...")
+		if err := c.adbClient.KillScrcpyServers(c.deviceADBID); err != nil {
+			logging.Device(c.deviceADBID).Warn("orphans_kill_failed", "⚠️ Failed to clean up scrcpy leftovers: %v", err)
+		}
+	}
+
+	// A sleeping or locked device would stream a black or lock screen until someone taps it
+	if c.config.WakeOnStart {
+		if err := c.adbClient.WakeAndUnlock(c.deviceADBID); err != nil {
+			logging.Device(c.deviceADBID).Warn("wake_failed", "⚠️ Failed to wake/unlock device: %v", err)
+		} else {
+			logging.Device(c.deviceADBID).Info("device_woken", "☀️ Screen woken and keyguard dismissed")
+		}
+	}
+
 	// Step 1: Push scrcpy-server to device
-	log.Printf("📦 [%s] Pushing scrcpy-server...", c.deviceADBID)
-	jarPath := filepath.Join(".", "assets", "scrcpy-server")
-	remotePath := "/data/local/tmp/scrcpy-server.jar"
+	logging.Device(c.deviceADBID).Info("server_push", "📦 Pushing scrcpy-server %s (%s)...", c.server.Version, c.server.JarPath)
+	if _, err := os.Stat(c.server.JarPath); err != nil {
+		return nil, fmt.Errorf("scrcpy-server jar not found at %s (set SCRCPY_SERVER_PATH): %w", c.server.JarPath, err)
+	}
 
-	if err := c.adbClient.PushFile(c.deviceADBID, jarPath, remotePath); err != nil {
+	if err := c.adbClient.PushFile(c.deviceADBID, c.server.JarPath, c.server.RemotePath); err != nil {
 		return nil, fmt.Errorf("failed to push scrcpy server: %w", err)
 	}
-	log.Printf("✅ [%s] Server pushed successfully", c.deviceADBID)
+	logging.Device(c.deviceADBID).Info("server_pushed", "✅ Server pushed successfully")
 
 	// Step 2: Find free port and setup ADB forward with SCID-based socket name
 	c.localPort = findFreePort()
@@ -73,15 +212,16 @@ func (c *ScrcpyClient) Start() (net.Conn, error) {
 
 	// Scrcpy 3.x uses socket name: scrcpy_<SCID in 8-digit hex>
 	socketName := fmt.Sprintf("scrcpy_%08x", c.scid)
-	log.Printf("🔌 [%s] Setting up ADB forward on port %d (socket: %s)...", c.deviceADBID, c.localPort, socketName)
+	logging.Device(c.deviceADBID).Info("forward_setup", "🔌 Setting up ADB forward on port %d (socket: %s)...", c.localPort, socketName)
 	if err := c.adbClient.Forward(c.deviceADBID, c.localPort, socketName); err != nil {
 		return nil, fmt.Errorf("failed to setup ADB forward: %w", err)
 	}
-	log.Printf("✅ [%s] ADB forward established", c.deviceADBID)
+	logging.Device(c.deviceADBID).Info("forward_established", "✅ ADB forward established")
 
 	// Step 3: Start scrcpy server with 3.x protocol + raw_stream mode
 	// raw_stream=true: server sends pure H.264 Annex-B without any headers/meta
-	log.Printf("🚀 [%s] Starting scrcpy server (v3.3.3 raw_stream)...", c.deviceADBID)
+	// (StreamConfig.RawStream=false adds a metadata header, see handshake)
+	logging.Device(c.deviceADBID).Info("server_starting", "🚀 Starting scrcpy server (v%s raw_stream=%t)...", c.server.Version, c.config.UsesRawStream())
 
 	// Auto-reduce quality for WiFi devices (IP:port format contains ":")
 	isWiFi := strings.Contains(c.deviceADBID, ":")
@@ -97,12 +237,7 @@ func (c *ScrcpyClient) Start() (net.Conn, error) {
 	profiles := []qualityProfile{
 		// Profile 0: Default (USB: high quality, WiFi: reduced)
 		{
-			bitRate: func() string {
-				if isWiFi {
-					return "800000"
-				}
-				return "1500000"
-			}(),
+			bitRate: strconv.Itoa(defaultBitRate(c.deviceADBID)),
 			maxSize: func() string {
 				if isWiFi {
 					return "480"
@@ -128,13 +263,36 @@ func (c *ScrcpyClient) Start() (net.Conn, error) {
 		},
 	}
 
+	// Apply the named profile, then per-device overrides, to the default profile only
+	// Fallback profiles still degrade quality if the encoder fails
+	if p := c.profile; p != nil {
+		if p.MaxSize > 0 {
+			profiles[0].maxSize = strconv.Itoa(p.MaxSize)
+		}
+		if p.BitRate > 0 {
+			profiles[0].bitRate = strconv.Itoa(p.BitRate)
+		}
+		if p.MaxFPS > 0 {
+			profiles[0].maxFPS = strconv.Itoa(p.MaxFPS)
+		}
+	}
+	if c.config.MaxSize > 0 {
+		profiles[0].maxSize = strconv.Itoa(c.config.MaxSize)
+	}
+	if c.config.BitRate > 0 {
+		profiles[0].bitRate = strconv.Itoa(c.config.BitRate)
+	}
+	if c.config.MaxFPS > 0 {
+		profiles[0].maxFPS = strconv.Itoa(c.config.MaxFPS)
+	}
+
 	var cmd *exec.Cmd
 	var lastErr error
 
 	for attempt, profile := range profiles {
 		if attempt > 0 {
-			log.Printf("� [%s] Retry attempt %d with reduced quality (bitrate=%s, size=%s, fps=%s)",
-				c.deviceADBID, attempt, profile.bitRate, profile.maxSize, profile.maxFPS)
+			logging.Device(c.deviceADBID).Info("server_retry", "🔄 Retry attempt %d with reduced quality (bitrate=%s, size=%s, fps=%s)",
+				attempt, profile.bitRate, profile.maxSize, profile.maxFPS)
 			// Re-setup forward for new SCID
 			c.adbClient.RemoveForward(c.deviceADBID, c.localPort)
 			c.scid = rand.Uint32() & 0x7FFFFFFF
@@ -147,32 +305,57 @@ func (c *ScrcpyClient) Start() (net.Conn, error) {
 		}
 
 		serverArgs := []string{
-			"CLASSPATH=/data/local/tmp/scrcpy-server.jar",
+			"CLASSPATH=" + c.server.RemotePath,
 			"app_process",
 			"/",
 			"com.genymobile.scrcpy.Server",
-			"3.3.3",
+			c.server.Version,
 			fmt.Sprintf("scid=%08x", c.scid),
 			"log_level=debug",
 			"video=true",
-			"audio=false",
+			"audio=" + strconv.FormatBool(c.config.Audio),
 			"max_size=" + profile.maxSize,
 			"video_bit_rate=" + profile.bitRate,
 			"max_fps=" + profile.maxFPS,
 			"tunnel_forward=true",
 			"control=true",
-			"raw_stream=true",
+			"raw_stream=" + strconv.FormatBool(c.config.UsesRawStream()),
+		}
+		if !c.config.UsesRawStream() {
+			// Keep the metadata header (read by handshake). Per-packet headers are only
+			// needed to delimit audio packets; send_frame_meta covers the video socket too,
+			// where frameMetaConn strips them again
+			serverArgs = append(serverArgs, "send_frame_meta="+strconv.FormatBool(c.config.Audio))
+		}
+		if codec := c.videoCodec(); codec != "" {
+			serverArgs = append(serverArgs, "video_codec="+codec)
+		}
+		if opts := codecOptionsArg(c.config.CodecOptions); opts != "" {
+			serverArgs = append(serverArgs, "video_codec_options="+opts)
+		}
+		if c.config.DisplayID > 0 {
+			serverArgs = append(serverArgs, "display_id="+strconv.Itoa(c.config.DisplayID))
+		}
+		if c.config.Audio {
+			serverArgs = append(serverArgs, "audio_codec=opus")
+		}
+		if c.config.StayAwake {
+			serverArgs = append(serverArgs, "stay_awake=true")
+		}
+		if c.config.ShowTouches {
+			serverArgs = append(serverArgs, "show_touches=true")
 		}
 		serverArgs = append(serverArgs, profile.extraArgs...)
 
-		cmd, lastErr = c.adbClient.ExecuteCommandBackground(c.deviceADBID, serverArgs)
+		c.serverLogs.Note(fmt.Sprintf("scrcpy server start (profile %d)", attempt))
+		cmd, lastErr = c.adbClient.ExecuteCommandBackground(c.deviceADBID, serverArgs, c.serverLogs)
 		if lastErr != nil {
-			log.Printf("⚠️ [%s] Failed to start server (attempt %d): %v", c.deviceADBID, attempt+1, lastErr)
+			logging.Device(c.deviceADBID).Warn("server_start_failed", "⚠️ Failed to start server (attempt %d): %v", attempt+1, lastErr)
 			continue
 		}
 
 		c.serverCmd = cmd
-		log.Printf("✅ [%s] Scrcpy server started (PID: %d, profile: %d)", c.deviceADBID, cmd.Process.Pid, attempt)
+		logging.Device(c.deviceADBID).Info("server_started", "✅ Scrcpy server started (PID: %d, profile: %d)", cmd.Process.Pid, attempt)
 
 		// Wait longer for problematic devices
 		waitTime := 1500 * time.Millisecond
@@ -184,7 +367,7 @@ func (c *ScrcpyClient) Start() (net.Conn, error) {
 		// Try to connect
 		conn, err := c.connectWithRetry(10, 300*time.Millisecond)
 		if err != nil {
-			log.Printf("⚠️ [%s] Connection failed (attempt %d): %v", c.deviceADBID, attempt+1, err)
+			logging.Device(c.deviceADBID).Warn("video_connect_failed", "⚠️ Connection failed (attempt %d): %v", attempt+1, err)
 			if cmd.Process != nil {
 				cmd.Process.Kill()
 			}
@@ -193,7 +376,7 @@ func (c *ScrcpyClient) Start() (net.Conn, error) {
 		}
 
 		c.conn = conn
-		log.Printf("✅ [%s] Video socket connected using profile %d", c.deviceADBID, attempt)
+		logging.Device(c.deviceADBID).Info("video_connected", "✅ Video socket connected using profile %d", attempt)
 		break // Success!
 	}
 
@@ -202,27 +385,40 @@ func (c *ScrcpyClient) Start() (net.Conn, error) {
 		return nil, fmt.Errorf("all quality profiles failed: %w", lastErr)
 	}
 
+	// Step 5a: Connect audio socket (scrcpy accepts video, audio, control in that order)
+	if c.config.Audio {
+		logging.Device(c.deviceADBID).Info("audio_connecting", "🔊 Connecting to scrcpy audio socket...")
+		audioConn, err := c.connectWithRetry(5, 200*time.Millisecond)
+		if err != nil {
+			logging.Device(c.deviceADBID).Warn("audio_failed", "⚠️ Audio socket failed, audio disabled: %v", err)
+		} else {
+			c.audioConn = audioConn
+			logging.Device(c.deviceADBID).Info("audio_connected", "✅ Audio socket connected")
+		}
+	}
+
 	// Step 5b: Connect control socket (second connection to same socket)
-	log.Printf("🎮 [%s] Connecting to scrcpy control socket...", c.deviceADBID)
+	logging.Device(c.deviceADBID).Info("control_connecting", "🎮 Connecting to scrcpy control socket...")
 	ctrlConn, err := c.connectWithRetry(5, 200*time.Millisecond)
 	if err != nil {
-		log.Printf("⚠️ [%s] Control socket failed, keyboard disabled: %v", c.deviceADBID, err)
+		logging.Device(c.deviceADBID).Warn("control_failed", "⚠️ Control socket failed, keyboard disabled: %v", err)
 		// Continue without control - video still works
 	} else {
 		c.ctrlConn = ctrlConn
-		log.Printf("✅ [%s] Control socket connected", c.deviceADBID)
+		logging.Device(c.deviceADBID).Info("control_connected", "✅ Control socket connected")
+		go c.readDeviceMessages(ctrlConn)
 	}
 
 	// Step 6: Perform handshake
-	log.Printf("🤝 [%s] Performing handshake...", c.deviceADBID)
+	logging.Device(c.deviceADBID).Info("handshake_started", "🤝 Performing handshake...")
 	if err := c.handshake(); err != nil {
-		log.Printf("❌ [%s] Handshake failed: %v", c.deviceADBID, err)
+		logging.Device(c.deviceADBID).Error("handshake_failed", "❌ Handshake failed: %v", err)
 		c.cleanup()
 		return nil, fmt.Errorf("handshake failed: %w", err)
 	}
 
 	c.running = true
-	log.Printf("🎬 [%s] Scrcpy stream ready - %s @ %dx%d (control: %v)", c.deviceADBID, c.deviceName, c.width, c.height, c.ctrlConn != nil)
+	logging.Device(c.deviceADBID).Info("stream_ready", "🎬 Scrcpy stream ready - %s @ %dx%d (control: %v)", c.deviceName, c.width, c.height, c.ctrlConn != nil)
 
 	return c.conn, nil
 }
@@ -244,6 +440,12 @@ func (c *ScrcpyClient) cleanup() {
 		c.conn = nil
 	}
 
+	// Close audio socket
+	if c.audioConn != nil {
+		c.audioConn.Close()
+		c.audioConn = nil
+	}
+
 	// Close control socket
 	if c.ctrlConn != nil {
 		c.ctrlConn.Close()
@@ -252,7 +454,7 @@ func (c *ScrcpyClient) cleanup() {
 
 	// Kill server process
 	if c.serverCmd != nil && c.serverCmd.Process != nil {
-		log.Printf("🛑 [%s] Killing scrcpy server process...", c.deviceADBID)
+		logging.Device(c.deviceADBID).Info("server_killing", "🛑 Killing scrcpy server process...")
 		c.serverCmd.Process.Kill()
 		c.serverCmd.Wait()
 		c.serverCmd = nil
@@ -260,9 +462,9 @@ func (c *ScrcpyClient) cleanup() {
 
 	// Remove ADB forward
 	if c.localPort > 0 {
-		log.Printf("🔌 [%s] Removing ADB forward on port %d...", c.deviceADBID, c.localPort)
+		logging.Device(c.deviceADBID).Info("forward_removing", "🔌 Removing ADB forward on port %d...", c.localPort)
 		if err := c.adbClient.RemoveForward(c.deviceADBID, c.localPort); err != nil {
-			log.Printf("⚠️ [%s] Failed to remove forward: %v", c.deviceADBID, err)
+			logging.Device(c.deviceADBID).Warn("forward_remove_failed", "⚠️ Failed to remove forward: %v", err)
 		}
 		c.localPort = 0
 	}
@@ -270,39 +472,150 @@ func (c *ScrcpyClient) cleanup() {
 
 // connectWithRetry attempts to connect to the scrcpy server with retries
 func (c *ScrcpyClient) connectWithRetry(maxRetries int, delay time.Duration) (net.Conn, error) {
-	addr := fmt.Sprintf("127.0.0.1:%d", c.localPort)
+	addr := net.JoinHostPort(c.adbClient.ForwardHost(), strconv.Itoa(c.localPort))
 
 	for i := 0; i < maxRetries; i++ {
 		conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
 		if err == nil {
 			return conn, nil
 		}
-		log.Printf("⏳ [%s] Connection attempt %d/%d failed, retrying...", c.deviceADBID, i+1, maxRetries)
+		logging.Device(c.deviceADBID).Info("connect_retry", "⏳ Connection attempt %d/%d failed, retrying...", i+1, maxRetries)
 		time.Sleep(delay)
 	}
 
 	return nil, fmt.Errorf("failed to connect after %d retries", maxRetries)
 }
 
-// handshake for scrcpy 3.x raw_stream mode
-// raw_stream=true: server does NOT send any metadata (no dummy byte, no device name, no resolution)
-// So we just set default values and return - the stream is pure H.264 Annex-B
+// Stream metadata sent when raw_stream=false
+const (
+	deviceNameFieldLength = 64 // NUL-padded UTF-8 device name
+	handshakeTimeout      = 5 * time.Second
+
+	// Codec IDs are the ASCII codec name as a big-endian u32
+	codecIDH264 = 0x68323634 // "h264"
+	codecIDH265 = 0x68323635 // "h265"
+
+	// Audio codec IDs the server sends instead of a codec when capture isn't possible
+	audioCodecDisabled = 0
+	audioCodecError    = 1
+)
+
+// handshake reads the stream metadata the server sends before video data
+// raw_stream=true: no metadata at all, the socket starts with H.264 data
+// raw_stream=false: [dummy:1] [device name:64] [codec id:4] [width:4] [height:4] on the
+// video socket and [codec id:4] on the audio socket; with audio on, every packet after
+// that carries frame meta (see frame_meta.go)
 func (c *ScrcpyClient) handshake() error {
-	// raw_stream=true => socket immediately starts with H.264 data
-	// No dummy byte, no device meta, no codec meta, no frame headers
-	c.deviceName = c.deviceADBID // Use ADB ID as device name
-	c.width = 720                // Set by max_size
-	c.height = 0                 // Unknown in raw mode
+	if c.config.UsesRawStream() {
+		// No dummy byte, no device meta, no codec meta, no frame headers
+		c.deviceName = c.deviceADBID // Use ADB ID as device name
+		c.width = 720                // Set by max_size
+		c.height = 0                 // Unknown in raw mode
+
+		logging.Device(c.deviceADBID).Info("handshake_done", "✅ Handshake (raw_stream mode): pure H.264 stream ready")
+		return nil
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
 
-	log.Printf("✅ [%s] Handshake (raw_stream mode): pure H.264 stream ready", c.deviceADBID)
+	header := make([]byte, 1+deviceNameFieldLength+12)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return fmt.Errorf("failed to read stream metadata: %w", err)
+	}
+
+	name := header[1 : 1+deviceNameFieldLength]
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	c.deviceName = string(name)
+	if c.deviceName == "" {
+		c.deviceName = c.deviceADBID
+	}
+
+	codecMeta := header[1+deviceNameFieldLength:]
+	codecID := binary.BigEndian.Uint32(codecMeta[0:4])
+	expected := uint32(codecIDH264)
+	if c.activeCodec() == CodecH265 {
+		expected = codecIDH265
+	}
+	if codecID != expected {
+		return fmt.Errorf("unexpected video codec id 0x%08x (want %s)", codecID, c.activeCodec())
+	}
+	c.width = int(binary.BigEndian.Uint32(codecMeta[4:8]))
+	c.height = int(binary.BigEndian.Uint32(codecMeta[8:12]))
+
+	if c.audioConn != nil {
+		c.audioConn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+		var audioMeta [4]byte
+		_, err := io.ReadFull(c.audioConn, audioMeta[:])
+		c.audioConn.SetReadDeadline(time.Time{})
+
+		audioCodec := binary.BigEndian.Uint32(audioMeta[:])
+		if err != nil || audioCodec == audioCodecDisabled || audioCodec == audioCodecError {
+			logging.Device(c.deviceADBID).Warn("audio_unavailable", "⚠️ Audio not available (codec id %d, err: %v), audio disabled", audioCodec, err)
+			c.audioConn.Close()
+			c.audioConn = nil
+		}
+	}
+	if c.config.Audio {
+		c.conn = &frameMetaConn{Conn: c.conn}
+	}
+
+	logging.Device(c.deviceADBID).Info("handshake_done", "✅ Handshake (metadata mode): %s, %s %dx%d", c.deviceName, c.activeCodec(), c.width, c.height)
 	return nil
 }
 
+// SetStreamConfig sets encoder overrides used by the next Start()
+func (c *ScrcpyClient) SetStreamConfig(cfg StreamConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = cfg
+}
+
+// SetCleanStart makes Start kill leftover scrcpy servers and forwards on the device first
+// Breaks the crash loop where an orphaned server keeps every new one from binding
+func (c *ScrcpyClient) SetCleanStart(clean bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cleanStart = clean
+}
+
+// SetStreamProfile sets the named profile Start applies under the explicit config
+func (c *ScrcpyClient) SetStreamProfile(profile *StreamProfile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.profile = profile
+}
+
+// videoCodec returns the requested video_codec ("" = server default), config before profile
+func (c *ScrcpyClient) videoCodec() string {
+	if c.config.Codec == "" && c.profile != nil {
+		return c.profile.Codec
+	}
+	return c.config.Codec
+}
+
+// activeCodec returns the codec the server streams with
+func (c *ScrcpyClient) activeCodec() string {
+	if codec := c.videoCodec(); codec != "" {
+		return codec
+	}
+	return CodecH264
+}
+
 // GetResolution returns the device screen resolution after successful handshake
 func (c *ScrcpyClient) GetResolution() (width, height int) {
 	return c.width, c.height
 }
 
+// AudioConn returns the audio connection, Opus packets with frame meta (nil when audio is off or failed)
+func (c *ScrcpyClient) AudioConn() net.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.audioConn
+}
+
 // GetDeviceName returns the device name after successful handshake
 func (c *ScrcpyClient) GetDeviceName() string {
 	return c.deviceName
@@ -339,8 +652,37 @@ func (c *ScrcpyClient) SendControl(data []byte) error {
 }
 
 // SendKeyEvent sends a key press/release event
-func (c *ScrcpyClient) SendKeyEvent(action, keycode, metastate int) error {
-	data := SerializeKeycode(action, keycode, 0, metastate)
+// repeat counts auto-repeated DOWNs of a held key (0 for the initial press)
+func (c *ScrcpyClient) SendKeyEvent(action, keycode, repeat, metastate int) error {
+	data := SerializeKeycode(action, keycode, repeat, metastate)
+	return c.SendControl(data)
+}
+
+// SendTouchEvent sends a touch down/move/up event
+func (c *ScrcpyClient) SendTouchEvent(action int, pointerID uint64, x, y, width, height int, pressure float32, buttons int) error {
+	data := SerializeTouchEvent(action, pointerID, x, y, width, height, pressure, buttons)
+	return c.SendControl(data)
+}
+
+// SendBackOrScreenOn presses BACK, or turns the screen on if it is off
+func (c *ScrcpyClient) SendBackOrScreenOn(action int) error {
+	data := SerializeBackOrScreenOn(action)
+	return c.SendControl(data)
+}
+
+// SetDisplayPower turns the device display off (video keeps streaming) or back on
+func (c *ScrcpyClient) SetDisplayPower(on bool) error {
+	return c.SendControl(SerializeDisplayPower(on))
+}
+
+// SendResetVideo asks the server to reset the encoder (emits a fresh keyframe)
+func (c *ScrcpyClient) SendResetVideo() error {
+	return c.SendControl(SerializeResetVideo())
+}
+
+// SendScroll sends a mouse wheel scroll at a position
+func (c *ScrcpyClient) SendScroll(x, y, width, height int, hScroll, vScroll float32, buttons int) error {
+	data := SerializeScrollEvent(x, y, width, height, hScroll, vScroll, buttons)
 	return c.SendControl(data)
 }
 
@@ -351,9 +693,99 @@ func (c *ScrcpyClient) SendText(text string) error {
 }
 
 // SendClipboard sets Android clipboard and optionally pastes
-func (c *ScrcpyClient) SendClipboard(text string, paste bool) error {
-	data := SerializeClipboard(text, paste, 0)
-	return c.SendControl(data)
+// With wait, the message carries a sequence number and SendClipboard blocks until the
+// device acks it, returning an error after clipboardTimeout (the clipboard didn't stick)
+func (c *ScrcpyClient) SendClipboard(text string, paste, wait bool) error {
+	if !wait {
+		return c.SendControl(SerializeClipboard(text, paste, 0)) // Sequence 0: no ack
+	}
+
+	seq := c.clipboardSeq.Add(1)
+	ack := make(chan struct{})
+	c.ackMu.Lock()
+	c.ackWaiters[seq] = ack
+	c.ackMu.Unlock()
+	defer func() {
+		c.ackMu.Lock()
+		delete(c.ackWaiters, seq)
+		c.ackMu.Unlock()
+	}()
+
+	if err := c.SendControl(SerializeClipboard(text, paste, seq)); err != nil {
+		return err
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-time.After(clipboardTimeout):
+		return fmt.Errorf("timed out waiting for clipboard ack (seq %d)", seq)
+	}
+}
+
+// readDeviceMessages reads device -> client messages until the control socket closes
+// A socket the server closed is dropped, so HasControl turns false instead of writes failing
+func (c *ScrcpyClient) readDeviceMessages(conn net.Conn) {
+	for {
+		msg, err := readDeviceMessage(conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logging.Device(c.deviceADBID).Warn("control_reader_stopped", "⚠️ Control socket reader stopped: %v", err)
+			}
+			c.mu.Lock()
+			lost := c.ctrlConn == conn
+			if lost {
+				c.ctrlConn.Close()
+				c.ctrlConn = nil
+			}
+			c.mu.Unlock()
+			if lost {
+				logging.Device(c.deviceADBID).Warn("control_lost", "⚠️ Control socket closed, input disabled")
+			}
+			return
+		}
+
+		switch msg.msgType {
+		case DeviceMsgClipboard:
+			// Keep only the latest clipboard (also sent unsolicited on device copy)
+			select {
+			case <-c.clipboardCh:
+			default:
+			}
+			c.clipboardCh <- msg.text
+		case DeviceMsgAckClipboard:
+			c.ackMu.Lock()
+			if ack, ok := c.ackWaiters[msg.sequence]; ok {
+				delete(c.ackWaiters, msg.sequence)
+				close(ack)
+			}
+			c.ackMu.Unlock()
+			logging.Device(c.deviceADBID).Info("clipboard_ack", "📋 Clipboard set acknowledged (seq %d)", msg.sequence)
+		}
+	}
+}
+
+// GetClipboard requests the device clipboard and waits for the reply
+func (c *ScrcpyClient) GetClipboard() (string, error) {
+	c.clipboardMu.Lock()
+	defer c.clipboardMu.Unlock()
+
+	// Drop any stale unsolicited clipboard
+	select {
+	case <-c.clipboardCh:
+	default:
+	}
+
+	if err := c.SendControl(SerializeGetClipboard(CopyKeyNone)); err != nil {
+		return "", err
+	}
+
+	select {
+	case text := <-c.clipboardCh:
+		return text, nil
+	case <-time.After(clipboardTimeout):
+		return "", fmt.Errorf("timed out waiting for device clipboard")
+	}
 }
 
 // HasControl returns whether control socket is available
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listTimeout bounds a list-mode server run (push + app_process startup + MediaCodec query)
+const listTimeout = 20 * time.Second
+
+// EncoderInfo is one MediaCodec encoder reported by the scrcpy server
+type EncoderInfo struct {
+	Type    string `json:"type"`           // "video" or "audio"
+	Codec   string `json:"codec"`          // scrcpy codec name (h264, h265, av1, opus, aac, ...)
+	Name    string `json:"name"`           // Encoder name for video_encoder= / audio_encoder=
+	Mode    string `json:"mode,omitempty"` // "hw", "sw" or "hybrid" (Android 10+)
+	Vendor  bool   `json:"vendor"`         // Provided by the device vendor
+	AliasOf string `json:"alias_of,omitempty"`
+}
+
+// DisplayInfo is one display reported by the scrcpy server
+type DisplayInfo struct {
+	ID     int `json:"id"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Lines of the server's "List of ... encoders:" / "List of displays:" output
+var (
+	encoderLineRe = regexp.MustCompile(`--(video|audio)-codec=(\S+)\s+--(?:video|audio)-encoder=(\S+)(?:\s+\((hw|sw|hybrid)\))?(\s+\[vendor\])?(?:\s+\(alias for (\S+)\))?`)
+	displayLineRe = regexp.MustCompile(`--display-id=(\d+)\s+\((\d+)x(\d+)\)`)
+)
+
+// ListEncoders runs the server in list mode and returns the device's video and audio encoders
+func (c *ScrcpyClient) ListEncoders() ([]EncoderInfo, error) {
+	out, err := c.runList("list_encoders=true")
+	if err != nil {
+		return nil, err
+	}
+	return parseEncoderList(out), nil
+}
+
+// ListDisplays runs the server in list mode and returns the device's displays
+func (c *ScrcpyClient) ListDisplays() ([]DisplayInfo, error) {
+	out, err := c.runList("list_displays=true")
+	if err != nil {
+		return nil, err
+	}
+	return parseDisplayList(out), nil
+}
+
+// runList pushes the server and runs it with a list option; it prints the list and exits
+// without opening any socket, so it doesn't disturb a running stream
+func (c *ScrcpyClient) runList(option string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	if err := c.adbClient.PushFileContext(ctx, c.deviceADBID, c.server.JarPath, c.server.RemotePath); err != nil {
+		return "", fmt.Errorf("failed to push scrcpy server: %w", err)
+	}
+
+	command := strings.Join([]string{
+		"CLASSPATH=" + c.server.RemotePath,
+		"app_process",
+		"/",
+		"com.genymobile.scrcpy.Server",
+		c.server.Version,
+		"log_level=info",
+		option,
+	}, " ")
+	result, err := c.adbClient.ExecuteCommandContext(ctx, c.deviceADBID, command)
+	if err != nil {
+		return "", err
+	}
+
+	out := result.Stdout + result.Stderr
+	if result.ExitCode != 0 || !strings.Contains(out, "List of") {
+		return "", fmt.Errorf("scrcpy server list failed (exit %d): %s", result.ExitCode, strings.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// parseEncoderList extracts encoders from list_encoders output
+func parseEncoderList(out string) []EncoderInfo {
+	encoders := []EncoderInfo{}
+	for _, m := range encoderLineRe.FindAllStringSubmatch(out, -1) {
+		encoders = append(encoders, EncoderInfo{
+			Type:    m[1],
+			Codec:   m[2],
+			Name:    m[3],
+			Mode:    m[4],
+			Vendor:  m[5] != "",
+			AliasOf: m[6],
+		})
+	}
+	return encoders
+}
+
+// parseDisplayList extracts displays from list_displays output
+func parseDisplayList(out string) []DisplayInfo {
+	displays := []DisplayInfo{}
+	for _, m := range displayLineRe.FindAllStringSubmatch(out, -1) {
+		id, _ := strconv.Atoi(m[1])
+		width, _ := strconv.Atoi(m[2])
+		height, _ := strconv.Atoi(m[3])
+		displays = append(displays, DisplayInfo{ID: id, Width: width, Height: height})
+	}
+	return displays
+}
+
+// ListEncoders returns the encoders a device offers, to check a codec before requesting it
+func (s *StreamingService) ListEncoders(deviceID string) ([]EncoderInfo, error) {
+	client, err := s.listClient(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListEncoders()
+}
+
+// ListDisplays returns the displays a device exposes
+func (s *StreamingService) ListDisplays(deviceID string) ([]DisplayInfo, error) {
+	client, err := s.listClient(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListDisplays()
+}
+
+// listClient creates a throwaway scrcpy client for a list-mode run
+func (s *StreamingService) listClient(deviceID string) (*ScrcpyClient, error) {
+	if err := s.requireStreaming(); err != nil {
+		return nil, err
+	}
+	device := s.deviceManager.GetDevice(deviceID)
+	if device == nil {
+		return nil, fmt.Errorf("device not found: %s", deviceID)
+	}
+	return NewScrcpyClient(s.deviceManager.GetADBClient(), device.ADBDeviceID), nil
+}
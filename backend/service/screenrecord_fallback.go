@@ -0,0 +1,130 @@
+package service
+
+import (
+	"androidcontrol/adb"
+	"androidcontrol/logging"
+	"time"
+)
+
+// minFallbackSession is how long a screenrecord session must last to be restarted;
+// shorter runs mean screenrecord doesn't work on the device either
+const minFallbackSession = 5 * time.Second
+
+// runScreenrecordFallback streams `adb exec-out screenrecord` after scrcpy keeps failing
+// Video only (H.264, no control socket or audio); the stream is reported as degraded.
+// screenrecord exits at its 3-minute limit, so sessions are restarted until the stream
+// stops or a session dies early. Returns true when a restart was requested (e.g. new
+// config), meaning scrcpy should be tried again.
+func (s *StreamingService) runScreenrecordFallback(stream *deviceStream) bool {
+	logger := logging.Device(stream.deviceID)
+
+	stream.mu.Lock()
+	if stream.state != StateStarting && stream.state != StateRunning {
+		stream.mu.Unlock()
+		return false
+	}
+	if stream.config.DisplayID != 0 {
+		stream.mu.Unlock()
+		return false // screenrecord here only captures the main display
+	}
+	if stream.scrcpyClient != nil {
+		stream.scrcpyClient.Stop()
+		stream.scrcpyClient = nil // Input calls now fail with "stream not found"
+	}
+	stream.state = StateRunning
+	stream.degraded = true
+	stream.restartRequested = false
+	ctx := stream.devCtx
+	opts := s.screenrecordOpts(stream)
+	if stream.codec != CodecH264 {
+		stream.vpsPkt, stream.spsPkt, stream.ppsPkt, stream.lastIDRPkt = nil, nil, nil, nil
+		stream.codec = CodecH264
+	}
+	stream.mu.Unlock()
+
+	defer func() {
+		stream.mu.Lock()
+		stream.degraded = false
+		stream.stopFallback = nil
+		stream.mu.Unlock()
+	}()
+
+	logger.Warn("screenrecord_fallback", "⚠️ scrcpy keeps failing, falling back to screenrecord (video only, no control, max_size=%d bitrate=%d)", opts.MaxSize, opts.BitRate)
+	s.broadcastStreamStatus(stream.deviceID, streamStatusDegraded, 0, 0)
+
+	adbClient := s.deviceManager.GetADBClient()
+	for {
+		out, cmd, err := adbClient.StartH264Stream(stream.deviceADBID, opts)
+		if err != nil {
+			logger.Error("screenrecord_failed", "❌ screenrecord fallback failed: %v", err)
+			return false
+		}
+
+		// Pipe reads don't see ctx, so stopping kills screenrecord and closes its pipe
+		stop := func() {
+			cmd.Process.Kill()
+			out.Close()
+		}
+		stream.mu.Lock()
+		stream.stopFallback = stop
+		stream.ptsBase = time.Now()
+		stream.mu.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				stop()
+			case <-done:
+			}
+		}()
+
+		sessionStart := time.Now()
+		stream.metrics.reset()
+		s.consumeH264(ctx, stream, CodecH264, out)
+		close(done)
+		stop()
+		cmd.Wait()
+		sessionDuration := time.Since(sessionStart)
+
+		stream.mu.Lock()
+		stream.stopFallback = nil
+		if stream.state == StateStopping || stream.state == StateStopped {
+			stream.mu.Unlock()
+			return false
+		}
+		if stream.restartRequested {
+			stream.restartRequested = false
+			stream.scrcpyClient = s.newScrcpyClient(stream)
+			stream.mu.Unlock()
+			logger.Info("screenrecord_retry_scrcpy", "🔁 Restart requested, trying scrcpy again")
+			return true
+		}
+		stream.mu.Unlock()
+
+		if sessionDuration < minFallbackSession {
+			logger.Error("screenrecord_died", "❌ screenrecord died after %v", sessionDuration.Round(time.Millisecond))
+			return false
+		}
+		logger.Info("screenrecord_restart", "📼 screenrecord session ended after %v, restarting", sessionDuration.Round(time.Second))
+	}
+}
+
+// screenrecordOpts maps the stream's size/bitrate (config, adaptive bitrate, then profile)
+// to screenrecord; unset values use the H264_SIZE / H264_BITRATE defaults (must hold stream.mu)
+func (s *StreamingService) screenrecordOpts(stream *deviceStream) adb.H264Opts {
+	cfg := stream.config
+	opts := adb.H264Opts{MaxSize: cfg.MaxSize, BitRate: cfg.BitRate}
+	if stream.adaptive.bitRate > 0 {
+		opts.BitRate = stream.adaptive.bitRate
+	}
+	if profile := s.profiles.resolve(cfg.Profile); profile != nil {
+		if opts.MaxSize == 0 {
+			opts.MaxSize = profile.MaxSize
+		}
+		if opts.BitRate == 0 {
+			opts.BitRate = profile.BitRate
+		}
+	}
+	return opts
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// serverLogLines is how many scrcpy server output lines are kept per device
+const serverLogLines = 500
+
+// maxServerLogLine truncates runaway lines (e.g. a stack trace without newlines)
+const maxServerLogLine = 4096
+
+// serverLogBuffer is a ring of timestamped scrcpy server output lines
+// Shared by every ScrcpyClient of a device stream so restart loops keep their history
+type serverLogBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int    // Slot for the next line once the ring is full
+	partial []byte // Unterminated tail of the last Write
+}
+
+func newServerLogBuffer() *serverLogBuffer {
+	return &serverLogBuffer{lines: make([]string, 0, serverLogLines)}
+}
+
+// Write splits output into lines; implements io.Writer for exec.Cmd
+func (b *serverLogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			b.partial = append(b.partial, data...)
+			if len(b.partial) > maxServerLogLine {
+				b.appendLine(string(b.partial))
+				b.partial = b.partial[:0]
+			}
+			break
+		}
+		line := append(b.partial, data[:i]...)
+		b.partial = b.partial[:0]
+		data = data[i+1:]
+		b.appendLine(string(bytes.TrimRight(line, "\r")))
+	}
+	return len(p), nil
+}
+
+// Note adds a backend-side marker line (e.g. server start) to the log
+func (b *serverLogBuffer) Note(text string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.appendLine("--- " + text + " ---")
+}
+
+// appendLine stores a line with its arrival time (must hold mu)
+func (b *serverLogBuffer) appendLine(line string) {
+	if len(line) > maxServerLogLine {
+		line = line[:maxServerLogLine]
+	}
+	line = time.Now().Format("15:04:05.000") + " " + line
+
+	if len(b.lines) < serverLogLines {
+		b.lines = append(b.lines, line)
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % serverLogLines
+}
+
+// Last returns up to n of the most recent lines, oldest first (n <= 0 returns all)
+func (b *serverLogBuffer) Last(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	total := len(b.lines)
+	if n <= 0 || n > total {
+		n = total
+	}
+	out := make([]string, 0, n)
+	for i := total - n; i < total; i++ {
+		out = append(out, b.lines[(b.next+i)%total])
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"time"
+)
+
+// snapshotTimeout bounds the ffmpeg decode of a single keyframe
+const snapshotTimeout = 5 * time.Second
+
+// Snapshot returns a JPEG decoded from the cached [VPS]+SPS+PPS+IDR of the live stream
+// Falls back to `screencap` (PNG) when no keyframe is cached yet, ffmpeg is missing or decoding fails
+func (s *StreamingService) Snapshot(deviceID string) ([]byte, error) {
+	caps := s.Capabilities()
+	if !caps.ADB {
+		return nil, fmt.Errorf("%w: snapshots need adb (see GET /api/capabilities)", ErrUnavailable)
+	}
+
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+
+	if exists && caps.FFmpeg {
+		stream.mu.Lock()
+		hasKeyframe := stream.spsPkt != nil && stream.ppsPkt != nil && stream.lastIDRPkt != nil
+		codec := stream.codec
+		stream.mu.Unlock()
+
+		if hasKeyframe {
+			var annexB bytes.Buffer
+			for _, pkt := range s.GetStreamData(deviceID) {
+				annexB.Write(stripPacketPrefix(pkt))
+			}
+
+			jpeg, err := decodeKeyframeJPEG(annexB.Bytes(), codec)
+			if err == nil {
+				return jpeg, nil
+			}
+			log.Printf("⚠️ [%s] Snapshot decode failed, using screencap: %v", deviceID, err)
+		}
+	}
+
+	device := s.GetDevice(deviceID)
+	if device == nil {
+		return nil, fmt.Errorf("device not found: %s", deviceID)
+	}
+	if _, displayID := ParseStreamKey(deviceID); displayID != 0 {
+		return nil, fmt.Errorf("no keyframe for display %d yet (screencap only captures the main display)", displayID)
+	}
+	return s.deviceManager.GetADBClient().ScreenCapture(device.ADBDeviceID)
+}
+
+// decodeKeyframeJPEG decodes one Annex-B keyframe to JPEG via ffmpeg (stdin -> stdout)
+func decodeKeyframeJPEG(annexB []byte, codec string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+
+	inputFormat := "h264"
+	if codec == CodecH265 {
+		inputFormat = "hevc"
+	}
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-f", inputFormat, "-i", "-",
+		"-frames:v", "1", "-f", "image2", "-c:v", "mjpeg", "-q:v", "3", "-")
+	cmd.Stdin = bytes.NewReader(annexB)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("ffmpeg: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("ffmpeg: %w, stderr: %s", err, stderr.String())
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no image")
+	}
+	return stdout.Bytes(), nil
+}
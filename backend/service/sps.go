@@ -0,0 +1,222 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errSPSTruncated is returned when the SPS ends before all needed fields are read
+var errSPSTruncated = errors.New("sps truncated")
+
+// bitReader reads bits MSB-first from an RBSP (emulation prevention already removed)
+type bitReader struct {
+	data []byte
+	pos  int // Bit position
+}
+
+func (r *bitReader) u(n int) (uint32, error) {
+	var v uint32
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.data)*8 {
+			return 0, errSPSTruncated
+		}
+		bit := (r.data[r.pos/8] >> (7 - uint(r.pos%8))) & 1
+		v = v<<1 | uint32(bit)
+		r.pos++
+	}
+	return v, nil
+}
+
+// ue reads an unsigned Exp-Golomb code
+func (r *bitReader) ue() (uint32, error) {
+	leadingZeros := 0
+	for {
+		bit, err := r.u(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 {
+			break
+		}
+		leadingZeros++
+		if leadingZeros > 31 {
+			return 0, fmt.Errorf("invalid exp-golomb code")
+		}
+	}
+	suffix, err := r.u(leadingZeros)
+	if err != nil {
+		return 0, err
+	}
+	return (1<<uint(leadingZeros) - 1) + suffix, nil
+}
+
+// se reads a signed Exp-Golomb code
+func (r *bitReader) se() (int32, error) {
+	v, err := r.ue()
+	if err != nil {
+		return 0, err
+	}
+	if v%2 == 1 {
+		return int32((v + 1) / 2), nil
+	}
+	return -int32(v / 2), nil
+}
+
+// unescapeRBSP removes emulation prevention bytes (00 00 03 -> 00 00)
+func unescapeRBSP(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	zeros := 0
+	for _, b := range data {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// parseH264SPSResolution decodes the display size from an H.264 SPS NAL
+// nalData may include the Annex-B start code. Uses pic_width_in_mbs/pic_height_in_map_units
+// and frame_crop offsets, so the result matches the decoded picture size
+func parseH264SPSResolution(nalData []byte) (width, height int, err error) {
+	// Skip start code
+	i, scLen := findStartCode(nalData, 0)
+	if i == 0 {
+		nalData = nalData[scLen:]
+	}
+	if len(nalData) < 4 || nalData[0]&0x1F != 7 {
+		return 0, 0, fmt.Errorf("not an SPS NAL")
+	}
+
+	r := &bitReader{data: unescapeRBSP(nalData[1:])}
+	return readSPSResolution(r)
+}
+
+// readSPSResolution parses seq_parameter_set_data up to the cropping fields
+func readSPSResolution(r *bitReader) (width, height int, err error) {
+	// Errors are sticky: once a read fails, every later read returns 0
+	var readErr error
+	u := func(n int) uint32 {
+		if readErr != nil {
+			return 0
+		}
+		v, e := r.u(n)
+		readErr = e
+		return v
+	}
+	ue := func() uint32 {
+		if readErr != nil {
+			return 0
+		}
+		v, e := r.ue()
+		readErr = e
+		return v
+	}
+	se := func() int32 {
+		if readErr != nil {
+			return 0
+		}
+		v, e := r.se()
+		readErr = e
+		return v
+	}
+
+	profileIdc := u(8)
+	u(8) // constraint flags + reserved
+	u(8) // level_idc
+	ue() // seq_parameter_set_id
+
+	chromaFormatIdc := uint32(1) // 4:2:0 unless signalled
+	separateColourPlane := uint32(0)
+	switch profileIdc {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		chromaFormatIdc = ue()
+		if chromaFormatIdc == 3 {
+			separateColourPlane = u(1)
+		}
+		ue()           // bit_depth_luma_minus8
+		ue()           // bit_depth_chroma_minus8
+		u(1)           // qpprime_y_zero_transform_bypass_flag
+		if u(1) == 1 { // seq_scaling_matrix_present_flag
+			count := 8
+			if chromaFormatIdc == 3 {
+				count = 12
+			}
+			for i := 0; i < count; i++ {
+				if u(1) == 1 { // seq_scaling_list_present_flag
+					size := 16
+					if i >= 6 {
+						size = 64
+					}
+					lastScale, nextScale := int32(8), int32(8)
+					for j := 0; j < size; j++ {
+						if nextScale != 0 {
+							nextScale = (lastScale + se() + 256) % 256
+						}
+						if nextScale != 0 {
+							lastScale = nextScale
+						}
+					}
+				}
+			}
+		}
+	}
+
+	ue()          // log2_max_frame_num_minus4
+	switch ue() { // pic_order_cnt_type
+	case 0:
+		ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		u(1) // delta_pic_order_always_zero_flag
+		se() // offset_for_non_ref_pic
+		se() // offset_for_top_to_bottom_field
+		cycle := ue()
+		for i := uint32(0); i < cycle && readErr == nil; i++ {
+			se() // offset_for_ref_frame
+		}
+	}
+	ue() // max_num_ref_frames
+	u(1) // gaps_in_frame_num_value_allowed_flag
+	widthInMbs := ue() + 1
+	heightInMapUnits := ue() + 1
+	frameMbsOnly := u(1)
+	if frameMbsOnly == 0 {
+		u(1) // mb_adaptive_frame_field_flag
+	}
+	u(1) // direct_8x8_inference_flag
+
+	var cropLeft, cropRight, cropTop, cropBottom uint32
+	if u(1) == 1 { // frame_cropping_flag
+		cropLeft, cropRight, cropTop, cropBottom = ue(), ue(), ue(), ue()
+	}
+
+	if readErr != nil {
+		return 0, 0, readErr
+	}
+
+	// Crop units depend on chroma subsampling (ChromaArrayType)
+	chromaArrayType := chromaFormatIdc
+	if separateColourPlane == 1 {
+		chromaArrayType = 0
+	}
+	cropUnitX, cropUnitY := uint32(1), 2-frameMbsOnly
+	switch chromaArrayType {
+	case 1: // 4:2:0
+		cropUnitX, cropUnitY = 2, 2*(2-frameMbsOnly)
+	case 2: // 4:2:2
+		cropUnitX, cropUnitY = 2, 2-frameMbsOnly
+	}
+
+	width = int(widthInMbs*16) - int(cropUnitX*(cropLeft+cropRight))
+	height = int((2-frameMbsOnly)*heightInMapUnits*16) - int(cropUnitY*(cropTop+cropBottom))
+	if width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid sps dimensions %dx%d", width, height)
+	}
+	return width, height, nil
+}
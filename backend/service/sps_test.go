@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// spsBytes decodes a space-separated hex dump of an SPS NAL (without start code)
+func spsBytes(t *testing.T, dump string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(dump, " ", ""))
+	if err != nil {
+		t.Fatalf("bad hex %q: %v", dump, err)
+	}
+	return b
+}
+
+// SPS NALs captured from real encoders
+const (
+	// Baseline@3.1 1280x720, pic_order_cnt_type 2, VUI, no cropping
+	spsBaseline720p = "67 42 c0 1f da 01 40 16 e8 06 d0 a1 35"
+	// Main@3.1 1280x720, VUI with timing info and emulation prevention bytes
+	spsMain720p = "67 4d 40 1f e8 80 28 02 dd 80 b5 01 01 01 40 00 00 03 00 40 00 00 0c 03 c6 0c 44 80"
+	// High@3.1 1280x720 (x264), VUI
+	spsHigh720p = "67 64 00 1f ac d9 40 50 05 bb 01 10 00 00 03 00 10 00 00 03 03 c0 f1 83 19 60"
+	// High@4.0 1920x1080 (x264): 1088 coded lines cropped by frame_crop_bottom_offset=4
+	spsHigh1080p = "67 64 00 28 ac d9 40 78 02 27 e5 c0 44 00 00 03 00 04 00 00 03 00 f0 3c 60 c6 58"
+	// High@3.0 640x360: 368 coded lines cropped to 360
+	spsHigh360p = "67 64 00 1e ac d9 40 a0 2f f9 70 11 00 00 03 03 e9 00 00 ea 60 0f 16 2d 96"
+	// Baseline@1.3 320x240, VUI with timing info
+	spsBaseline240p = "67 42 c0 0d 9a 74 0a 0f d0 80 00 00 03 00 80 00 00 19 47 8a 15 50"
+)
+
+func TestParseH264SPSResolution(t *testing.T) {
+	tests := []struct {
+		name          string
+		sps           string
+		width, height int
+	}{
+		{"baseline 720p", spsBaseline720p, 1280, 720},
+		{"main 720p with VUI", spsMain720p, 1280, 720},
+		{"high 720p with VUI", spsHigh720p, 1280, 720},
+		{"high 1080p cropped", spsHigh1080p, 1920, 1080},
+		{"high 360p cropped", spsHigh360p, 640, 360},
+		{"baseline 240p", spsBaseline240p, 320, 240},
+	}
+	for _, tc := range tests {
+		nal := spsBytes(t, tc.sps)
+		inputs := map[string][]byte{
+			"bare":         nal,
+			"start code 3": concat(sc3, nal),
+			"start code 4": concat(sc4, nal),
+		}
+		for form, input := range inputs {
+			t.Run(tc.name+"/"+form, func(t *testing.T) {
+				w, h, err := parseH264SPSResolution(input)
+				if err != nil || w != tc.width || h != tc.height {
+					t.Errorf("parseH264SPSResolution = %dx%d, %v, want %dx%d", w, h, err, tc.width, tc.height)
+				}
+			})
+		}
+	}
+}
+
+func TestParseH264SPSResolutionRejects(t *testing.T) {
+	full := spsBytes(t, spsHigh1080p)
+	tests := []struct {
+		name      string
+		nal       []byte
+		truncated bool
+	}{
+		{"pps", spsBytes(t, "68 eb e3 cb 22 c0"), false},
+		{"too short", full[:3], false},
+		{"cut before the size", full[:6], true},
+		{"cut before cropping", full[:8], true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w, h, err := parseH264SPSResolution(tc.nal)
+			if err == nil {
+				t.Fatalf("parseH264SPSResolution = %dx%d, want an error", w, h)
+			}
+			if tc.truncated && !errors.Is(err, errSPSTruncated) {
+				t.Errorf("error = %v, want errSPSTruncated", err)
+			}
+		})
+	}
+}
+
+func TestUnescapeRBSP(t *testing.T) {
+	tests := []struct {
+		name    string
+		in, out []byte
+	}{
+		{"no escapes", []byte{1, 2, 3}, []byte{1, 2, 3}},
+		{"escape removed", []byte{0, 0, 3, 1}, []byte{0, 0, 1}},
+		{"two escapes", []byte{0, 0, 3, 0, 0, 3, 0}, []byte{0, 0, 0, 0, 0}},
+		{"3 after one zero kept", []byte{0, 3, 0}, []byte{0, 3, 0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := unescapeRBSP(tc.in); !bytes.Equal(got, tc.out) {
+				t.Errorf("unescapeRBSP(% x) = % x, want % x", tc.in, got, tc.out)
+			}
+		})
+	}
+}
+
+func TestCachedNALEqualIgnoresPTS(t *testing.T) {
+	sps := concat(sc4, spsBytes(t, spsHigh720p))
+	first := EncodeFrame(FrameHeader{Type: FrameTypeVideo, Flags: FrameFlagConfig, PTS: 1000, DeviceID: "dev"}, sps)
+	if !cachedNALEqual(first, sps) {
+		t.Error("the cached SPS frame doesn't match the SPS it carries")
+	}
+	if cachedNALEqual(nil, sps) {
+		t.Error("no cached SPS matched")
+	}
+	other := concat(sc4, spsBytes(t, spsHigh1080p))
+	if cachedNALEqual(first, other) {
+		t.Error("a different SPS matched the cached one")
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// metricsWindow is the rolling window for FPS/bitrate calculation
+const metricsWindow = time.Second
+
+// Inter-frame arrival tracking: percentiles over the last frameIntervalSamples
+// frames, logged every intervalLogWindows metrics windows
+const (
+	frameIntervalSamples = 512
+	intervalLogWindows   = 30
+)
+
+// streamMetrics tracks delivered frames and bytes for a device stream
+// Has its own lock so the hot NAL path doesn't contend with stream.mu
+type streamMetrics struct {
+	mu sync.Mutex
+
+	windowStart  time.Time
+	windowFrames int64
+	windowBytes  int64
+
+	fps         float64 // Frames per second over the last full window
+	kbps        float64 // Kilobits per second over the last full window
+	totalFrames int64
+
+	// Ring of recent inter-frame arrival gaps (device-side stutter shows up here
+	// even when the network to the browser is fine)
+	lastFrameAt     time.Time
+	intervals       [frameIntervalSamples]time.Duration
+	intervalCount   int
+	intervalNext    int
+	intervalP50     time.Duration
+	intervalP99     time.Duration
+	windowsSinceLog int
+	logPending      bool
+}
+
+// record adds a NAL unit to the current window; isFrame marks picture (VCL) NALs
+func (m *streamMetrics) record(isFrame bool, size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if m.windowStart.IsZero() {
+		m.windowStart = now
+	}
+
+	if isFrame {
+		m.windowFrames++
+		m.totalFrames++
+		if !m.lastFrameAt.IsZero() {
+			m.intervals[m.intervalNext] = now.Sub(m.lastFrameAt)
+			m.intervalNext = (m.intervalNext + 1) % frameIntervalSamples
+			m.intervalCount = min(m.intervalCount+1, frameIntervalSamples)
+		}
+		m.lastFrameAt = now
+	}
+	m.windowBytes += int64(size)
+
+	if elapsed := now.Sub(m.windowStart); elapsed >= metricsWindow {
+		secs := elapsed.Seconds()
+		m.fps = float64(m.windowFrames) / secs
+		m.kbps = float64(m.windowBytes*8) / 1000 / secs
+		m.windowStart = now
+		m.windowFrames = 0
+		m.windowBytes = 0
+		m.updateIntervalPercentiles()
+	}
+}
+
+// updateIntervalPercentiles recomputes p50/p99 of the sampled gaps (must hold mu)
+func (m *streamMetrics) updateIntervalPercentiles() {
+	if m.intervalCount == 0 {
+		return
+	}
+	sorted := slices.Clone(m.intervals[:m.intervalCount])
+	slices.Sort(sorted)
+	m.intervalP50 = sorted[(len(sorted)-1)*50/100]
+	m.intervalP99 = sorted[(len(sorted)-1)*99/100]
+
+	m.windowsSinceLog++
+	if m.windowsSinceLog >= intervalLogWindows {
+		m.windowsSinceLog = 0
+		m.logPending = true
+	}
+}
+
+// frameIntervals returns p50/p99 inter-frame arrival gaps (0 until frames arrive)
+func (m *streamMetrics) frameIntervals() (p50, p99 time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.intervalP50, m.intervalP99
+}
+
+// takeIntervalLog returns the percentiles once per log period (ok=false otherwise)
+func (m *streamMetrics) takeIntervalLog() (p50, p99 time.Duration, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.logPending {
+		return 0, 0, false
+	}
+	m.logPending = false
+	return m.intervalP50, m.intervalP99, true
+}
+
+// snapshot returns the latest rates; a stalled stream (no data for 2 windows) reports 0
+func (m *streamMetrics) snapshot() (fps, kbps float64, frames int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.windowStart.IsZero() || time.Since(m.windowStart) >= 2*metricsWindow {
+		return 0, 0, m.totalFrames
+	}
+	return m.fps, m.kbps, m.totalFrames
+}
+
+// reset clears the window (used when a new scrcpy session starts)
+func (m *streamMetrics) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.windowStart = time.Time{}
+	m.windowFrames = 0
+	m.windowBytes = 0
+	m.fps = 0
+	m.kbps = 0
+	m.lastFrameAt = time.Time{}
+	m.intervalCount = 0
+	m.intervalNext = 0
+	m.intervalP50 = 0
+	m.intervalP99 = 0
+	m.windowsSinceLog = 0
+	m.logPending = false
+}
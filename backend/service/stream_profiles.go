@@ -0,0 +1,187 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+)
+
+// StreamProfile is a named bundle of encoder settings ("low", "balanced", "hq")
+// Zero fields keep the built-in default for that setting
+type StreamProfile struct {
+	MaxSize int    `json:"maxSize"`         // max_size (longest edge in px)
+	BitRate int    `json:"bitRate"`         // video_bit_rate (bps)
+	MaxFPS  int    `json:"maxFps"`          // max_fps
+	Codec   string `json:"codec,omitempty"` // video_codec: "h264" or "h265"
+}
+
+// DefaultStreamProfiles returns the built-in profiles, used when no profiles file exists
+func DefaultStreamProfiles() map[string]StreamProfile {
+	return map[string]StreamProfile{
+		"low":      {MaxSize: 480, BitRate: 500_000, MaxFPS: 15},
+		"balanced": {MaxSize: 720, BitRate: 1_500_000, MaxFPS: 30},
+		"hq":       {MaxSize: 1080, BitRate: 6_000_000, MaxFPS: 60},
+	}
+}
+
+// LoadStreamProfiles reads {"name": {maxSize, bitRate, maxFps, codec}} from a JSON file
+// File entries replace or add to the built-in defaults; a missing file yields the defaults
+func LoadStreamProfiles(path string) (map[string]StreamProfile, error) {
+	profiles := DefaultStreamProfiles()
+	if path == "" {
+		return profiles, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		return profiles, err
+	}
+
+	var fromFile map[string]StreamProfile
+	if err := json.Unmarshal(data, &fromFile); err != nil {
+		return profiles, fmt.Errorf("invalid profiles file %s: %w", path, err)
+	}
+	for name, p := range fromFile {
+		if err := p.validate(); err != nil {
+			return profiles, fmt.Errorf("profile %q: %w", name, err)
+		}
+		profiles[name] = p
+	}
+	log.Printf("🎚️ Loaded %d stream profiles from %s", len(fromFile), path)
+	return profiles, nil
+}
+
+func (p StreamProfile) validate() error {
+	if p.MaxSize < 0 || p.BitRate < 0 || p.MaxFPS < 0 {
+		return fmt.Errorf("values must be >= 0")
+	}
+	if p.Codec != "" && p.Codec != CodecH264 && p.Codec != CodecH265 {
+		return fmt.Errorf("invalid codec: %s (expected %s or %s)", p.Codec, CodecH264, CodecH265)
+	}
+	return nil
+}
+
+// streamProfiles is the profile table plus the global selection (own lock, taken after stream.mu)
+type streamProfiles struct {
+	mu     sync.RWMutex
+	byName map[string]StreamProfile
+	global string // Profile for devices without their own ("" = built-in defaults)
+}
+
+// resolve returns the profile a device uses: its own, else the global one (nil = none)
+func (p *streamProfiles) resolve(name string) *StreamProfile {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if name == "" {
+		name = p.global
+	}
+	profile, ok := p.byName[name]
+	if !ok {
+		return nil
+	}
+	return &profile
+}
+
+func (p *streamProfiles) exists(name string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.byName[name]
+	return ok
+}
+
+// SetStreamProfiles replaces the profile table (e.g. from LoadStreamProfiles at startup)
+func (s *StreamingService) SetStreamProfiles(profiles map[string]StreamProfile) {
+	s.profiles.mu.Lock()
+	defer s.profiles.mu.Unlock()
+	s.profiles.byName = maps.Clone(profiles)
+}
+
+// StreamProfiles returns the profile table and the global profile name
+func (s *StreamingService) StreamProfiles() (map[string]StreamProfile, string) {
+	s.profiles.mu.RLock()
+	defer s.profiles.mu.RUnlock()
+	return maps.Clone(s.profiles.byName), s.profiles.global
+}
+
+// SetGlobalProfile selects the profile for every device without its own ("" clears it)
+// Running streams that follow the global profile are restarted to apply it
+func (s *StreamingService) SetGlobalProfile(name string) error {
+	if name != "" && !s.profiles.exists(name) {
+		return fmt.Errorf("unknown profile: %s (have %v)", name, s.profileNames())
+	}
+
+	s.profiles.mu.Lock()
+	s.profiles.global = name
+	s.profiles.mu.Unlock()
+	log.Printf("🎚️ Global stream profile: %q", name)
+
+	s.mu.RLock()
+	streams := make([]*deviceStream, 0, len(s.streams))
+	for _, stream := range s.streams {
+		streams = append(streams, stream)
+	}
+	s.mu.RUnlock()
+
+	for _, stream := range streams {
+		stream.mu.Lock()
+		if stream.config.Profile == "" && stream.state == StateRunning {
+			stream.adaptive = adaptiveBitrate{}
+			s.restartSession(stream)
+		}
+		stream.mu.Unlock()
+	}
+	return nil
+}
+
+// SetStreamProfile selects a named profile for one device ("" follows the global profile)
+// Clears the device's explicit size/bitrate/fps/codec so the profile takes effect;
+// other settings (audio, stay awake, ...) are kept. Restarts the stream if RUNNING.
+func (s *StreamingService) SetStreamProfile(deviceID, name string) error {
+	if name != "" && !s.profiles.exists(name) {
+		return fmt.Errorf("unknown profile: %s (have %v)", name, s.profileNames())
+	}
+
+	stream, err := s.getOrCreateStream(deviceID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	cfg := stream.config
+	cfg.Profile = name
+	cfg.MaxSize, cfg.BitRate, cfg.MaxFPS, cfg.Codec = 0, 0, 0, ""
+	stream.config = cfg
+	stream.adaptive = adaptiveBitrate{}
+	log.Printf("🎚️ [%s] Stream profile: %q", deviceID, name)
+
+	if stream.state == StateRunning {
+		s.restartSession(stream)
+	}
+	return nil
+}
+
+// streamCodec returns the codec a stream runs with: explicit config, else its profile's
+func (s *StreamingService) streamCodec(cfg StreamConfig) string {
+	if cfg.Codec == "" {
+		if profile := s.profiles.resolve(cfg.Profile); profile != nil && profile.Codec != "" {
+			return profile.Codec
+		}
+	}
+	return cfg.ActiveCodec()
+}
+
+func (s *StreamingService) profileNames() []string {
+	s.profiles.mu.RLock()
+	defer s.profiles.mu.RUnlock()
+	return slices.Sorted(maps.Keys(s.profiles.byName))
+}
@@ -1,24 +1,30 @@
 package service
 
 import (
+	"androidcontrol/events"
+	"androidcontrol/logging"
+	"androidcontrol/models"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // WebSocketBroadcaster interface to avoid import cycle
+// Carries per-device media and topic data; status events go through the event bus
 type WebSocketBroadcaster interface {
 	BroadcastToDevice(deviceID string, message interface{})
-	BroadcastToAll(message interface{})
 }
 
-// Warm session TTL - keep stream alive after last viewer disconnects
-const warmSessionTTL = 120 * time.Second
+// Default warm session TTL - keep stream alive after last viewer disconnects
+// A TTL of 0 stops the stream as soon as viewers hit zero; a negative TTL never stops it
+const defaultWarmSessionTTL = 120 * time.Second
 
 // StreamState represents the lifecycle state of a device stream
 type StreamState int
@@ -35,12 +41,33 @@ func (s StreamState) String() string {
 	return [...]string{"STOPPED", "STARTING", "RUNNING", "IDLE", "STOPPING"}[s]
 }
 
+// StartOutcome reports what a StartStreaming call actually did
+type StartOutcome string
+
+const (
+	StartStarted        StartOutcome = "started"         // A fresh stream was launched
+	StartAlreadyRunning StartOutcome = "already_running" // Attached to a running (or idle) stream
+	StartStarting       StartOutcome = "starting"        // Another call is already starting it
+	StartBusyStopping   StartOutcome = "busy_stopping"   // Stream is shutting down, retry later
+)
+
+// ErrStreamStopping is returned when a start races with a stop still in progress
+var ErrStreamStopping = errors.New("stream is stopping, retry later")
+
 // StreamingService handles real-time screen streaming for devices
 type StreamingService struct {
 	deviceManager *DeviceManager
 	wsHub         WebSocketBroadcaster
 	streams       map[string]*deviceStream
 	mu            sync.RWMutex
+	logcats       logcatRegistry
+	stats         statsRegistry
+	inputRate     int                          // Touch MOVE cap per device per second (0 = unlimited)
+	warmTTL       time.Duration                // Default warm session TTL for devices without an override
+	reconnect     ReconnectPolicy              // Default scrcpy retry policy for streams without an override
+	profiles      streamProfiles               // Named encoder profiles (own lock)
+	caps          atomic.Pointer[Capabilities] // Startup preflight result (nil = assume everything is available)
+	bus           atomic.Pointer[events.Bus]   // Stream status events for WebSocket clients (nil = not published)
 }
 
 // deviceStream holds the device-scoped context and state
@@ -59,13 +86,64 @@ type deviceStream struct {
 	devCancel context.CancelFunc
 
 	// Viewer management
-	viewers   int         // Number of active WS subscribers
-	idleTimer *time.Timer // TTL countdown when viewers=0
+	viewers   map[string]int   // Active WS subscribers: client ID -> subscribed connections
+	idleTimer *time.Timer      // TTL countdown when viewers=0
+	warmTTL   *time.Duration   // Per-device warm session TTL (nil = service default)
+	reconnect *ReconnectPolicy // Per-device scrcpy retry policy (nil = service default)
+	paused    bool             // Explicitly paused by a client; blocks automatic restarts
 
 	// Cached headers for instant client attach
+	vpsPkt     []byte // H.265 only
 	spsPkt     []byte
 	ppsPkt     []byte
 	lastIDRPkt []byte
+	codec      string // Codec of the running session (h264/h265)
+
+	// Video size decoded from the latest SPS (raw_stream handshake doesn't report it)
+	videoWidth  int
+	videoHeight int
+
+	// Per-device encoder settings, applied on every scrcpy (re)start
+	config           StreamConfig
+	restartRequested bool // Set when the scrcpy session is restarted on purpose
+
+	// screenrecord fallback after repeated scrcpy failures (video only, no control)
+	degraded     bool
+	stopFallback func() // Ends the running screenrecord session (nil between sessions)
+
+	// Keyframe-on-demand: closed when the next IDR is broadcast
+	idrWaiters     []chan struct{}
+	lastResetVideo time.Time
+	lastIDRAt      time.Time
+
+	// Session lifecycle, set by runStream
+	startedAt         time.Time // When the current runStream began
+	reconnectAttempts int       // Reconnects since startedAt (failed starts and dropped sessions)
+	ptsBase           time.Time // Zero point for frame PTS, reset per scrcpy session (written under mu by runStream, which reads it unlocked)
+
+	// Bitrate override driven by WebSocket backpressure
+	adaptive adaptiveBitrate
+
+	// Touch MOVE coalescing, created on first touch (guarded by mu)
+	touch *touchLimiter
+
+	// Client holding the input lock ("" = anyone may send input, guarded by mu)
+	controlOwner string
+
+	// Display kept off while mirroring, re-applied after every scrcpy (re)start (guarded by mu)
+	displayOff bool
+
+	// Delivery metrics (own lock)
+	metrics streamMetrics
+
+	// Broadcast-side FPS cap (interval is atomic, the rest is owned by the NAL loop)
+	decimator frameDecimator
+
+	// scrcpy server output, kept across restarts (created on first newScrcpyClient, guarded by mu)
+	serverLogs *serverLogBuffer
+
+	// Active MP4 recording (nil when not recording) - atomic for the hot NAL path
+	recorder atomic.Pointer[recorder]
 }
 
 // NewStreamingService creates a new streaming service
@@ -74,32 +152,77 @@ func NewStreamingService(dm *DeviceManager, wsHub WebSocketBroadcaster) *Streami
 		deviceManager: dm,
 		wsHub:         wsHub,
 		streams:       make(map[string]*deviceStream),
+		logcats:       logcatRegistry{sessions: make(map[string]*logcatSession)},
+		stats:         statsRegistry{samplers: make(map[string]*statsSampler)},
+		warmTTL:       defaultWarmSessionTTL,
+		reconnect:     DefaultReconnectPolicy(),
+		profiles:      streamProfiles{byName: DefaultStreamProfiles()},
+	}
+}
+
+// SetDefaultWarmTTL sets the warm session TTL for devices without their own
+func (s *StreamingService) SetDefaultWarmTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.warmTTL = ttl
+}
+
+// SetWarmTTL overrides the warm session TTL for one device (0 = stop immediately, <0 = never stop)
+// An already idle stream is rescheduled with the new TTL
+func (s *StreamingService) SetWarmTTL(deviceID string, ttl time.Duration) error {
+	stream, err := s.getOrCreateStream(deviceID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	stream.warmTTL = &ttl
+	if stream.state == StateIdle {
+		s.armIdleTimer(stream, ttl)
 	}
+	return nil
+}
+
+// GetDevice returns a device's current info from the DeviceManager (nil if unknown)
+// Accepts a stream key, so a display stream resolves to its device
+func (s *StreamingService) GetDevice(deviceID string) *models.Device {
+	id, _ := ParseStreamKey(deviceID)
+	return s.deviceManager.GetDevice(id)
+}
+
+// SetInputRate sets the per-device touch MOVE cap (0 = unlimited)
+// Applies to devices whose first touch comes after the call; set it at startup
+func (s *StreamingService) SetInputRate(maxRate int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inputRate = maxRate
 }
 
 // StartStreaming starts or attaches to streaming for a device
-// Uses state machine to handle concurrent requests safely
-func (s *StreamingService) StartStreaming(deviceID string) error {
+// deviceID may be a stream key ("device_X@2") to mirror another display alongside the main one.
+// Uses state machine to handle concurrent requests safely; the outcome says which branch was taken
+func (s *StreamingService) StartStreaming(deviceID string) (StartOutcome, error) {
+	if err := s.requireStreaming(); err != nil {
+		return "", err
+	}
+
+	// Only online devices can stream (not offline, unauthorized or no_permissions)
+	device := s.GetDevice(deviceID)
+	if device == nil {
+		return "", fmt.Errorf("device not found: %s", deviceID)
+	}
+	if device.Status != models.DeviceStatusOnline {
+		return "", fmt.Errorf("device not available for streaming (%s): %s", device.Status, deviceID)
+	}
+
 	s.mu.Lock()
 
 	stream, exists := s.streams[deviceID]
 	if !exists {
 		// Create new stream entry
-		device := s.deviceManager.GetDevice(deviceID)
-		if device == nil {
-			s.mu.Unlock()
-			return fmt.Errorf("device not found: %s", deviceID)
-		}
-		if device.Status != "online" {
-			s.mu.Unlock()
-			return fmt.Errorf("device offline: %s", deviceID)
-		}
-
-		stream = &deviceStream{
-			deviceID:    deviceID,
-			deviceADBID: device.ADBDeviceID,
-			state:       StateStopped,
-		}
+		stream = newDeviceStream(deviceID, device.ADBDeviceID)
 		s.streams[deviceID] = stream
 	}
 	s.mu.Unlock()
@@ -108,7 +231,11 @@ func (s *StreamingService) StartStreaming(deviceID string) error {
 	stream.mu.Lock()
 	defer stream.mu.Unlock()
 
-	log.Printf("🚀 [%s] StartStreaming called (state=%s, viewers=%d)", deviceID, stream.state, stream.viewers)
+	logging.Device(deviceID).Info("stream_start_requested", "🚀 StartStreaming called (state=%s, viewers=%d)", stream.state, len(stream.viewers))
+
+	if stream.paused {
+		return "", fmt.Errorf("stream is paused (resume it first): %s", deviceID)
+	}
 
 	switch stream.state {
 	case StateRunning, StateIdle:
@@ -117,53 +244,59 @@ func (s *StreamingService) StartStreaming(deviceID string) error {
 		if stream.idleTimer != nil {
 			stream.idleTimer.Stop()
 			stream.idleTimer = nil
-			log.Printf("⏱️ [%s] Idle timer cancelled", deviceID)
+			logging.Device(deviceID).Info("idle_timer_cancelled", "⏱️ Idle timer cancelled")
 		}
 		if stream.state == StateIdle {
 			stream.state = StateRunning
-			log.Printf("▶️ [%s] Resuming from IDLE to RUNNING", deviceID)
+			logging.Device(deviceID).Info("stream_resumed", "▶️ Resuming from IDLE to RUNNING")
 		}
-		return nil
+		return StartAlreadyRunning, nil
 
 	case StateStarting:
 		// Already starting, just wait
-		log.Printf("⏳ [%s] Already starting, skipping duplicate start", deviceID)
-		return nil
+		logging.Device(deviceID).Info("start_skipped", "⏳ Already starting, skipping duplicate start")
+		return StartStarting, nil
 
 	case StateStopping:
 		// Wait for stop to complete, or return busy
-		log.Printf("⏳ [%s] Currently stopping, please retry", deviceID)
-		return fmt.Errorf("stream is stopping, retry later")
+		logging.Device(deviceID).Info("start_skipped", "⏳ Currently stopping, please retry")
+		return StartBusyStopping, ErrStreamStopping
 
 	case StateStopped:
 		// Start fresh
 		stream.state = StateStarting
-		log.Printf("🆕 [%s] Starting fresh stream", deviceID)
+		logging.Device(deviceID).Info("stream_starting", "🆕 Starting fresh stream")
 
 		// Create device-scoped context
 		stream.devCtx, stream.devCancel = context.WithCancel(context.Background())
 
 		// Create scrcpy client
-		adbClient := s.deviceManager.GetADBClient()
-		stream.scrcpyClient = NewScrcpyClient(adbClient, stream.deviceADBID)
+		stream.scrcpyClient = s.newScrcpyClient(stream)
 
 		// Start streaming goroutine
 		go s.runStream(stream)
-		return nil
+		return StartStarted, nil
 	}
 
-	return nil
+	return "", fmt.Errorf("unknown stream state: %s", stream.state)
 }
 
 // runStream manages the scrcpy streaming lifecycle for a device
 // Includes auto-reconnect on unexpected stream termination
 func (s *StreamingService) runStream(stream *deviceStream) {
-	const maxReconnectAttempts = 3
 	reconnectAttempt := 0
+	var policy ReconnectPolicy
+
+	stream.mu.Lock()
+	stream.startedAt = time.Now()
+	stream.reconnectAttempts = 0
+	stream.mu.Unlock()
 
 	defer func() {
+		stream.stopRecordingOnExit()
+
 		stream.mu.Lock()
-		log.Printf("🛑 [%s] Stream goroutine ending (state=%s)", stream.deviceID, stream.state)
+		logging.Device(stream.deviceID).Info("stream_goroutine_ended", "🛑 Stream goroutine ending (state=%s)", stream.state)
 		stream.state = StateStopped
 		if stream.scrcpyClient != nil {
 			stream.scrcpyClient.Stop()
@@ -171,101 +304,358 @@ func (s *StreamingService) runStream(stream *deviceStream) {
 		}
 		stream.devCancel = nil
 		stream.devCtx = nil
+		stream.displayOff = false // The server turned the display back on as it exited
 		stream.mu.Unlock()
 	}()
 
-	for reconnectAttempt <= maxReconnectAttempts {
+	startFailed := false
+	for {
+		// Re-read each round so an override set while retrying applies to the next wait
+		policy = s.reconnectPolicy(stream)
+		if reconnectAttempt > policy.MaxAttempts {
+			// scrcpy keeps failing: keep the tile alive with screenrecord (video only, no control)
+			if !s.runScreenrecordFallback(stream) {
+				break
+			}
+			reconnectAttempt = 0
+		}
+
+		// After a failed start, clear orphaned servers unless another display of the device
+		// is streaming (killing leftovers would take its server down too)
+		cleanStart := false
+		if startFailed {
+			deviceID, _ := ParseStreamKey(stream.deviceID)
+			cleanStart = len(s.activeStreamKeys(deviceID)) <= 1
+		}
+
 		// Start scrcpy and get the connection
 		stream.mu.Lock()
 		if stream.state != StateStarting && stream.state != StateRunning {
-			log.Printf("⚠️ [%s] State changed during startup, aborting", stream.deviceID)
+			logging.Device(stream.deviceID).Warn("start_aborted", "⚠️ State changed during startup, aborting")
 			stream.mu.Unlock()
 			return
 		}
 
 		// If reconnecting, recreate scrcpy client
 		if reconnectAttempt > 0 {
-			log.Printf("🔄 [%s] Reconnect attempt %d/%d", stream.deviceID, reconnectAttempt, maxReconnectAttempts)
+			stream.reconnectAttempts++
+			logging.Device(stream.deviceID).Info("reconnect_attempt", "🔄 Reconnect attempt %d/%d", reconnectAttempt, policy.MaxAttempts)
 			if stream.scrcpyClient != nil {
 				stream.scrcpyClient.Stop()
 			}
-			adbClient := s.deviceManager.GetADBClient()
-			stream.scrcpyClient = NewScrcpyClient(adbClient, stream.deviceADBID)
+			stream.scrcpyClient = s.newScrcpyClient(stream)
 		}
 
 		scrcpyClient := stream.scrcpyClient
 		stream.mu.Unlock()
 
+		scrcpyClient.SetCleanStart(cleanStart)
 		conn, err := scrcpyClient.Start()
+		startFailed = err != nil
 		if err != nil {
-			log.Printf("❌ [%s] Failed to start scrcpy (attempt %d): %v", stream.deviceID, reconnectAttempt+1, err)
+			logging.Device(stream.deviceID).Error("scrcpy_start_failed", "❌ Failed to start scrcpy (attempt %d): %v", reconnectAttempt+1, err)
 			reconnectAttempt++
-			if reconnectAttempt <= maxReconnectAttempts {
-				// Exponential backoff: 2s, 4s, 8s
-				backoff := time.Duration(1<<reconnectAttempt) * time.Second
-				log.Printf("⏳ [%s] Waiting %v before retry...", stream.deviceID, backoff)
-				time.Sleep(backoff)
-				continue
+			if reconnectAttempt <= policy.MaxAttempts && s.waitRetry(stream, policy, reconnectAttempt) != nil {
+				return // Stopped during the backoff
 			}
-			return
+			continue
 		}
 
 		// Transition to RUNNING
 		stream.mu.Lock()
 		if stream.state != StateStarting && stream.state != StateRunning {
-			log.Printf("⚠️ [%s] State changed during scrcpy connect, aborting", stream.deviceID)
+			logging.Device(stream.deviceID).Warn("start_aborted", "⚠️ State changed during scrcpy connect, aborting")
 			stream.mu.Unlock()
 			return
 		}
 		stream.state = StateRunning
 		ctx := stream.devCtx
-		log.Printf("✅ [%s] Stream now RUNNING (attempt %d)", stream.deviceID, reconnectAttempt+1)
+		codec := s.streamCodec(stream.config)
+		if stream.codec != codec {
+			// Cached headers from the previous codec can't be decoded anymore
+			stream.vpsPkt, stream.spsPkt, stream.ppsPkt, stream.lastIDRPkt = nil, nil, nil, nil
+			stream.codec = codec
+		}
+		logging.Device(stream.deviceID).Info("stream_running", "✅ Stream now RUNNING (attempt %d)", reconnectAttempt+1)
+		displayOff := stream.displayOff
 		stream.mu.Unlock()
+		s.broadcastStreamStatus(stream.deviceID, streamStatusRunning, reconnectAttempt, policy.MaxAttempts)
+
+		// A new server session starts with the display on
+		if displayOff {
+			if err := scrcpyClient.SetDisplayPower(false); err != nil {
+				logging.Device(stream.deviceID).Warn("display_power_failed", "⚠️ Failed to turn display off again: %v", err)
+			}
+		}
 
 		// TCP optimizations
-		if tc, ok := conn.(*net.TCPConn); ok {
+		tcpConn := conn
+		if fm, ok := conn.(*frameMetaConn); ok {
+			tcpConn = fm.Conn
+		}
+		if tc, ok := tcpConn.(*net.TCPConn); ok {
 			tc.SetNoDelay(true)
 			tc.SetReadBuffer(1 << 20)
 			tc.SetWriteBuffer(1 << 20)
 		}
 
-		log.Printf("🎬 [%s] Started H.264 stream from scrcpy", stream.deviceID)
+		logging.Device(stream.deviceID).Info("stream_started", "🎬 Started %s stream from scrcpy", codec)
+
+		// Frame PTS restarts with every scrcpy session
+		ptsBase := time.Now()
+		stream.mu.Lock()
+		stream.ptsBase = ptsBase
+		stream.mu.Unlock()
+		if audioConn := scrcpyClient.AudioConn(); audioConn != nil {
+			go s.pumpAudio(ctx, stream.deviceID, audioConn, ptsBase)
+		}
 
-		// Consume H.264 stream (blocks until stream ends or context cancelled)
+		// Consume Annex-B stream (blocks until stream ends or context cancelled)
 		streamStartTime := time.Now()
-		s.consumeH264(ctx, stream.deviceID, conn)
+		stream.metrics.reset()
+		s.consumeH264(ctx, stream, codec, conn)
 		streamDuration := time.Since(streamStartTime)
 
 		// Check if stream was cancelled by user or stopped externally
 		stream.mu.Lock()
 		if stream.state == StateStopping || stream.state == StateStopped {
-			log.Printf("🛑 [%s] Stream stopped by user", stream.deviceID)
+			logging.Device(stream.deviceID).Info("stream_stopped", "🛑 Stream stopped by user")
 			stream.mu.Unlock()
 			return
 		}
+
+		// Intentional restart (e.g. config change) - reconnect immediately with a fresh client
+		if stream.restartRequested {
+			stream.restartRequested = false
+			stream.scrcpyClient = s.newScrcpyClient(stream)
+			stream.mu.Unlock()
+			logging.Device(stream.deviceID).Info("session_restarting", "🔁 Restarting scrcpy session with new config")
+			reconnectAttempt = 0
+			continue
+		}
 		stream.mu.Unlock()
 
 		// If stream lasted less than 5 seconds, it's likely an encoder crash - retry
 		if streamDuration < 5*time.Second {
 			reconnectAttempt++
-			log.Printf("⚠️ [%s] Stream died after %v (attempt %d) - will retry",
-				stream.deviceID, streamDuration.Round(time.Millisecond), reconnectAttempt)
-			if reconnectAttempt <= maxReconnectAttempts {
-				backoff := time.Duration(1<<reconnectAttempt) * time.Second
-				log.Printf("⏳ [%s] Waiting %v before retry...", stream.deviceID, backoff)
-				time.Sleep(backoff)
+			logging.Device(stream.deviceID).Warn("stream_died", "⚠️ Stream died after %v (attempt %d) - will retry",
+				streamDuration.Round(time.Millisecond), reconnectAttempt)
+			if reconnectAttempt <= policy.MaxAttempts {
+				if s.waitRetry(stream, policy, reconnectAttempt) != nil {
+					return // Stopped during the backoff
+				}
 				continue
 			}
 		} else {
 			// Stream lasted a reasonable time, just reconnect without incrementing attempts
-			log.Printf("📺 [%s] Stream ended after %v, reconnecting...", stream.deviceID, streamDuration.Round(time.Second))
+			logging.Device(stream.deviceID).Info("stream_ended", "📺 Stream ended after %v, reconnecting...", streamDuration.Round(time.Second))
 			reconnectAttempt = 0 // Reset since it worked for a while
+			stream.mu.Lock()
+			stream.reconnectAttempts++
+			stream.mu.Unlock()
+			s.broadcastStreamStatus(stream.deviceID, streamStatusReconnecting, reconnectAttempt+1, policy.MaxAttempts) // Not counted against the limit
 			time.Sleep(500 * time.Millisecond)
 			continue
 		}
 	}
 
-	log.Printf("❌ [%s] Giving up after %d reconnect attempts", stream.deviceID, maxReconnectAttempts)
+	stream.mu.Lock()
+	stopped := stream.state == StateStopping || stream.state == StateStopped
+	stream.mu.Unlock()
+	if stopped {
+		return
+	}
+	logging.Device(stream.deviceID).Error("reconnect_gave_up", "❌ Giving up after %d reconnect attempts and screenrecord fallback", policy.MaxAttempts)
+	s.broadcastStreamStatus(stream.deviceID, streamStatusFailed, reconnectAttempt-1, policy.MaxAttempts)
+}
+
+// waitRetry waits the policy's backoff before retry attempt n, telling viewers a reconnect is coming
+// Returns the context error if the stream is stopped while waiting
+func (s *StreamingService) waitRetry(stream *deviceStream, policy ReconnectPolicy, attempt int) error {
+	stream.mu.Lock()
+	ctx := stream.devCtx
+	stream.mu.Unlock()
+	if ctx == nil {
+		return context.Canceled
+	}
+
+	backoff := policy.Backoff(attempt)
+	logging.Device(stream.deviceID).Info("retry_backoff", "⏳ Waiting %v before retry...", backoff)
+	s.broadcastStreamStatus(stream.deviceID, streamStatusReconnecting, attempt, policy.MaxAttempts)
+
+	timer := retryTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryTimer starts the wait between reconnect attempts (a var so the schedule can be checked without sleeping)
+var retryTimer = time.NewTimer
+
+// Stream status values broadcast to subscribers as {type:"stream_status"}
+const (
+	streamStatusRunning      = "running"
+	streamStatusReconnecting = "reconnecting"
+	streamStatusFailed       = "failed"
+	streamStatusPaused       = "paused"
+	streamStatusDegraded     = "degraded" // screenrecord fallback: video only, no control
+)
+
+// broadcastStreamStatus tells a device's subscribers about a reconnect transition
+// so the UI can show a spinner or a failure instead of a frozen frame
+func (s *StreamingService) broadcastStreamStatus(deviceID, state string, attempt, maxAttempts int) {
+	s.bus.Load().Publish(events.Event{Type: "stream_status", Topic: deviceID, Payload: map[string]interface{}{
+		"type":         "stream_status",
+		"device_id":    deviceID,
+		"state":        state,
+		"attempt":      attempt,
+		"max_attempts": maxAttempts,
+	}})
+}
+
+// SetEventBus sets where stream status events are published for WebSocket clients
+func (s *StreamingService) SetEventBus(bus *events.Bus) {
+	s.bus.Store(bus)
+}
+
+// newScrcpyClient creates a scrcpy client carrying the stream's config
+// Must be called while holding stream.mu
+func (s *StreamingService) newScrcpyClient(stream *deviceStream) *ScrcpyClient {
+	client := NewScrcpyClient(s.deviceManager.GetADBClient(), stream.deviceADBID)
+	cfg := stream.config
+	if stream.adaptive.bitRate > 0 {
+		cfg.BitRate = stream.adaptive.bitRate
+	}
+	client.SetStreamConfig(cfg)
+	client.SetStreamProfile(s.profiles.resolve(cfg.Profile))
+
+	if stream.serverLogs == nil {
+		stream.serverLogs = client.serverLogs
+	}
+	client.serverLogs = stream.serverLogs
+	return client
+}
+
+// ServerLogs returns up to n of the latest scrcpy server output lines for a device
+func (s *StreamingService) ServerLogs(deviceID string, n int) ([]string, error) {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("no stream for device: %s", deviceID)
+	}
+
+	stream.mu.Lock()
+	logs := stream.serverLogs
+	stream.mu.Unlock()
+	if logs == nil {
+		return []string{}, nil // Never started
+	}
+	return logs.Last(n), nil
+}
+
+// SetStreamConfig stores encoder settings for a device
+// If the stream is RUNNING, the scrcpy session is restarted so they take effect
+func (s *StreamingService) SetStreamConfig(deviceID string, cfg StreamConfig) error {
+	if cfg.MaxSize < 0 || cfg.BitRate < 0 || cfg.MaxFPS < 0 {
+		return fmt.Errorf("invalid stream config: values must be >= 0")
+	}
+	if cfg.Codec != "" && cfg.Codec != CodecH264 && cfg.Codec != CodecH265 {
+		return fmt.Errorf("invalid codec: %s (expected %s or %s)", cfg.Codec, CodecH264, CodecH265)
+	}
+	if cfg.Profile != "" && !s.profiles.exists(cfg.Profile) {
+		return fmt.Errorf("unknown profile: %s (have %v)", cfg.Profile, s.profileNames())
+	}
+	if err := validateCodecOptions(cfg.CodecOptions); err != nil {
+		return err
+	}
+
+	stream, err := s.getOrCreateStream(deviceID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	_, cfg.DisplayID = ParseStreamKey(deviceID) // The display is part of the stream's identity
+	stream.config = cfg
+	stream.adaptive = adaptiveBitrate{} // Explicit settings win over adaptation
+	logging.Device(deviceID).Info("config_set", "⚙️ Stream config set: profile=%q maxSize=%d bitRate=%d maxFps=%d codec=%s stayAwake=%t wakeOnStart=%t showTouches=%t codecOptions=%q",
+		cfg.Profile, cfg.MaxSize, cfg.BitRate, cfg.MaxFPS, s.streamCodec(cfg), cfg.StayAwake, cfg.WakeOnStart, cfg.ShowTouches, codecOptionsArg(cfg.CodecOptions))
+
+	if stream.state == StateRunning {
+		s.restartSession(stream)
+	}
+	return nil
+}
+
+// GetStreamConfig returns the encoder settings for a device
+func (s *StreamingService) GetStreamConfig(deviceID string) StreamConfig {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return StreamConfig{}
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	return stream.config
+}
+
+// restartSession stops the current scrcpy client so runStream reconnects
+// Must be called while holding stream.mu
+func (s *StreamingService) restartSession(stream *deviceStream) {
+	if stream.stopFallback != nil {
+		// Degraded: end screenrecord so runStream tries scrcpy again with the new config
+		stream.restartRequested = true
+		stream.stopFallback()
+		return
+	}
+	if stream.scrcpyClient == nil {
+		return
+	}
+	stream.restartRequested = true
+	logging.Device(stream.deviceID).Info("session_restart_requested", "🔁 Scrcpy session restart requested")
+
+	// Closing the socket unblocks consumeH264; Stop may wait on Start, so don't hold stream.mu
+	go stream.scrcpyClient.Stop()
+}
+
+// getOrCreateStream returns the stream entry for a device, creating a STOPPED one if needed
+func (s *StreamingService) getOrCreateStream(deviceID string) (*deviceStream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stream, exists := s.streams[deviceID]; exists {
+		return stream, nil
+	}
+
+	device := s.GetDevice(deviceID)
+	if device == nil {
+		return nil, fmt.Errorf("device not found: %s", deviceID)
+	}
+
+	stream := newDeviceStream(deviceID, device.ADBDeviceID)
+	s.streams[deviceID] = stream
+	return stream, nil
+}
+
+// newDeviceStream creates a STOPPED stream entry; a display in the key is pinned in its config
+func newDeviceStream(key, deviceADBID string) *deviceStream {
+	_, displayID := ParseStreamKey(key)
+	return &deviceStream{
+		deviceID:    key,
+		deviceADBID: deviceADBID,
+		state:       StateStopped,
+		config:      StreamConfig{DisplayID: displayID},
+	}
 }
 
 // StopStreaming stops streaming for a specific device (force stop)
@@ -275,14 +665,14 @@ func (s *StreamingService) StopStreaming(deviceID string) error {
 	s.mu.RUnlock()
 
 	if !exists {
-		log.Printf("⚠️ [%s] StopStreaming called but stream not found", deviceID)
+		logging.Device(deviceID).Warn("stop_unknown_stream", "⚠️ StopStreaming called but stream not found")
 		return nil
 	}
 
 	stream.mu.Lock()
 	defer stream.mu.Unlock()
 
-	log.Printf("� [%s] StopStreaming called (state=%s)", deviceID, stream.state)
+	logging.Device(deviceID).Info("stream_stopping", "⏹️ StopStreaming called (state=%s)", stream.state)
 
 	if stream.state == StateStopped || stream.state == StateStopping {
 		return nil
@@ -304,8 +694,44 @@ func (s *StreamingService) StopStreaming(deviceID string) error {
 	return nil
 }
 
-// AddViewer increments the viewer count for a device
-func (s *StreamingService) AddViewer(deviceID string) {
+// PauseStreaming stops a device's capture until ResumeStreaming, regardless of viewers
+// Unlike StopStreaming, device events and StartAllStreaming won't restart it
+func (s *StreamingService) PauseStreaming(deviceID string) error {
+	stream, err := s.getOrCreateStream(deviceID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	stream.paused = true
+	stream.mu.Unlock()
+
+	logging.Device(deviceID).Info("stream_paused", "⏸️ Stream paused by client")
+	s.broadcastStreamStatus(deviceID, streamStatusPaused, 0, 0)
+	return s.StopStreaming(deviceID)
+}
+
+// ResumeStreaming clears a pause and starts the stream again
+func (s *StreamingService) ResumeStreaming(deviceID string) error {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+
+	if exists {
+		stream.mu.Lock()
+		stream.paused = false
+		stream.mu.Unlock()
+	}
+
+	logging.Device(deviceID).Info("stream_resumed", "▶️ Stream resumed by client")
+	_, err := s.StartStreaming(deviceID)
+	return err
+}
+
+// AddViewer records a client watching a device
+// Viewers are counted per client ID, so a client that reconnects and subscribes again
+// before its old socket is reaped counts once
+func (s *StreamingService) AddViewer(deviceID, clientID string) {
 	s.mu.RLock()
 	stream, exists := s.streams[deviceID]
 	s.mu.RUnlock()
@@ -315,29 +741,41 @@ func (s *StreamingService) AddViewer(deviceID string) {
 	}
 
 	stream.mu.Lock()
-	defer stream.mu.Unlock()
-
-	stream.viewers++
-	log.Printf("�️ [%s] Viewer added (total: %d, state: %s)", deviceID, stream.viewers, stream.state)
+	if stream.viewers == nil {
+		stream.viewers = make(map[string]int)
+	}
+	stream.viewers[clientID]++
+	count := len(stream.viewers)
+	if stream.viewers[clientID] > 1 {
+		logging.Device(deviceID).Info("viewer_resubscribed", "👁️ Viewer %s resubscribed (connections: %d, total: %d)", clientID, stream.viewers[clientID], count)
+		stream.mu.Unlock()
+		return
+	}
+	logging.Device(deviceID).Info("viewer_added", "👁️ Viewer added (total: %d, state: %s)", count, stream.state)
 
 	// Cancel idle timer if exists
 	if stream.idleTimer != nil {
 		stream.idleTimer.Stop()
 		stream.idleTimer = nil
-		log.Printf("⏱️ [%s] Idle timer cancelled by new viewer", deviceID)
+		logging.Device(deviceID).Info("idle_timer_cancelled", "⏱️ Idle timer cancelled by new viewer")
 	}
 
 	// Resume from idle if needed
 	if stream.state == StateIdle {
 		stream.state = StateRunning
-		log.Printf("▶️ [%s] Resumed from IDLE to RUNNING", deviceID)
+		logging.Device(deviceID).Info("stream_resumed", "▶️ Resumed from IDLE to RUNNING")
 	}
+	stream.mu.Unlock()
+
+	s.broadcastViewerCount(deviceID, count)
 }
 
-// RemoveViewer decrements the viewer count and starts idle timer if no viewers
-func (s *StreamingService) RemoveViewer(deviceID string) {
+// RemoveViewer drops one of a client's subscribed connections and starts idle timer if no viewers
+// The client stops counting as a viewer once its last connection is gone
+func (s *StreamingService) RemoveViewer(deviceID, clientID string) {
 	s.mu.RLock()
 	stream, exists := s.streams[deviceID]
+	ttl := s.warmTTL
 	s.mu.RUnlock()
 
 	if !exists {
@@ -345,24 +783,74 @@ func (s *StreamingService) RemoveViewer(deviceID string) {
 	}
 
 	stream.mu.Lock()
-	defer stream.mu.Unlock()
-
-	if stream.viewers > 0 {
-		stream.viewers--
+	changed := false
+	if refs := stream.viewers[clientID]; refs > 1 {
+		stream.viewers[clientID] = refs - 1
+	} else if refs == 1 {
+		delete(stream.viewers, clientID)
+		changed = true
+	}
+	count := len(stream.viewers)
+	if changed {
+		logging.Device(deviceID).Info("viewer_removed", "👁️ Viewer removed (remaining: %d, state: %s)", count, stream.state)
 	}
-	log.Printf("👁️ [%s] Viewer removed (remaining: %d, state: %s)", deviceID, stream.viewers, stream.state)
 
 	// Start idle timer if no viewers and currently running
-	if stream.viewers == 0 && stream.state == StateRunning {
+	if count == 0 && stream.state == StateRunning {
+		if stream.warmTTL != nil {
+			ttl = *stream.warmTTL
+		}
 		stream.state = StateIdle
-		log.Printf("⏸️ [%s] Entering IDLE state, starting %.0fs timer", deviceID, warmSessionTTL.Seconds())
+		s.armIdleTimer(stream, ttl)
+	}
+	stream.mu.Unlock()
+
+	if changed {
+		s.broadcastViewerCount(deviceID, count)
+	}
+}
 
-		stream.idleTimer = time.AfterFunc(warmSessionTTL, func() {
+// broadcastViewerCount tells a device's subscribers how many clients are watching it
+func (s *StreamingService) broadcastViewerCount(deviceID string, count int) {
+	s.wsHub.BroadcastToDevice(deviceID, map[string]interface{}{
+		"type":      "viewer_count",
+		"device_id": deviceID,
+		"count":     count,
+	})
+}
+
+// armIdleTimer (re)starts the idle countdown for an IDLE stream (must hold stream.mu)
+func (s *StreamingService) armIdleTimer(stream *deviceStream, ttl time.Duration) {
+	if stream.idleTimer != nil {
+		stream.idleTimer.Stop()
+		stream.idleTimer = nil
+	}
+
+	switch {
+	case ttl < 0:
+		logging.Device(stream.deviceID).Info("stream_idle", "⏸️ Entering IDLE state, kept warm indefinitely")
+	case ttl == 0:
+		logging.Device(stream.deviceID).Info("idle_stop", "💤 No warm session, stopping stream")
+		s.stopIdleStream(stream)
+	default:
+		logging.Device(stream.deviceID).Info("stream_idle", "⏸️ Entering IDLE state, starting %.0fs timer", ttl.Seconds())
+		deviceID := stream.deviceID
+		stream.idleTimer = time.AfterFunc(ttl, func() {
 			s.handleIdleTimeout(deviceID)
 		})
 	}
 }
 
+// stopIdleStream cancels an idle stream's goroutine (must hold stream.mu)
+func (s *StreamingService) stopIdleStream(stream *deviceStream) {
+	stream.state = StateStopping
+
+	// Cancel device context to stop goroutine
+	if stream.devCancel != nil {
+		stream.devCancel()
+	}
+}
+
 // handleIdleTimeout is called when idle timer expires
 func (s *StreamingService) handleIdleTimeout(deviceID string) {
 	s.mu.RLock()
@@ -377,45 +865,49 @@ func (s *StreamingService) handleIdleTimeout(deviceID string) {
 	defer stream.mu.Unlock()
 
 	// Only kill if still idle with no viewers
-	if stream.viewers == 0 && stream.state == StateIdle {
-		log.Printf("💤 [%s] Idle timeout reached, stopping warm stream", deviceID)
-		stream.state = StateStopping
-
-		// Cancel device context to stop goroutine
-		if stream.devCancel != nil {
-			stream.devCancel()
-		}
+	if len(stream.viewers) == 0 && stream.state == StateIdle {
+		logging.Device(deviceID).Info("idle_timeout", "💤 Idle timeout reached, stopping warm stream")
+		s.stopIdleStream(stream)
 	} else {
-		log.Printf("⏱️ [%s] Idle timeout ignored (viewers=%d, state=%s)", deviceID, stream.viewers, stream.state)
+		logging.Device(deviceID).Info("idle_timeout_ignored", "⏱️ Idle timeout ignored (viewers=%d, state=%s)", len(stream.viewers), stream.state)
 	}
 }
 
-// GetStreamData returns cached SPS, PPS, and last IDR for instant decode
-func (s *StreamingService) GetStreamData(deviceID string) (sps, pps, idr []byte) {
+// GetStreamData returns cached parameter sets and last IDR for instant decode
+// Packets are in decode order: [VPS (H.265 only)], SPS, PPS, IDR - missing ones are skipped
+func (s *StreamingService) GetStreamData(deviceID string) [][]byte {
 	s.mu.RLock()
 	stream, exists := s.streams[deviceID]
 	s.mu.RUnlock()
 
 	if !exists {
-		return nil, nil, nil
+		return nil
 	}
 
 	stream.mu.Lock()
 	defer stream.mu.Unlock()
 
-	if stream.spsPkt != nil {
-		sps = make([]byte, len(stream.spsPkt))
-		copy(sps, stream.spsPkt)
-	}
-	if stream.ppsPkt != nil {
-		pps = make([]byte, len(stream.ppsPkt))
-		copy(pps, stream.ppsPkt)
+	var pkts [][]byte
+	for _, cached := range [][]byte{stream.vpsPkt, stream.spsPkt, stream.ppsPkt, stream.lastIDRPkt} {
+		if cached != nil {
+			pkt := make([]byte, len(cached))
+			copy(pkt, cached)
+			pkts = append(pkts, pkt)
+		}
 	}
-	if stream.lastIDRPkt != nil {
-		idr = make([]byte, len(stream.lastIDRPkt))
-		copy(idr, stream.lastIDRPkt)
+	return pkts
+}
+
+// GetStreamMetrics returns delivered FPS, bitrate (kbps) and total frames for a device
+func (s *StreamingService) GetStreamMetrics(deviceID string) (fps float64, kbps float64, frames int64) {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return 0, 0, 0
 	}
-	return sps, pps, idr
+	return stream.metrics.snapshot()
 }
 
 // GetViewerCount returns the current viewer count for a device
@@ -430,7 +922,25 @@ func (s *StreamingService) GetViewerCount(deviceID string) int {
 
 	stream.mu.Lock()
 	defer stream.mu.Unlock()
-	return stream.viewers
+	return len(stream.viewers)
+}
+
+// GetViewerCounts returns the viewer count of every known device stream
+func (s *StreamingService) GetViewerCounts() map[string]int {
+	s.mu.RLock()
+	streams := make([]*deviceStream, 0, len(s.streams))
+	for _, stream := range s.streams {
+		streams = append(streams, stream)
+	}
+	s.mu.RUnlock()
+
+	counts := make(map[string]int, len(streams))
+	for _, stream := range streams {
+		stream.mu.Lock()
+		counts[stream.deviceID] = len(stream.viewers)
+		stream.mu.Unlock()
+	}
+	return counts
 }
 
 // IsStreaming checks if a device is currently streaming
@@ -448,18 +958,19 @@ func (s *StreamingService) IsStreaming(deviceID string) bool {
 	return stream.state == StateRunning || stream.state == StateIdle || stream.state == StateStarting
 }
 
-// consumeH264 reads raw H.264 stream and broadcasts NAL units
-func (s *StreamingService) consumeH264(ctx context.Context, deviceID string, r io.Reader) {
-	log.Printf("🎬 Consuming H.264 stream: %s", deviceID)
+// consumeH264 reads a raw Annex-B stream (H.264 or H.265) and broadcasts NAL units
+func (s *StreamingService) consumeH264(ctx context.Context, stream *deviceStream, codec string, r io.Reader) {
+	deviceID := stream.deviceID
+	logging.Device(deviceID).Info("stream_consuming", "🎬 Consuming %s stream", codec)
 
-	accBuf := make([]byte, 0, 1024*1024)
+	splitter := newNALSplitter()
 	readBuf := make([]byte, 65536)
 	frameCount := 0
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("⏹️ [%s] Stream context cancelled", deviceID)
+			logging.Device(deviceID).Info("stream_cancelled", "⏹️ Stream context cancelled")
 			return
 		default:
 		}
@@ -470,10 +981,12 @@ func (s *StreamingService) consumeH264(ctx context.Context, deviceID string, r i
 
 		n, err := r.Read(readBuf)
 		if n > 0 {
-			if len(accBuf) == 0 && frameCount == 0 {
-				log.Printf("� [%s] First data chunk received: %d bytes", deviceID, n)
+			if splitter.buffered() == 0 && frameCount == 0 {
+				logging.Device(deviceID).Info("first_chunk", "📥 First data chunk received: %d bytes", n)
+			}
+			if !splitter.write(readBuf[:n]) {
+				logging.Device(deviceID).Warn("nal_resync", "⚠️ No NAL boundary within %d bytes - dropped buffer, resyncing on next start code", maxNALBufferSize)
 			}
-			accBuf = append(accBuf, readBuf[:n]...)
 		}
 
 		if err != nil {
@@ -483,169 +996,443 @@ func (s *StreamingService) consumeH264(ctx context.Context, deviceID string, r i
 			// Handle "connection reset by peer" - can happen with ADB WiFi
 			errStr := err.Error()
 			if strings.Contains(errStr, "connection reset") || strings.Contains(errStr, "forcibly closed") {
-				log.Printf("⚠️ [%s] Connection reset, retrying...", deviceID)
+				logging.Device(deviceID).Warn("connection_reset", "⚠️ Connection reset, retrying...")
 				time.Sleep(200 * time.Millisecond)
 				continue
 			}
 			// Specific EOF logging for WiFi/encoder debugging
 			if err == io.EOF {
-				log.Printf("⚠️ [%s] Stream closed by remote device (EOF) - Check WiFi stability or device encoder", deviceID)
+				logging.Device(deviceID).Warn("stream_eof", "⚠️ Stream closed by remote device (EOF) - Check WiFi stability or device encoder")
 			} else if !strings.Contains(errStr, "use of closed network connection") {
-				log.Printf("❌ [%s] Stream read error: %v", deviceID, err)
+				logging.Device(deviceID).Error("stream_read_error", "❌ Stream read error: %v", err)
 			}
 			return
 		}
 
 		// Extract and broadcast NAL units
 		for {
-			nalData, remaining := extractNAL(accBuf)
+			nalData := splitter.next()
 			if nalData == nil {
 				break
 			}
-			accBuf = remaining
-			s.broadcastNAL(deviceID, nalData, &frameCount)
+			s.broadcastNAL(stream, codec, nalData, &frameCount)
 		}
 	}
 }
 
-// extractNAL extracts a single NAL unit from buffer
-func extractNAL(buf []byte) (nalData []byte, remaining []byte) {
-	if len(buf) < 4 {
-		return nil, buf
+// maxNALBufferSize is the safety ceiling for a NAL still waiting for its end
+// Large IDR frames on a slow link are fine; a buffer this big means the stream is corrupt
+const maxNALBufferSize = 4 * 1024 * 1024
+
+// nalSplitter accumulates Annex-B bytes and yields complete NAL units (including start code)
+// A NAL is only emitted once the next start code has been seen, never truncated.
+// It remembers how far it has scanned so a large NAL arriving in small chunks isn't rescanned.
+type nalSplitter struct {
+	buf     []byte
+	scanned int // Length of buf already searched for the next start code
+}
+
+func newNALSplitter() *nalSplitter {
+	return &nalSplitter{buf: make([]byte, 0, 1024*1024)}
+}
+
+// buffered returns the number of bytes waiting for a NAL boundary
+func (sp *nalSplitter) buffered() int {
+	return len(sp.buf)
+}
+
+// write appends stream data. Returns false if the buffer hit maxNALBufferSize and was reset
+func (sp *nalSplitter) write(data []byte) bool {
+	ok := true
+	if len(sp.buf)+len(data) > maxNALBufferSize {
+		sp.buf = sp.buf[:0]
+		sp.scanned = 0
+		ok = false
 	}
+	sp.buf = append(sp.buf, data...)
+	return ok
+}
 
-	startIdx := findStartCodeIndex(buf)
+// next returns the next complete NAL unit, or nil if more data is needed
+// Bytes before the first start code are discarded. The returned slice is only
+// valid until the next write.
+func (sp *nalSplitter) next() []byte {
+	startIdx, startLen := findStartCode(sp.buf, 0)
 	if startIdx < 0 {
-		return nil, buf
+		return nil
+	}
+	if startIdx > 0 {
+		sp.buf = sp.buf[startIdx:]
+		sp.scanned = 0
 	}
 
-	searchStart := startIdx + 3
-	if len(buf) > startIdx+3 && buf[startIdx+2] == 0 {
-		searchStart = startIdx + 4
+	// Resume where the last scan stopped; back off 3 bytes so a start code
+	// (or the leading zero of a 4-byte one) split across writes is still found
+	from := max(startLen, sp.scanned-3)
+	nextIdx, _ := findStartCode(sp.buf, from)
+	if nextIdx < 0 {
+		sp.scanned = len(sp.buf)
+		return nil
 	}
 
-	nextIdx := -1
-	for i := searchStart; i < len(buf)-2; i++ {
-		if buf[i] == 0 && buf[i+1] == 0 && (buf[i+2] == 1 || (buf[i+2] == 0 && i+3 < len(buf) && buf[i+3] == 1)) {
-			nextIdx = i
-			break
+	nal := sp.buf[:nextIdx]
+	sp.buf = sp.buf[nextIdx:]
+	sp.scanned = 0
+	return nal
+}
+
+// findStartCode finds the first Annex-B start code at or after from
+// Returns its index and length: 4 for 00 00 00 01, 3 for 00 00 01, or (-1, 0) if none.
+// A 00 00 01 preceded by a zero byte (at or after from) is reported as a 4-byte code.
+func findStartCode(buf []byte, from int) (idx, length int) {
+	for i := from; i+2 < len(buf); i++ {
+		if buf[i] != 0 || buf[i+1] != 0 {
+			continue
 		}
+		if buf[i+2] != 1 {
+			continue
+		}
+		if i-1 >= from && buf[i-1] == 0 {
+			return i - 1, 4
+		}
+		return i, 3
 	}
+	return -1, 0
+}
 
-	if nextIdx > 0 {
-		return buf[startIdx:nextIdx], buf[nextIdx:]
+// nalKind classifies NAL units that matter for caching, independent of codec
+type nalKind int
+
+const (
+	nalOther nalKind = iota
+	nalVPS           // H.265 only
+	nalSPS
+	nalPPS
+	nalIDR
+)
+
+// nalHeaderIndex returns the offset of the NAL header after a 3- or 4-byte start code (-1 if none)
+func nalHeaderIndex(nalData []byte) int {
+	if len(nalData) >= 4 && nalData[0] == 0 && nalData[1] == 0 {
+		if nalData[2] == 1 {
+			return 3
+		} else if nalData[2] == 0 && nalData[3] == 1 && len(nalData) > 4 {
+			return 4
+		}
 	}
+	return -1
+}
 
-	if len(buf) > 1024*100 {
-		return buf[startIdx:], nil
+// nalUnitType returns the NAL type from the header byte after the start code
+// H.264: bits 0-4 of the first byte; H.265: bits 1-6 of the first byte
+func nalUnitType(nalData []byte, codec string) int {
+	headerIdx := nalHeaderIndex(nalData)
+	if headerIdx < 0 {
+		return -1
 	}
 
-	return nil, buf
+	if codec == CodecH265 {
+		return int((nalData[headerIdx] >> 1) & 0x3F)
+	}
+	return int(nalData[headerIdx] & 0x1F)
 }
 
-// findStartCodeIndex finds the position of 00 00 01 or 00 00 00 01
-func findStartCodeIndex(data []byte) int {
-	n := len(data)
-	for i := 0; i < n-2; i++ {
-		if data[i] == 0 && data[i+1] == 0 && data[i+2] == 1 {
-			if i > 0 && data[i-1] == 0 {
-				return i - 1
-			}
-			return i
+// classifyNAL maps a codec-specific NAL type to a nalKind
+// H.264: 7=SPS, 8=PPS, 5=IDR; H.265: 32=VPS, 33=SPS, 34=PPS, 19/20=IDR
+func classifyNAL(nalData []byte, codec string) nalKind {
+	nalType := nalUnitType(nalData, codec)
+
+	if codec == CodecH265 {
+		switch nalType {
+		case 32:
+			return nalVPS
+		case 33:
+			return nalSPS
+		case 34:
+			return nalPPS
+		case 19, 20:
+			return nalIDR
 		}
+		return nalOther
 	}
-	return -1
+
+	switch nalType {
+	case 7:
+		return nalSPS
+	case 8:
+		return nalPPS
+	case 5:
+		return nalIDR
+	}
+	return nalOther
+}
+
+// isVCLNAL reports whether a NAL unit carries picture data (counts as a frame)
+// H.264: types 1-5; H.265: types 0-31
+func isVCLNAL(nalData []byte, codec string) bool {
+	nalType := nalUnitType(nalData, codec)
+	if codec == CodecH265 {
+		return nalType >= 0 && nalType <= 31
+	}
+	return nalType >= 1 && nalType <= 5
 }
 
 // broadcastNAL sends a single NAL unit to WebSocket
-func (s *StreamingService) broadcastNAL(deviceID string, nalData []byte, frameCount *int) {
+func (s *StreamingService) broadcastNAL(stream *deviceStream, codec string, nalData []byte, frameCount *int) {
 	if len(nalData) == 0 {
 		return
 	}
+	deviceID := stream.deviceID
 
 	*frameCount++
+	stream.metrics.record(isVCLNAL(nalData, codec), len(nalData))
+	if p50, p99, ok := stream.metrics.takeIntervalLog(); ok {
+		logging.Device(deviceID).Info("frame_interval", "⏱️ Frame arrival interval p50=%v p99=%v", p50.Round(time.Millisecond/10), p99.Round(time.Millisecond/10))
+	}
+	stream.recordNAL(nalData)
 
 	if *frameCount == 1 {
-		log.Printf("🎞️ [%s] First NAL received (%d bytes)", deviceID, len(nalData))
+		logging.Device(deviceID).Info("first_nal", "🎞️ First NAL received (%d bytes)", len(nalData))
 	} else if *frameCount%5000 == 0 {
-		log.Printf("📹 [%s] Streaming: %d NALs sent", deviceID, *frameCount)
+		logging.Device(deviceID).Info("nals_sent", "📹 Streaming: %d NALs sent", *frameCount)
 	}
 
-	idLen := len(deviceID)
-	if idLen > 255 {
+	kind := classifyNAL(nalData, codec)
+	send, refresh := stream.decimator.allow(nalData, kind, codec, time.Now())
+	if refresh {
+		go s.RequestKeyframe(deviceID, decimateRefresh)
+	}
+	if !send {
 		return
 	}
 
-	pkt := make([]byte, 1+idLen+len(nalData))
-	pkt[0] = byte(idLen)
-	copy(pkt[1:], []byte(deviceID))
-	copy(pkt[1+idLen:], nalData)
+	header := FrameHeader{Type: FrameTypeVideo, PTS: ptsSince(stream.ptsBase), DeviceID: deviceID}
+	switch kind {
+	case nalVPS, nalSPS, nalPPS:
+		header.Flags |= FrameFlagConfig
+	case nalIDR:
+		header.Flags |= FrameFlagKeyframe
+	}
+	if codec == CodecH265 {
+		header.Flags |= FrameFlagH265
+	}
+
+	pkt := EncodeFrame(header, nalData)
+	if pkt == nil {
+		return
+	}
 
 	s.wsHub.BroadcastToDevice(deviceID, pkt)
 
-	// Extract NAL type
-	nalType := -1
-	if len(nalData) >= 4 && nalData[0] == 0 && nalData[1] == 0 {
-		if nalData[2] == 1 {
-			nalType = int(nalData[3] & 0x1F)
-		} else if nalData[2] == 0 && nalData[3] == 1 && len(nalData) > 4 {
-			nalType = int(nalData[4] & 0x1F)
-		}
+	// Cache VPS/SPS/PPS/IDR
+	if kind == nalOther {
+		return
 	}
 
-	// Cache SPS/PPS/IDR
-	if nalType == 5 || nalType == 7 || nalType == 8 {
-		s.mu.RLock()
-		stream, exists := s.streams[deviceID]
-		s.mu.RUnlock()
+	cached := make([]byte, len(pkt))
+	copy(cached, pkt)
 
-		if exists {
-			stream.mu.Lock()
-			if nalType == 7 {
-				stream.spsPkt = make([]byte, len(pkt))
-				copy(stream.spsPkt, pkt)
-			} else if nalType == 8 {
-				stream.ppsPkt = make([]byte, len(pkt))
-				copy(stream.ppsPkt, pkt)
-			} else if nalType == 5 {
-				stream.lastIDRPkt = make([]byte, len(pkt))
-				copy(stream.lastIDRPkt, pkt)
+	resolutionChanged := false
+	stream.mu.Lock()
+	switch kind {
+	case nalVPS:
+		stream.vpsPkt = cached
+	case nalSPS:
+		// New SPS (first one or rotation/resize) - re-derive the video size
+		if codec == CodecH264 && !cachedNALEqual(stream.spsPkt, nalData) {
+			if w, h, err := parseH264SPSResolution(nalData); err != nil {
+				logging.Device(deviceID).Warn("sps_parse_failed", "⚠️ Failed to parse SPS: %v", err)
+			} else if w != stream.videoWidth || h != stream.videoHeight {
+				stream.videoWidth, stream.videoHeight = w, h
+				resolutionChanged = true
 			}
-			stream.mu.Unlock()
 		}
+		stream.spsPkt = cached
+	case nalPPS:
+		stream.ppsPkt = cached
+	case nalIDR:
+		stream.lastIDRPkt = cached
+		stream.lastIDRAt = time.Now()
+		for _, waiter := range stream.idrWaiters {
+			close(waiter)
+		}
+		stream.idrWaiters = nil
+	}
+	width, height := stream.videoWidth, stream.videoHeight
+	stream.mu.Unlock()
+
+	if resolutionChanged {
+		logging.Device(deviceID).Info("resolution_changed", "📐 Video resolution: %dx%d", width, height)
+		s.wsHub.BroadcastToDevice(deviceID, map[string]interface{}{
+			"type":      "resolution",
+			"device_id": deviceID,
+			"width":     width,
+			"height":    height,
+		})
+	}
+}
+
+// cachedNALEqual reports whether a cached frame carries nalData
+// Cached frames are PTS-stamped, so the frames themselves differ even for the same NAL
+func cachedNALEqual(cached, nalData []byte) bool {
+	if cached == nil {
+		return false
 	}
+	_, payload, err := DecodeFrame(cached)
+	return err == nil && bytes.Equal(payload, nalData)
 }
 
 // Control socket methods
 
-// SendKeyEvent sends a key press/release to a device
-func (s *StreamingService) SendKeyEvent(deviceID string, action, keycode, metastate int) error {
+// streamClient returns the device's current scrcpy client, read under stream.mu
+// runStream swaps the client on restarts, so callers use this snapshot instead of the field
+func (s *StreamingService) streamClient(deviceID string) (*ScrcpyClient, error) {
 	s.mu.RLock()
 	stream, exists := s.streams[deviceID]
 	s.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("stream not found for device: %s", deviceID)
+	}
 
-	if !exists || stream.scrcpyClient == nil {
-		return fmt.Errorf("stream not found for device: %s", deviceID)
+	stream.mu.Lock()
+	client := stream.scrcpyClient
+	stream.mu.Unlock()
+	if client == nil {
+		return nil, fmt.Errorf("stream not found for device: %s", deviceID)
 	}
+	return client, nil
+}
 
-	return stream.scrcpyClient.SendKeyEvent(action, keycode, metastate)
+// controlClient is streamClient for calls that need the control socket connected
+func (s *StreamingService) controlClient(deviceID string) (*ScrcpyClient, error) {
+	client, err := s.streamClient(deviceID)
+	if err != nil || !client.HasControl() {
+		return nil, fmt.Errorf("control socket not connected for device: %s", deviceID)
+	}
+	return client, nil
 }
 
-// SendText injects text directly to a device
-func (s *StreamingService) SendText(deviceID string, text string) error {
+// SendKeyEvent sends a key press/release to a device
+// repeat > 0 marks an auto-repeated DOWN of a held key (see SendLongPress)
+func (s *StreamingService) SendKeyEvent(deviceID string, action, keycode, repeat, metastate int) error {
+	client, err := s.streamClient(deviceID)
+	if err != nil {
+		return err
+	}
+	return client.SendKeyEvent(action, keycode, repeat, metastate)
+}
+
+// RequestKeyframe resets the encoder and waits for the fresh IDR to be broadcast
+// Returns false if no new IDR arrived within timeout (caller should fall back to cached headers)
+// Resets are coalesced: requests within timeout of the last reset only wait for it
+func (s *StreamingService) RequestKeyframe(deviceID string, timeout time.Duration) bool {
 	s.mu.RLock()
 	stream, exists := s.streams[deviceID]
 	s.mu.RUnlock()
+	if !exists {
+		return false
+	}
 
-	if !exists || stream.scrcpyClient == nil {
+	stream.mu.Lock()
+	client := stream.scrcpyClient
+	if stream.state != StateRunning || client == nil || !client.HasControl() {
+		stream.mu.Unlock()
+		return false
+	}
+
+	waiter := make(chan struct{})
+	stream.idrWaiters = append(stream.idrWaiters, waiter)
+	needReset := time.Since(stream.lastResetVideo) >= timeout
+	if needReset {
+		stream.lastResetVideo = time.Now()
+	}
+	stream.mu.Unlock()
+
+	if needReset {
+		if err := client.SendResetVideo(); err != nil {
+			logging.Device(deviceID).Warn("keyframe_request_failed", "⚠️ Reset video failed: %v", err)
+		} else {
+			logging.Device(deviceID).Info("keyframe_requested", "🔑 Keyframe requested (encoder reset)")
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-waiter:
+		return true
+	case <-timer.C:
+		// Drop our waiter so it isn't leaked until the next IDR
+		stream.mu.Lock()
+		for i, w := range stream.idrWaiters {
+			if w == waiter {
+				stream.idrWaiters = append(stream.idrWaiters[:i], stream.idrWaiters[i+1:]...)
+				break
+			}
+		}
+		stream.mu.Unlock()
+		return false
+	}
+}
+
+// SetDisplayPower turns a streaming device's display off or back on; mirroring continues either way
+// The setting sticks across scrcpy restarts until turned back on or the stream stops
+func (s *StreamingService) SetDisplayPower(deviceID string, on bool) error {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists {
 		return fmt.Errorf("stream not found for device: %s", deviceID)
 	}
 
-	return stream.scrcpyClient.SendText(text)
+	stream.mu.Lock()
+	client := stream.scrcpyClient
+	if client == nil || !client.HasControl() {
+		stream.mu.Unlock()
+		return fmt.Errorf("control socket not connected for device: %s", deviceID)
+	}
+	stream.displayOff = !on
+	stream.mu.Unlock()
+
+	if err := client.SetDisplayPower(on); err != nil {
+		return err
+	}
+	logging.Device(deviceID).Info("display_power", "🔌 Display turned %s while streaming", map[bool]string{true: "on", false: "off"}[on])
+	return nil
 }
 
-// SendClipboard sets Android clipboard and optionally pastes
-func (s *StreamingService) SendClipboard(deviceID string, text string, paste bool) error {
+// SendBackOrScreenOn sends BACK (or screen on) to a device over the control socket
+func (s *StreamingService) SendBackOrScreenOn(deviceID string, action int) error {
+	client, err := s.streamClient(deviceID)
+	if err != nil {
+		return err
+	}
+	return client.SendBackOrScreenOn(action)
+}
+
+// SendHome presses HOME over the control socket
+func (s *StreamingService) SendHome(deviceID string) error {
+	return s.sendKeyPress(deviceID, AKEYCODE_HOME)
+}
+
+// SendAppSwitch presses APP_SWITCH (recents) over the control socket
+func (s *StreamingService) SendAppSwitch(deviceID string) error {
+	return s.sendKeyPress(deviceID, AKEYCODE_APP_SWITCH)
+}
+
+// sendKeyPress sends a key down followed by key up
+func (s *StreamingService) sendKeyPress(deviceID string, keycode int) error {
+	if err := s.SendKeyEvent(deviceID, ActionDown, keycode, 0, MetaNone); err != nil {
+		return err
+	}
+	return s.SendKeyEvent(deviceID, ActionUp, keycode, 0, MetaNone)
+}
+
+// SendTouch injects a touch event to a device over the control socket
+// With normalized set, x/y are fractions (0.0-1.0) of the screen and width/height are
+// ignored: they are scaled server-side to the frame size scrcpy expects (see touchFrameSize)
+func (s *StreamingService) SendTouch(deviceID string, action int, pointerID uint64, x, y float64, width, height int, normalized bool, pressure float32, buttons int) error {
 	s.mu.RLock()
 	stream, exists := s.streams[deviceID]
 	s.mu.RUnlock()
@@ -654,7 +1441,118 @@ func (s *StreamingService) SendClipboard(deviceID string, text string, paste boo
 		return fmt.Errorf("stream not found for device: %s", deviceID)
 	}
 
-	return stream.scrcpyClient.SendClipboard(text, paste)
+	s.mu.RLock()
+	inputRate := s.inputRate
+	s.mu.RUnlock()
+
+	stream.mu.Lock()
+	if stream.touch == nil {
+		stream.touch = newTouchLimiter(inputRate)
+	}
+	limiter := stream.touch
+	client := stream.scrcpyClient
+	videoWidth, videoHeight := stream.videoWidth, stream.videoHeight
+	stream.mu.Unlock()
+
+	if client == nil {
+		return fmt.Errorf("stream not found for device: %s", deviceID)
+	}
+
+	px, py := int(x), int(y)
+	if normalized {
+		if x < 0 || x > 1 || y < 0 || y > 1 {
+			return fmt.Errorf("normalized coordinates must be within 0-1: (%g, %g)", x, y)
+		}
+		width, height = s.touchFrameSize(deviceID, client, videoWidth, videoHeight)
+		if width == 0 || height == 0 {
+			return fmt.Errorf("screen size unknown, cannot scale normalized touch: %s", deviceID)
+		}
+		px, py = scaleNormalized(x, width), scaleNormalized(y, height)
+	}
+
+	return limiter.submit(action, pointerID, func() error {
+		return client.SendTouchEvent(action, pointerID, px, py, width, height, pressure, buttons)
+	})
+}
+
+// touchFrameSize returns the size normalized touches are scaled to
+// scrcpy drops positional events whose size isn't the current video size, so the size
+// parsed from the SPS comes first, then the handshake's, then the device's wm size
+// (only right when the video isn't downscaled, e.g. before the first H.265 frame)
+func (s *StreamingService) touchFrameSize(deviceID string, client *ScrcpyClient, videoWidth, videoHeight int) (int, int) {
+	if videoWidth > 0 && videoHeight > 0 {
+		return videoWidth, videoHeight
+	}
+	if width, height := client.GetResolution(); width > 0 && height > 0 {
+		return width, height
+	}
+	if device := s.GetDevice(deviceID); device != nil {
+		if width, height, ok := parseResolution(device.Resolution); ok {
+			return width, height
+		}
+	}
+	return 0, 0
+}
+
+// scaleNormalized maps a 0.0-1.0 fraction to a pixel in [0, size-1]
+func scaleNormalized(v float64, size int) int {
+	return min(int(v*float64(size)), size-1)
+}
+
+// SendScroll injects a mouse wheel scroll to a device over the control socket
+func (s *StreamingService) SendScroll(deviceID string, x, y, width, height int, hScroll, vScroll float32, buttons int) error {
+	client, err := s.streamClient(deviceID)
+	if err != nil {
+		return err
+	}
+	return client.SendScroll(x, y, width, height, hScroll, vScroll, buttons)
+}
+
+// SendText injects text directly to a device
+func (s *StreamingService) SendText(deviceID string, text string) error {
+	client, err := s.streamClient(deviceID)
+	if err != nil {
+		return err
+	}
+
+	if needsClipboardPaste(text) {
+		// Inject-text only types what the keyboard map can; paste the rest (replaces the device clipboard)
+		return client.SendClipboard(text, true, false)
+	}
+	return client.SendText(text)
+}
+
+// needsClipboardPaste reports whether text can't go through inject-text:
+// non-ASCII (Unicode, emoji), control characters other than newline/tab, or longer than one message
+func needsClipboardPaste(text string) bool {
+	if len(text) > injectTextMaxLength {
+		return true
+	}
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; (c < 0x20 && c != '\n' && c != '\t') || c > 0x7E {
+			return true
+		}
+	}
+	return false
+}
+
+// SendClipboard sets Android clipboard and optionally pastes
+// With wait it blocks until the device acks the clipboard, or fails after a timeout
+func (s *StreamingService) SendClipboard(deviceID string, text string, paste, wait bool) error {
+	client, err := s.streamClient(deviceID)
+	if err != nil {
+		return err
+	}
+	return client.SendClipboard(text, paste, wait)
+}
+
+// GetClipboard reads the Android clipboard over the control socket
+func (s *StreamingService) GetClipboard(deviceID string) (string, error) {
+	client, err := s.streamClient(deviceID)
+	if err != nil {
+		return "", err
+	}
+	return client.GetClipboard()
 }
 
 // HasControl checks if a device has control socket available
@@ -663,26 +1561,74 @@ func (s *StreamingService) HasControl(deviceID string) bool {
 	stream, exists := s.streams[deviceID]
 	s.mu.RUnlock()
 
-	if !exists || stream.scrcpyClient == nil {
+	if !exists {
 		return false
 	}
 
-	return stream.scrcpyClient.HasControl()
+	stream.mu.Lock()
+	client := stream.scrcpyClient
+	stream.mu.Unlock()
+	return client != nil && client.HasControl()
+}
+
+// ReconnectControl restores control for a running stream that came up video-only (or lost
+// control mid-session) by restarting its scrcpy session: the server only accepts the control
+// socket at session start, so re-dialing it alone can't work
+// Returns false when control is already connected and nothing was restarted
+func (s *StreamingService) ReconnectControl(deviceID string) (bool, error) {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists {
+		return false, fmt.Errorf("stream not found for device: %s", deviceID)
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	client := stream.scrcpyClient
+	if stream.state != StateRunning || stream.degraded || client == nil {
+		return false, fmt.Errorf("no running scrcpy session for device: %s", deviceID)
+	}
+	if client.HasControl() {
+		return false, nil
+	}
+
+	logging.Device(deviceID).Info("control_restart", "🎮 Control socket missing, restarting the scrcpy session")
+	s.restartSession(stream)
+	return true, nil
 }
 
 // StartAllStreaming starts streaming for all online devices
 func (s *StreamingService) StartAllStreaming() error {
+	if err := s.requireStreaming(); err != nil {
+		return err
+	}
 	devices := s.deviceManager.GetAllDevices()
 	for _, device := range devices {
 		if device.Status == "online" {
-			if err := s.StartStreaming(device.ID); err != nil {
-				log.Printf("⚠️ Failed to start streaming for %s: %v", device.ID, err)
+			if _, err := s.StartStreaming(device.ID); err != nil {
+				logging.Device(device.ID).Warn("autostart_failed", "⚠️ Failed to start streaming: %v", err)
 			}
 		}
 	}
 	return nil
 }
 
+// HandleDeviceEvent reacts to device presence changes from DeviceManager
+// Starts/stops the device's stream (clients hear about the event from the bus)
+func (s *StreamingService) HandleDeviceEvent(event string, device *models.Device) {
+	switch event {
+	case DeviceEventOnline:
+		if _, err := s.StartStreaming(device.ID); err != nil {
+			logging.Device(device.ID).Warn("autostart_failed", "⚠️ Failed to start streaming: %v", err)
+		}
+	case DeviceEventOffline:
+		for _, key := range s.streamKeysOf(device.ID) {
+			s.StopStreaming(key)
+		}
+	}
+}
+
 // StopAllStreaming stops all active streams
 func (s *StreamingService) StopAllStreaming() {
 	s.mu.RLock()
@@ -697,6 +1643,144 @@ func (s *StreamingService) StopAllStreaming() {
 	}
 }
 
+// WaitAllStopped blocks until every stream reaches STOPPED (scrcpy killed, forward removed) or ctx ends
+func (s *StreamingService) WaitAllStopped(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		running := 0
+		s.mu.RLock()
+		for _, stream := range s.streams {
+			stream.mu.Lock()
+			if stream.state != StateStopped {
+				running++
+			}
+			stream.mu.Unlock()
+		}
+		s.mu.RUnlock()
+
+		if running == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d streams still running: %w", running, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// StreamSummary aggregates all streams for monitoring
+type StreamSummary struct {
+	ByState map[string]int `json:"by_state"`
+	FPS     float64        `json:"fps"`    // Sum of delivered FPS across streams
+	Kbps    float64        `json:"kbps"`   // Sum of bitrate across streams
+	Frames  int64          `json:"frames"` // Total frames delivered since start
+}
+
+// GetStreamSummary counts streams by state and sums their throughput
+func (s *StreamingService) GetStreamSummary() StreamSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summary := StreamSummary{ByState: make(map[string]int)}
+	for _, stream := range s.streams {
+		fps, kbps, frames := stream.metrics.snapshot()
+		summary.FPS += fps
+		summary.Kbps += kbps
+		summary.Frames += frames
+
+		stream.mu.Lock()
+		summary.ByState[stream.state.String()]++
+		stream.mu.Unlock()
+	}
+	return summary
+}
+
+// StreamSession is the detailed state of one device's stream, for diagnosing black tiles
+type StreamSession struct {
+	DeviceID          string  `json:"device_id"`
+	State             string  `json:"state"`
+	Viewers           int     `json:"viewers"`
+	Codec             string  `json:"codec"`
+	UptimeSeconds     float64 `json:"uptime_seconds"` // Since runStream began (0 when stopped)
+	ReconnectAttempts int     `json:"reconnect_attempts"`
+	LastIDRAgeMs      int64   `json:"last_idr_age_ms"`    // -1 if no IDR seen yet
+	HasCachedHeaders  bool    `json:"has_cached_headers"` // SPS+PPS (and VPS for H.265) cached
+	HasCachedIDR      bool    `json:"has_cached_idr"`
+	Paused            bool    `json:"paused"`
+	Degraded          bool    `json:"degraded"`      // screenrecord fallback: video only, no control
+	HasControl        bool    `json:"has_control"`   // Control socket connected (input works)
+	ControlOwner      string  `json:"control_owner"` // WebSocket client holding the input lock ("" = none)
+	PTSEpochMs        int64   `json:"pts_epoch_ms"`  // Unix ms of frame PTS 0 (0 when no session) - transit delay = now - epoch - pts/1000
+	IntervalP50Ms     float64 `json:"frame_interval_p50_ms"`
+	IntervalP99Ms     float64 `json:"frame_interval_p99_ms"`
+	Width             int     `json:"width"`
+	Height            int     `json:"height"`
+	FPS               float64 `json:"fps"`
+	Kbps              float64 `json:"kbps"`
+}
+
+// GetStreamSession returns detailed session info for a device
+func (s *StreamingService) GetStreamSession(deviceID string) (StreamSession, error) {
+	s.mu.RLock()
+	stream, exists := s.streams[deviceID]
+	s.mu.RUnlock()
+	if !exists {
+		return StreamSession{}, fmt.Errorf("stream not found for device: %s", deviceID)
+	}
+
+	fps, kbps, _ := stream.metrics.snapshot()
+	p50, p99 := stream.metrics.frameIntervals()
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	session := StreamSession{
+		DeviceID:          deviceID,
+		State:             stream.state.String(),
+		Viewers:           len(stream.viewers),
+		Codec:             s.sessionCodec(stream),
+		ReconnectAttempts: stream.reconnectAttempts,
+		Degraded:          stream.degraded,
+		LastIDRAgeMs:      -1,
+		HasCachedIDR:      stream.lastIDRPkt != nil,
+		Paused:            stream.paused,
+		ControlOwner:      stream.controlOwner,
+		HasControl:        stream.scrcpyClient != nil && stream.scrcpyClient.HasControl(),
+		Width:             stream.videoWidth,
+		Height:            stream.videoHeight,
+		FPS:               fps,
+		Kbps:              kbps,
+		IntervalP50Ms:     float64(p50.Microseconds()) / 1000,
+		IntervalP99Ms:     float64(p99.Microseconds()) / 1000,
+	}
+	if !stream.ptsBase.IsZero() {
+		session.PTSEpochMs = stream.ptsBase.UnixMilli()
+	}
+	if stream.state != StateStopped && !stream.startedAt.IsZero() {
+		session.UptimeSeconds = time.Since(stream.startedAt).Seconds()
+	}
+	if !stream.lastIDRAt.IsZero() {
+		session.LastIDRAgeMs = time.Since(stream.lastIDRAt).Milliseconds()
+	}
+	session.HasCachedHeaders = stream.spsPkt != nil && stream.ppsPkt != nil
+	if stream.codec == CodecH265 {
+		session.HasCachedHeaders = session.HasCachedHeaders && stream.vpsPkt != nil
+	}
+	return session, nil
+}
+
+// sessionCodec is the codec being streamed: always H.264 in the screenrecord fallback (caller holds stream.mu)
+func (s *StreamingService) sessionCodec(stream *deviceStream) string {
+	if stream.degraded {
+		return CodecH264
+	}
+	return s.streamCodec(stream.config)
+}
+
 // GetStreamingStatus returns the status of all streams
 func (s *StreamingService) GetStreamingStatus() map[string]interface{} {
 	s.mu.RLock()
@@ -704,10 +1788,16 @@ func (s *StreamingService) GetStreamingStatus() map[string]interface{} {
 
 	status := make(map[string]interface{})
 	for id, stream := range s.streams {
+		fps, kbps, _ := stream.metrics.snapshot()
 		stream.mu.Lock()
 		status[id] = map[string]interface{}{
-			"state":   stream.state.String(),
-			"viewers": stream.viewers,
+			"state":       stream.state.String(),
+			"viewers":     len(stream.viewers),
+			"codec":       s.sessionCodec(stream),
+			"fps":         fps,
+			"kbps":        kbps,
+			"degraded":    stream.degraded,
+			"has_control": stream.scrcpyClient != nil && stream.scrcpyClient.HasControl(),
 		}
 		stream.mu.Unlock()
 	}
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+)
+
+// splitAll feeds chunks through a nalSplitter and returns copies of every NAL it emits
+func splitAll(chunks ...[]byte) [][]byte {
+	sp := newNALSplitter()
+	var nals [][]byte
+	for _, chunk := range chunks {
+		sp.write(chunk)
+		for nal := sp.next(); nal != nil; nal = sp.next() {
+			nals = append(nals, append([]byte(nil), nal...))
+		}
+	}
+	return nals
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func assertNALs(t *testing.T, got, want [][]byte) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d NALs % x, want %d % x", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Errorf("NAL %d = % x, want % x", i, got[i], want[i])
+		}
+	}
+}
+
+var (
+	sc3 = []byte{0, 0, 1}
+	sc4 = []byte{0, 0, 0, 1}
+)
+
+// nalSplitterCases end with a start code so every NAL before it is emitted
+var nalSplitterCases = []struct {
+	name   string
+	stream []byte
+	want   [][]byte
+}{
+	{
+		name:   "3-byte start codes",
+		stream: concat(sc3, []byte{0x67, 0xAA}, sc3, []byte{0x68, 0xBB}, sc3, []byte{0x65, 0xCC, 0xDD}, sc3),
+		want: [][]byte{
+			{0, 0, 1, 0x67, 0xAA},
+			{0, 0, 1, 0x68, 0xBB},
+			{0, 0, 1, 0x65, 0xCC, 0xDD},
+		},
+	},
+	{
+		name:   "4-byte start codes",
+		stream: concat(sc4, []byte{0x67, 0xAA}, sc4, []byte{0x68, 0xBB}, sc4, []byte{0x65, 0xCC, 0xDD}, sc4),
+		want: [][]byte{
+			{0, 0, 0, 1, 0x67, 0xAA},
+			{0, 0, 0, 1, 0x68, 0xBB},
+			{0, 0, 0, 1, 0x65, 0xCC, 0xDD},
+		},
+	},
+	{
+		name:   "mixed start codes",
+		stream: concat(sc4, []byte{0x67, 0xAA}, sc3, []byte{0x68, 0xBB}, sc4, []byte{0x65, 0xCC}, sc3, []byte{0x41, 0xDD}, sc4),
+		want: [][]byte{
+			{0, 0, 0, 1, 0x67, 0xAA},
+			{0, 0, 1, 0x68, 0xBB},
+			{0, 0, 0, 1, 0x65, 0xCC},
+			{0, 0, 1, 0x41, 0xDD},
+		},
+	},
+	{
+		name:   "4-byte code is one start code",
+		stream: concat(sc4, []byte{0x09, 0xF0}, sc4, []byte{0x67}, sc4),
+		want: [][]byte{
+			{0, 0, 0, 1, 0x09, 0xF0},
+			{0, 0, 0, 1, 0x67},
+		},
+	},
+	{
+		name:   "leading garbage discarded",
+		stream: concat([]byte{0xDE, 0xAD, 0x00}, sc3, []byte{0x67, 0xAA}, sc4),
+		want: [][]byte{
+			{0, 0, 0, 1, 0x67, 0xAA},
+		},
+	},
+	{
+		name:   "payload zeros without start code",
+		stream: concat(sc4, []byte{0x65, 0x00, 0x00, 0x03, 0x00, 0x02, 0xAA}, sc3),
+		want: [][]byte{
+			{0, 0, 0, 1, 0x65, 0x00, 0x00, 0x03, 0x00, 0x02, 0xAA},
+		},
+	},
+}
+
+func TestNALSplitterSingleWrite(t *testing.T) {
+	for _, tc := range nalSplitterCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assertNALs(t, splitAll(tc.stream), tc.want)
+		})
+	}
+}
+
+func TestNALSplitterTrailingNALHeldBack(t *testing.T) {
+	// The last NAL has no end yet and must not be emitted truncated
+	sp := newNALSplitter()
+	sp.write(concat(sc4, []byte{0x67, 0xAA}, sc4, []byte{0x65, 0xBB}))
+	if nal := sp.next(); !bytes.Equal(nal, []byte{0, 0, 0, 1, 0x67, 0xAA}) {
+		t.Fatalf("first NAL = % x", nal)
+	}
+	if nal := sp.next(); nal != nil {
+		t.Fatalf("unterminated NAL emitted: % x", nal)
+	}
+	if sp.buffered() != 6 {
+		t.Fatalf("buffered = %d, want 6", sp.buffered())
+	}
+}
+
+func TestNALSplitterSplitAtEveryOffset(t *testing.T) {
+	for _, tc := range nalSplitterCases {
+		for i := 0; i <= len(tc.stream); i++ {
+			t.Run(fmt.Sprintf("%s/split=%d", tc.name, i), func(t *testing.T) {
+				assertNALs(t, splitAll(tc.stream[:i], tc.stream[i:]), tc.want)
+			})
+		}
+	}
+}
+
+func TestNALSplitterByteAtATime(t *testing.T) {
+	for _, tc := range nalSplitterCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chunks := make([][]byte, len(tc.stream))
+			for i := range tc.stream {
+				chunks[i] = tc.stream[i : i+1]
+			}
+			assertNALs(t, splitAll(chunks...), tc.want)
+		})
+	}
+}
+
+func TestFindStartCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		buf     []byte
+		from    int
+		wantIdx int
+		wantLen int
+	}{
+		{"empty", nil, 0, -1, 0},
+		{"3-byte", []byte{0, 0, 1, 0x67}, 0, 0, 3},
+		{"4-byte", []byte{0, 0, 0, 1, 0x67}, 0, 0, 4},
+		{"after payload", []byte{0xAA, 0, 0, 1}, 0, 1, 3},
+		{"4-byte after payload", []byte{0xAA, 0, 0, 0, 1}, 0, 1, 4},
+		{"zero before from not included", []byte{0, 0, 0, 1}, 1, 1, 3},
+		{"incomplete", []byte{0xAA, 0, 0}, 0, -1, 0},
+		{"emulation prevention", []byte{0, 0, 3, 1}, 0, -1, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			idx, length := findStartCode(tc.buf, tc.from)
+			if idx != tc.wantIdx || length != tc.wantLen {
+				t.Errorf("findStartCode(% x, %d) = (%d, %d), want (%d, %d)", tc.buf, tc.from, idx, length, tc.wantIdx, tc.wantLen)
+			}
+		})
+	}
+}
+
+// fakeNAL builds a NAL of n bytes (start code included) whose payload holds no start code
+func fakeNAL(header byte, n int, seed int64) []byte {
+	rng := rand.New(rand.NewSource(seed))
+	nal := make([]byte, n)
+	copy(nal, []byte{0, 0, 0, 1, header})
+	for i := 5; i < n; i++ {
+		nal[i] = byte(rng.Intn(255) + 1)
+	}
+	return nal
+}
+
+func TestNALSplitterLargeIDRInSmallChunks(t *testing.T) {
+	idr := fakeNAL(0x65, 300*1024, 1)
+	rng := rand.New(rand.NewSource(2))
+	sp := newNALSplitter()
+
+	for off := 0; off < len(idr); {
+		n := min(1024+rng.Intn(3*1024+1), len(idr)-off)
+		if !sp.write(idr[off : off+n]) {
+			t.Fatalf("buffer reset at offset %d", off)
+		}
+		off += n
+		if nal := sp.next(); nal != nil {
+			t.Fatalf("NAL of %d bytes emitted at offset %d before the next start code", len(nal), off)
+		}
+	}
+
+	sp.write([]byte{0, 0, 0, 1, 0x41})
+	nal := sp.next()
+	if !bytes.Equal(nal, idr) {
+		t.Fatalf("emitted NAL (%d bytes) differs from input IDR (%d bytes)", len(nal), len(idr))
+	}
+	if nal := sp.next(); nal != nil {
+		t.Fatalf("unexpected second NAL: %d bytes", len(nal))
+	}
+}
+
+func TestNALSplitterCeilingDropsPartialNAL(t *testing.T) {
+	huge := fakeNAL(0x65, maxNALBufferSize+64*1024, 3)
+	sp := newNALSplitter()
+
+	reset := false
+	for off := 0; off < len(huge); off += 4096 {
+		chunk := huge[off:min(off+4096, len(huge))]
+		if !sp.write(chunk) {
+			if reset {
+				t.Fatalf("second reset at offset %d", off)
+			}
+			reset = true
+			if sp.buffered() != len(chunk) {
+				t.Fatalf("buffered after reset = %d, want %d", sp.buffered(), len(chunk))
+			}
+		}
+		if nal := sp.next(); nal != nil {
+			t.Fatalf("NAL of %d bytes emitted at offset %d", len(nal), off)
+		}
+	}
+	if !reset {
+		t.Fatal("buffer never hit maxNALBufferSize")
+	}
+
+	// Resyncs on the next start code; the partial IDR tail is discarded, not emitted
+	next := []byte{0, 0, 0, 1, 0x41, 0xAA}
+	sp.write(next)
+	sp.write([]byte{0, 0, 0, 1, 0x41})
+	if nal := sp.next(); !bytes.Equal(nal, next) {
+		t.Fatalf("first NAL after reset = %d bytes, want % x", len(nal), next)
+	}
+}
+
+func TestNeedsClipboardPaste(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		paste bool
+	}{
+		{"empty", "", false},
+		{"plain ascii", "hello", false},
+		{"spaces and punctuation", "Hello, world! (a+b)*2 = ~42?", false},
+		{"newline and tab", "line1\nline2\tcol", false},
+		{"accented latin", "café déjà vu", true},
+		{"emoji", "ok 👍", true},
+		{"cjk", "你好世界", true},
+		{"control character", "bell\a", true},
+		{"delete", "x\x7f", true},
+		{"max length ascii", strings.Repeat("a", injectTextMaxLength), false},
+		{"too long ascii", strings.Repeat("a", injectTextMaxLength+1), true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := needsClipboardPaste(tc.text); got != tc.paste {
+				t.Errorf("needsClipboardPaste(%q) = %t, want %t", tc.text, got, tc.paste)
+			}
+		})
+	}
+}
+
+// swapClients keeps replacing the stream's scrcpy client the way runStream does on restarts
+func swapClients(stream *deviceStream, done <-chan struct{}) {
+	for i := 0; ; i++ {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		stream.mu.Lock()
+		if i%2 == 0 {
+			stream.scrcpyClient = nil
+		} else {
+			stream.scrcpyClient = NewScrcpyClient(nil, stream.deviceADBID)
+		}
+		stream.mu.Unlock()
+	}
+}
+
+func TestControlCallsDuringClientRestart(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	stream := runningStream(s, "dev", "serial", "")
+
+	done := make(chan struct{})
+	swapped := make(chan struct{})
+	go func() {
+		swapClients(stream, done)
+		close(swapped)
+	}()
+
+	// Run with -race: every call must read the client under stream.mu
+	calls := []func() error{
+		func() error { return s.SendScroll("dev", 10, 10, 720, 1280, 0, -1, 0) },
+		func() error { return s.SendText("dev", "hello") },
+		func() error { return s.SendText("dev", "héllo") },
+		func() error { return s.SendClipboard("dev", "copied", false, false) },
+		func() error { return s.SendKeyEvent("dev", ActionDown, AKEYCODE_HOME, 0, MetaNone) },
+		func() error { return s.TypeAsKeys("dev", "a") },
+		func() error { return s.SendBackOrScreenOn("dev", ActionDown) },
+		func() error { _, err := s.GetClipboard("dev"); return err },
+		func() error { return s.SendPinch("dev", 360, 640, 100, 300, 200) },
+	}
+	for i := 0; i < 200; i++ {
+		for _, call := range calls {
+			if err := call(); err == nil {
+				t.Fatal("control call succeeded without a control socket")
+			}
+		}
+	}
+	close(done)
+	<-swapped
+}
+
+func TestStreamClientSnapshot(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	if _, err := s.streamClient("missing"); err == nil {
+		t.Error("streamClient found a client for an unknown device")
+	}
+
+	stream := runningStream(s, "dev", "serial", "")
+	if _, err := s.streamClient("dev"); err == nil {
+		t.Error("streamClient returned a nil client")
+	}
+
+	client := NewScrcpyClient(nil, "serial")
+	stream.scrcpyClient = client
+	if got, err := s.streamClient("dev"); err != nil || got != client {
+		t.Errorf("streamClient = %p, %v, want %p", got, err, client)
+	}
+	if _, err := s.controlClient("dev"); err == nil {
+		t.Error("controlClient accepted a client without a control socket")
+	}
+}
+
+func TestReconnectControlRestartsSession(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	stream := runningStream(s, "dev", "serial", "")
+	stream.scrcpyClient = NewScrcpyClient(nil, "serial") // Video-only session
+
+	restarting, err := s.ReconnectControl("dev")
+	if err != nil || !restarting {
+		t.Fatalf("ReconnectControl = %t, %v, want a session restart", restarting, err)
+	}
+	stream.mu.Lock()
+	requested := stream.restartRequested
+	stream.mu.Unlock()
+	if !requested {
+		t.Error("no session restart was requested")
+	}
+}
+
+func TestReconnectControlWithControlUp(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	stream := runningStream(s, "dev", "serial", "")
+	client := NewScrcpyClient(nil, "serial")
+	ctrl, peer := net.Pipe()
+	defer peer.Close()
+	client.ctrlConn = ctrl
+	stream.scrcpyClient = client
+
+	if restarting, err := s.ReconnectControl("dev"); err != nil || restarting {
+		t.Errorf("ReconnectControl = %t, %v, want no restart", restarting, err)
+	}
+	if stream.restartRequested {
+		t.Error("a session with control was restarted")
+	}
+}
+
+func TestReconnectControlNeedsRunningSession(t *testing.T) {
+	s := NewStreamingService(NewDeviceManager(nil), nil)
+	if _, err := s.ReconnectControl("missing"); err == nil {
+		t.Error("ReconnectControl accepted an unknown stream")
+	}
+
+	stream := runningStream(s, "dev", "serial", "")
+	stream.scrcpyClient = NewScrcpyClient(nil, "serial")
+	stream.degraded = true // screenrecord fallback: no scrcpy session to restart into control
+	if _, err := s.ReconnectControl("dev"); err == nil {
+		t.Error("ReconnectControl accepted a degraded stream")
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"fmt"
+	"math"
+)
+
+// PointerIDStylus is the pointer stylus strokes are injected with, kept apart from the
+// finger IDs clients use so a pen stroke never continues or ends a finger gesture
+const PointerIDStylus uint64 = 0xFFFFFFFFFFFFFF00
+
+// Android MotionEvent button states for the stylus barrel buttons (buttons field)
+const (
+	ButtonStylusPrimary   = 1 << 5
+	ButtonStylusSecondary = 1 << 6
+)
+
+// SendStylus injects one stylus point (down/move/up) with pressure 0.0-1.0
+// Pressure reaches MotionEvent.getPressure() as 16-bit fixed point, so pressure-sensitive
+// apps vary stroke width. scrcpy 3.x reports every injected pointer as TOOL_TYPE_FINGER
+// (its protocol has no tool type), so apps that only accept TOOL_TYPE_STYLUS won't draw.
+func (s *StreamingService) SendStylus(deviceID string, action int, x, y float64, width, height int, normalized bool, pressure float32, buttons int) error {
+	if math.IsNaN(float64(pressure)) || pressure < 0 || pressure > 1 {
+		return fmt.Errorf("stylus pressure must be within 0-1: %g", pressure)
+	}
+	if buttons&^(ButtonStylusPrimary|ButtonStylusSecondary) != 0 {
+		return fmt.Errorf("unsupported stylus buttons: 0x%x", buttons)
+	}
+	return s.SendTouch(deviceID, action, PointerIDStylus, x, y, width, height, normalized, pressure, buttons)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"androidcontrol/models"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WiFi reconnect backoff: 2s, 4s, 8s, 16s, 32s between attempts (~1 minute) before giving up
+const (
+	wifiReconnectAttempts  = 5
+	wifiReconnectBaseDelay = 2 * time.Second
+)
+
+// wifiReconnect is a pending reconnect for a WiFi device that dropped out of a scan
+type wifiReconnect struct {
+	addr     string // ip:port to adb connect
+	device   models.Device
+	attempts int
+	next     time.Time
+}
+
+// isWiFiADBID reports whether an ADB ID is an ip:port wireless connection
+func isWiFiADBID(adbDeviceID string) bool {
+	return strings.Contains(adbDeviceID, ":")
+}
+
+// trackWiFiEndpoint remembers the ip:port of an online WiFi device (must hold mu)
+// Keyed by hardware serial so the endpoint outlives USB/WiFi dedup swapping ADB IDs
+func (m *DeviceManager) trackWiFiEndpoint(device *models.Device) {
+	if device.Status != models.DeviceStatusOnline || !isWiFiADBID(device.ADBDeviceID) {
+		return
+	}
+	if device.HardwareSerial == "" || device.HardwareSerial == device.ADBDeviceID {
+		return // Serial lookup failed - nothing stable to key on
+	}
+	m.wifiEndpoints[device.HardwareSerial] = device.ADBDeviceID
+}
+
+// scheduleWiFiReconnect queues reconnect attempts for a WiFi device that went missing (must hold mu)
+func (m *DeviceManager) scheduleWiFiReconnect(device *models.Device) {
+	addr, known := m.wifiEndpoints[device.HardwareSerial]
+	if !known || device.HardwareSerial == "" {
+		return
+	}
+	if _, pending := m.reconnects[device.HardwareSerial]; pending {
+		return
+	}
+	r := &wifiReconnect{addr: addr, device: *device, next: time.Now()}
+	r.device.Status = models.DeviceStatusOffline
+	m.reconnects[device.HardwareSerial] = r
+	log.Printf("📶 [%s] WiFi device dropped, will try reconnecting to %s", device.ID, addr)
+}
+
+// ForgetWiFiEndpoint stops reconnecting to ip:port (e.g. after an explicit disconnect)
+func (m *DeviceManager) ForgetWiFiEndpoint(addr string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for serial, endpoint := range m.wifiEndpoints {
+		if endpoint == addr {
+			delete(m.wifiEndpoints, serial)
+			delete(m.reconnects, serial)
+		}
+	}
+}
+
+// reconnectWiFiDevices runs due adb connect attempts for dropped WiFi devices
+// A device counts as recovered once a rescan lists its serial online again; the
+// rescan emits the online event, followed by a reconnected event. Giving up
+// after wifiReconnectAttempts emits reconnect_failed.
+func (m *DeviceManager) reconnectWiFiDevices() {
+	now := time.Now()
+	m.mu.RLock()
+	var due []*wifiReconnect
+	for _, r := range m.reconnects {
+		if !now.Before(r.next) {
+			due = append(due, r)
+		}
+	}
+	m.mu.RUnlock()
+	if len(due) == 0 {
+		return
+	}
+
+	// adb connect can take seconds per endpoint - run it outside the lock
+	// "already connected" also succeeds for endpoints adb lists as offline, so
+	// only the rescan below decides whether the device is really back
+	errs := make([]error, len(due))
+	connected := false
+	for i, r := range due {
+		errs[i] = m.connectWiFi(r.addr)
+		connected = connected || errs[i] == nil
+	}
+	if connected {
+		if err := m.ScanDevices(); err != nil {
+			log.Printf("⚠️ Rescan after WiFi reconnect failed: %v", err)
+		}
+	}
+
+	var recovered, failed []models.Device
+	m.mu.Lock()
+	// Only a WiFi entry counts - the phone may still be online over USB
+	online := make(map[string]*models.Device, len(m.devices))
+	for _, d := range m.devices {
+		if d.Status == models.DeviceStatusOnline && d.HardwareSerial != "" && isWiFiADBID(d.ADBDeviceID) {
+			online[d.HardwareSerial] = d
+		}
+	}
+	for i, r := range due {
+		serial := r.device.HardwareSerial
+		if m.reconnects[serial] != r {
+			continue // Forgotten meanwhile
+		}
+		r.attempts++
+		if current, ok := online[serial]; ok {
+			delete(m.reconnects, serial)
+			recovered = append(recovered, *current)
+			log.Printf("📶 [%s] Reconnected to %s (attempt %d)", current.ID, r.addr, r.attempts)
+			continue
+		}
+
+		err := errs[i]
+		if err == nil {
+			err = fmt.Errorf("%s connected but device not online", r.addr)
+		}
+		if r.attempts >= wifiReconnectAttempts {
+			delete(m.reconnects, serial)
+			failed = append(failed, r.device)
+			log.Printf("❌ [%s] Giving up reconnecting to %s after %d attempts: %v", r.device.ID, r.addr, r.attempts, err)
+			continue
+		}
+		delay := wifiReconnectBaseDelay << (r.attempts - 1)
+		r.next = time.Now().Add(delay)
+		log.Printf("⚠️ [%s] Reconnect to %s failed (attempt %d/%d), retrying in %v: %v",
+			r.device.ID, r.addr, r.attempts, wifiReconnectAttempts, delay, err)
+	}
+	handler := m.eventHandler
+	m.mu.Unlock()
+
+	for i := range recovered {
+		m.notify(handler, DeviceEventReconnected, &recovered[i])
+	}
+	for i := range failed {
+		m.notify(handler, DeviceEventReconnectFailed, &failed[i])
+	}
+}
+
+// connectWiFi runs adb connect for an ip:port endpoint
+func (m *DeviceManager) connectWiFi(addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+	return m.adbClient.Connect(host, port)
+}
@@ -195,7 +195,13 @@ export const DeviceCard: React.FC<DeviceCardProps> = memo(({ device, slotIndex,
                                 {String(slotIndex + 1).padStart(2, '0')}
                             </div>
                             <div className="text-sm text-gray-600">
-                                {device ? 'Offline' : 'Chưa kết nối'}
+                                {!device
+                                    ? 'Chưa kết nối'
+                                    : device.status === 'unauthorized'
+                                        ? 'Chấp nhận gỡ lỗi USB trên máy'
+                                        : device.status === 'no_permissions'
+                                            ? 'Thiếu quyền USB (udev)'
+                                            : 'Offline'}
                             </div>
                         </div>
                     )}
@@ -6,6 +6,7 @@ import { wsService } from '@/services/websocket';
 import { useSettingsStore } from '@/store/useSettingsStore';
 import { useAppStore } from '@/store/useAppStore';
 import { getAndroidKeycode, getMetaState, isPrintableKey } from '@/utils/keymap';
+import { parseFrame, FRAME_TYPE_VIDEO } from '@/utils/frame';
 
 interface ScreenViewProps {
     device: Device;
@@ -161,24 +162,17 @@ export const ScreenView: React.FC<ScreenViewProps> = ({
             if (!decoderRef.current || decoderRef.current.state === 'closed') return;
             if (!(data instanceof ArrayBuffer)) return;
 
-            const buf = new Uint8Array(data);
-
-            // Protocol mới: [1 byte ID_LENGTH] + [ID_BYTES] + [NAL_DATA]
-            if (buf.byteLength < 2) return;
-
-            const idLen = buf[0];
-            if (buf.byteLength < 1 + idLen) return;
-
-            // Đọc Device ID từ gói tin
-            const msgDeviceId = new TextDecoder().decode(buf.subarray(1, 1 + idLen));
+            // Header: v2 (magic/version/type/flags/pts) or legacy [idLen][ID][NAL]
+            const frame = parseFrame(new Uint8Array(data));
+            if (!frame || frame.type !== FRAME_TYPE_VIDEO) return;
 
             // 🔥 LỌC: Nếu không phải ID của máy mình -> Bỏ qua ngay lập tức
-            if (msgDeviceId !== device.id) {
+            if (frame.deviceId !== device.id) {
                 return;
             }
 
             // Lấy NAL Data thực sự
-            const nalUnit = buf.subarray(1 + idLen);
+            const nalUnit = frame.payload;
             const nalType = getNALType(nalUnit);
 
             // 1. Lưu SPS/PPS
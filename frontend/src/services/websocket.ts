@@ -1,4 +1,5 @@
 import { WS_URL } from '@/utils/constants';
+import { generateId } from '@/utils/helpers';
 
 type MessageHandler = (data: ArrayBuffer | string) => void;
 
@@ -12,6 +13,8 @@ class WebSocketService {
     private deviceSubs = new Set<string>();
     // Queue messages when socket is not open
     private sendQueue: string[] = [];
+    // Stable across reconnects so the backend doesn't count us twice as a viewer
+    private readonly clientId = generateId();
 
     constructor() {
         this.connect();
@@ -23,7 +26,7 @@ class WebSocketService {
         this.isConnecting = true;
         console.log('🔌 WebSocket Connecting to', WS_URL);
 
-        this.ws = new WebSocket(WS_URL);
+        this.ws = new WebSocket(`${WS_URL}?client_id=${encodeURIComponent(this.clientId)}`);
         this.ws.binaryType = 'arraybuffer';
 
         this.ws.onopen = () => {
@@ -1,11 +1,19 @@
 export interface Device {
     id: string;
-    name: string;
+    name: string; // alias when set, otherwise the model
+    model?: string; // model reported by adb
     adb_device_id: string;
-    status: 'online' | 'offline';
+    status: 'online' | 'offline' | 'unauthorized' | 'no_permissions'; // unauthorized = RSA prompt not accepted
     resolution: string;
     battery: number;
     android_version: string;
+    hardware_serial?: string;
+    connection_type: 'usb' | 'wifi' | 'emulator'; // from the adb ID format; emulator is wired (treat like usb if only wired/wireless matters)
+    ip?: string; // wifi only
+    port?: number; // wifi only
+    manufacturer?: string;
+    sdk_int?: number; // API level
+    cpu_abi?: string;
     last_seen: number;
     frame?: string; // base64 encoded screen frame
     tags?: string[]; // Device tags
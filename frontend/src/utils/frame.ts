@@ -0,0 +1,80 @@
+// Binary WebSocket frame parsing (mirrors backend/service/frame_header.go)
+//
+// v2 header, big-endian:
+//   [magic 0xAC][version 0x02][type][flags][idLen:2][pts:8][deviceID][payload]
+// Legacy (WS_LEGACY_FRAMES=true):
+//   video: [idLen:1][deviceID][NAL]
+//   typed: [0x00][type][idLen:1][deviceID][payload]
+
+export const FRAME_MAGIC = 0xac;
+export const FRAME_VERSION = 0x02;
+const FRAME_HEADER_SIZE = 14;
+
+export const FRAME_TYPE_VIDEO = 0x00;
+export const FRAME_TYPE_AUDIO = 0x01;
+
+export const FRAME_FLAG_KEYFRAME = 1 << 0;
+export const FRAME_FLAG_CONFIG = 1 << 1;
+export const FRAME_FLAG_H265 = 1 << 2;
+
+export interface Frame {
+    version: number; // 0 for legacy frames
+    type: number;
+    flags: number;
+    pts: number; // microseconds since the scrcpy session started (0 for legacy)
+    deviceId: string;
+    payload: Uint8Array;
+}
+
+const decoder = new TextDecoder();
+
+/**
+ * Parse a binary WebSocket frame in either the v2 or legacy layout.
+ * Returns null for truncated frames.
+ */
+export function parseFrame(buf: Uint8Array): Frame | null {
+    if (
+        buf.byteLength >= FRAME_HEADER_SIZE &&
+        buf[0] === FRAME_MAGIC &&
+        buf[1] === FRAME_VERSION &&
+        (buf[2] === FRAME_TYPE_VIDEO || buf[2] === FRAME_TYPE_AUDIO)
+    ) {
+        const view = new DataView(buf.buffer, buf.byteOffset, buf.byteLength);
+        const idLen = view.getUint16(4);
+        if (buf.byteLength < FRAME_HEADER_SIZE + idLen) return null;
+        return {
+            version: buf[1],
+            type: buf[2],
+            flags: buf[3],
+            pts: Number(view.getBigUint64(6)),
+            deviceId: decoder.decode(buf.subarray(FRAME_HEADER_SIZE, FRAME_HEADER_SIZE + idLen)),
+            payload: buf.subarray(FRAME_HEADER_SIZE + idLen),
+        };
+    }
+
+    if (buf.byteLength < 2) return null;
+
+    if (buf[0] === 0x00) {
+        if (buf.byteLength < 3 || buf.byteLength < 3 + buf[2]) return null;
+        const idLen = buf[2];
+        return {
+            version: 0,
+            type: buf[1],
+            flags: 0,
+            pts: 0,
+            deviceId: decoder.decode(buf.subarray(3, 3 + idLen)),
+            payload: buf.subarray(3 + idLen),
+        };
+    }
+
+    const idLen = buf[0];
+    if (buf.byteLength < 1 + idLen) return null;
+    return {
+        version: 0,
+        type: FRAME_TYPE_VIDEO,
+        flags: 0,
+        pts: 0,
+        deviceId: decoder.decode(buf.subarray(1, 1 + idLen)),
+        payload: buf.subarray(1 + idLen),
+    };
+}
@@ -3,6 +3,8 @@
  * Runs in a Web Worker to offload main thread
  */
 
+import { parseFrame, FRAME_TYPE_VIDEO } from "../utils/frame";
+
 // ==== GLOBALS ====
 let ws: WebSocket | null = null;
 let wsUrl = "";
@@ -12,6 +14,8 @@ let terminated = false;
 let hasConfigured = false;
 let lastOutput = performance.now();
 let lastKeyframeRequestTime = 0;
+// Stable across reconnects so the backend doesn't count this tile twice as a viewer
+const clientId = `tile-${Date.now()}-${Math.random().toString(36).slice(2, 11)}`;
 
 // Decoder globals
 let decoder: VideoDecoder | null = null;
@@ -89,17 +93,12 @@ function onWsMessage(ev: MessageEvent) {
         return;
     }
 
-    const buf = new Uint8Array(ev.data as ArrayBuffer);
-    if (buf.byteLength < 2) return;
-
-    // Protocol: [1 byte ID_LENGTH] + [ID_BYTES] + [NAL_DATA]
-    const idLen = buf[0];
-    if (buf.byteLength < 1 + idLen) return;
-
-    const msgDeviceId = new TextDecoder().decode(buf.subarray(1, 1 + idLen));
-    if (msgDeviceId !== deviceId) return; // Filter: only our device
+    // Header: v2 (magic/version/type/flags/pts) or legacy [idLen][ID][NAL]
+    const frame = parseFrame(new Uint8Array(ev.data as ArrayBuffer));
+    if (!frame || frame.type !== FRAME_TYPE_VIDEO) return;
+    if (frame.deviceId !== deviceId) return; // Filter: only our device
 
-    const nalUnit = buf.subarray(1 + idLen);
+    const nalUnit = frame.payload;
     const nalType = getNALType(nalUnit);
 
     // Cache SPS/PPS
@@ -179,7 +178,7 @@ function ensureWs() {
     if (terminated) return;
     if (ws && (ws.readyState === WebSocket.OPEN || ws.readyState === WebSocket.CONNECTING)) return;
 
-    const url = `${wsUrl}?device_id=${encodeURIComponent(deviceId)}`;
+    const url = `${wsUrl}?device_id=${encodeURIComponent(deviceId)}&client_id=${encodeURIComponent(clientId)}`;
     ws = new WebSocket(url);
     ws.binaryType = "arraybuffer";
     ws.addEventListener("open", onWsOpen);